@@ -0,0 +1,151 @@
+package api
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/models"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/policy"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/responses"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/storage"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/utils/formaterror"
+)
+
+// CreateAdmin creates a verified admin account. The password is read from stdin when asked for, otherwise one
+// is generated and printed once.
+func CreateAdmin(username, email string, age uint32, passwordStdin bool) {
+
+	password, generated := commandPassword(passwordStdin)
+	server.Open(loadConfig())
+	defer server.DB.Close()
+
+	user := models.User{Username: username, Email: email, Age: age, Password: password}
+	user.Prepare()
+	user.Role = policy.RoleAdmin
+	if errorMessages := user.Validate(""); len(errorMessages) > 0 {
+		log.Fatalf("Cannot create the admin: %v", errorMessages)
+	}
+	// the operator vouches for the address
+	verifiedAt := time.Now()
+	user.EmailVerifiedAt = &verifiedAt
+	if _, err := user.SaveUser(server.DB); err != nil {
+		log.Fatalf("Cannot create the admin: %v", formaterror.FormatError(err.Error()))
+	}
+	fmt.Printf("\nCreated the admin %s with id %d\n", user.Username, user.ID)
+	if generated {
+		fmt.Printf("Password: %s\n", password)
+	}
+}
+
+// ResetPassword sets a new password for the user and revokes every session, like a password reset by email does
+func ResetPassword(email string, uid uint32, passwordStdin bool) {
+
+	password, generated := commandPassword(passwordStdin)
+	if len(password) < 6 {
+		log.Fatal("Password should be atleast 6 characters")
+	}
+	server.Open(loadConfig())
+	defer server.DB.Close()
+
+	user := commandUser(email, uid)
+	if err := user.UpdatePassword(server.DB, password); err != nil {
+		log.Fatalf("Cannot change the password: %v", err)
+	}
+	refreshToken := models.RefreshToken{}
+	revoked, err := refreshToken.RevokeUserTokens(server.DB, user.ID)
+	if err != nil {
+		log.Fatalf("The password is changed but the sessions are not revoked: %v", err)
+	}
+	fmt.Printf("\nChanged the password of %s and revoked %d sessions\n", user.Username, revoked)
+	if generated {
+		fmt.Printf("Password: %s\n", password)
+	}
+}
+
+// PurgeUser deletes the user with everything they own and their stored files, see Server.PurgeUser
+func PurgeUser(email string, uid uint32) {
+
+	cfg := loadConfig()
+	server.Open(cfg)
+	defer server.DB.Close()
+	// the stored files go too, the rest of the API is not needed
+	var err error
+	if server.Storage, err = storage.New(cfg.Storage); err != nil {
+		log.Fatalf("Cannot set up the photo storage: %v", err)
+	}
+
+	user := commandUser(email, uid)
+	if err := server.PurgeUser(user.ID); err != nil {
+		log.Fatalf("Cannot delete the user: %v", err)
+	}
+	fmt.Printf("\nDeleted the user %s with id %d\n", user.Username, user.ID)
+}
+
+// Export writes everything stored about the user as JSON to the file out, or to stdout when it is empty
+func Export(email string, uid uint32, out string) {
+
+	server.Open(loadConfig())
+	defer server.DB.Close()
+
+	user := commandUser(email, uid)
+	export, err := user.ExportUser(server.DB, user.ID)
+	if err != nil {
+		log.Fatalf("Cannot export the user: %v", err)
+	}
+	body, err := json.MarshalIndent(responses.NewExport(*export), "", "  ")
+	if err != nil {
+		log.Fatalf("Cannot export the user: %v", err)
+	}
+	body = append(body, '\n')
+
+	if out == "" {
+		// the connection messages of Open go to stdout as well, keep the JSON on its own lines
+		fmt.Println()
+		os.Stdout.Write(body)
+		return
+	}
+	// the export holds personal data, only the owner of the file may read it
+	if err = os.WriteFile(out, body, 0600); err != nil {
+		log.Fatalf("Cannot write the export: %v", err)
+	}
+	fmt.Printf("\nWrote the data of %s to %s\n", user.Username, out)
+}
+
+// commandUser finds the user named by email or by id, whichever is set
+func commandUser(email string, uid uint32) *models.User {
+	user := models.User{}
+	var err error
+	if email != "" {
+		_, err = user.FindUserByEmail(server.DB, email)
+	} else {
+		_, err = user.FindUserByID(server.DB, uid)
+	}
+	if err != nil {
+		log.Fatalf("Cannot find the user: %v", err)
+	}
+	return &user
+}
+
+// commandPassword reads the first line of stdin, or generates a password that generated reports so it is printed
+func commandPassword(fromStdin bool) (password string, generated bool) {
+	if fromStdin {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			log.Fatalf("Cannot read the password: %v", err)
+		}
+		return strings.TrimRight(line, "\r\n"), false
+	}
+	random := make([]byte, 18)
+	if _, err := rand.Read(random); err != nil {
+		log.Fatalf("Cannot generate a password: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(random), true
+}
@@ -1,47 +1,74 @@
 package auth
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
-	"os"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/config"
 	"github.com/dgrijalva/jwt-go"
 )
 
-func CreateToken(id uint32) (string, error) {
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrRevokedToken = errors.New("token has been revoked")
+)
+
+// RevocationStore reports whether a refresh token family has been revoked (e.g. on logout)
+type RevocationStore interface {
+	FamilyRevoked(familyID string) bool
+}
+
+// Tokens issues the access tokens and validates those of the requests, with the secret and the lifetimes
+// of the configuration. Access tokens of a revoked family are rejected when there is a revocation store.
+type Tokens struct {
+	settings    config.Auth
+	revocations RevocationStore
+}
+
+func NewTokens(cfg config.Auth, revocations RevocationStore) *Tokens {
+	return &Tokens{settings: cfg, revocations: revocations}
+}
+
+// AccessTokenTTL is how long an access token stays valid after it is issued
+func (t *Tokens) AccessTokenTTL() time.Duration {
+	return t.settings.AccessTokenTTL
+}
+
+// RefreshTokenTTL is how long a refresh token can be exchanged for a new pair
+func (t *Tokens) RefreshTokenTTL() time.Duration {
+	return t.settings.RefreshTokenTTL
+}
+
+// Claims is what the access token says about the caller
+type Claims struct {
+	UserID   uint32
+	Role     string
+	FamilyID string
+}
+
+// CreateToken issues a short-lived access token bound to the given refresh token family
+func (t *Tokens) CreateToken(id uint32, role string, familyID string) (string, error) {
+	now := time.Now()
 	claims := jwt.MapClaims{}
 	claims["authorized"] = true
 	claims["id"] = id
+	claims["role"] = role
+	claims["fid"] = familyID
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(t.settings.AccessTokenTTL).Unix()
 	// Create a new token with the HS256 signing method and the claims map
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	// Sign the token with the API_SECRET environment variable and return the signed token as a string
-	return token.SignedString([]byte(os.Getenv("API_SECRET")))
+	// Sign the token with the configured secret and return the signed token as a string
+	return token.SignedString([]byte(t.settings.Secret))
 }
 
-func TokenValid(r *http.Request) error {
-	// extract token from request
-	tokenString := ExtractToken(r)
-
-	// parse token and verify signature with HMAC method
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(os.Getenv("API_SECRET")), nil
-	})
-	if err != nil {
-		return err
-	}
-	// if token is valid, extract and print the claims
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		Pretty(claims)
-	}
-	// return nil if no errors occurred
-	return nil
+func (t *Tokens) TokenValid(r *http.Request) error {
+	_, err := t.parseToken(r)
+	return err
 }
 
 func ExtractToken(r *http.Request) string {
@@ -68,43 +95,82 @@ func ExtractToken(r *http.Request) string {
 }
 
 // ExtractTokenID extracts the ID from the JWT token in the request header or URL query parameter
-func ExtractTokenID(r *http.Request) (uint32, error) {
+func (t *Tokens) ExtractTokenID(r *http.Request) (uint32, error) {
+	claims, err := t.parseToken(r)
+	if err != nil {
+		// Return any errors encountered during parsing
+		return 0, err
+	}
+	// Convert the "id" claim to a uint32
+	uid, err := strconv.ParseUint(fmt.Sprintf("%.0f", claims["id"]), 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	// Return the ID as a uint32
+	return uint32(uid), nil
+}
+
+// ExtractTokenClaims validates the request token once and returns everything it carries
+func (t *Tokens) ExtractTokenClaims(r *http.Request) (*Claims, error) {
+	claims, err := t.parseToken(r)
+	if err != nil {
+		return nil, err
+	}
+	uid, err := strconv.ParseUint(fmt.Sprintf("%.0f", claims["id"]), 10, 32)
+	if err != nil {
+		return nil, err
+	}
+	// tokens issued before roles existed belong to regular users
+	role, ok := claims["role"].(string)
+	if !ok || role == "" {
+		role = "user"
+	}
+	return &Claims{
+		UserID:   uint32(uid),
+		Role:     role,
+		FamilyID: claims["fid"].(string),
+	}, nil
+}
+
+// ExtractTokenFamilyID returns the refresh token family the access token was issued for
+func (t *Tokens) ExtractTokenFamilyID(r *http.Request) (string, error) {
+	claims, err := t.parseToken(r)
+	if err != nil {
+		return "", err
+	}
+	return claims["fid"].(string), nil
+}
+
+// parseToken verifies the signature, expiry and revocation state of the request token
+func (t *Tokens) parseToken(r *http.Request) (jwt.MapClaims, error) {
 	// Extract the token string from the request
 	tokenString := ExtractToken(r)
-	// Parse the token using the API_SECRET as the key
+	// Parse the token using the configured secret as the key
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		// Ensure that the signing method is HMAC
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
 		}
-		// Return the API_SECRET as the key
-		return []byte(os.Getenv("API_SECRET")), nil
+		// Return the configured secret as the key
+		return []byte(t.settings.Secret), nil
 	})
 	if err != nil {
-		// Return any errors encountered during parsing
-		return 0, err
+		return nil, err
 	}
-	// Extract the claims from the token and convert the "id" claim to a uint32
 	claims, ok := token.Claims.(jwt.MapClaims)
-	if ok && token.Valid {
-		uid, err := strconv.ParseUint(fmt.Sprintf("%.0f", claims["id"]), 10, 32)
-		if err != nil {
-			return 0, err
-		}
-		// Return the ID as a uint32
-		return uint32(uid), nil
+	if !ok || !token.Valid {
+		return nil, ErrInvalidToken
 	}
-	// Return 0 and nil if the token is invalid or does not contain an ID claim
-	return 0, nil
-}
-
-// Pretty display the claims licely in the terminal
-func Pretty(data interface{}) {
-	b, err := json.MarshalIndent(data, "", " ")
-	if err != nil {
-		log.Println(err)
-		return
+	// jwt-go only checks "exp" when it is present, tokens issued before expiry was introduced never expire
+	if _, ok := claims["exp"]; !ok {
+		return nil, ErrInvalidToken
 	}
-
-	fmt.Println(string(b))
+	familyID, ok := claims["fid"].(string)
+	if !ok || familyID == "" {
+		return nil, ErrInvalidToken
+	}
+	if t.revocations != nil && t.revocations.FamilyRevoked(familyID) {
+		return nil, ErrRevokedToken
+	}
+	return claims, nil
 }
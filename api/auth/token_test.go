@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/config"
+)
+
+type revokedFamily string
+
+func (f revokedFamily) FamilyRevoked(familyID string) bool { return familyID == string(f) }
+
+func newTestTokens(secret string, ttl time.Duration, revocations RevocationStore) *Tokens {
+	cfg := config.Default().Auth
+	cfg.Secret = secret
+	cfg.AccessTokenTTL = ttl
+	return NewTokens(cfg, revocations)
+}
+
+func TestTokens(t *testing.T) {
+	tokens := newTestTokens("first-secret", time.Minute, revokedFamily("logged-out"))
+	token, err := tokens.CreateToken(7, "admin", "family")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	claims, err := tokens.ExtractTokenClaims(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *claims != (Claims{UserID: 7, Role: "admin", FamilyID: "family"}) {
+		t.Errorf("got claims %+v", *claims)
+	}
+
+	// every Tokens checks with its own secret
+	other := newTestTokens("second-secret", time.Minute, nil)
+	if _, err = other.ExtractTokenClaims(r); err == nil {
+		t.Error("a token signed with another secret is accepted")
+	}
+
+	revoked, _ := tokens.CreateToken(7, "admin", "logged-out")
+	r.Header.Set("Authorization", "Bearer "+revoked)
+	if _, err = tokens.ExtractTokenID(r); err != ErrRevokedToken {
+		t.Errorf("token of a revoked family: got %v, want ErrRevokedToken", err)
+	}
+
+	expired, _ := newTestTokens("first-secret", -time.Minute, nil).CreateToken(7, "admin", "family")
+	r.Header.Set("Authorization", "Bearer "+expired)
+	if _, err = tokens.ExtractTokenID(r); err == nil {
+		t.Error("an expired token is accepted")
+	}
+}
@@ -0,0 +1,206 @@
+package api
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// command is a subcommand of the binary. Every command loads the same configuration and opens the database the same way,
+// run gets the arguments after the command name.
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(name string, args []string)
+}
+
+var commands []command
+
+func init() {
+	// assigned in init as runHelp refers back to commands
+	commands = []command{
+		{"serve", "", "serve the API, the default when no command is given", runServe},
+		{"migrate", "up|down|status [-steps n]", "apply, roll back or list the schema migrations", runMigrate},
+		{"seed", "[-reset] demo|test", "upsert a seed set, the server never seeds on its own", runSeed},
+		{"create-admin", "-username name -email address -age n [-password-stdin]", "create a verified admin account", runCreateAdmin},
+		{"reset-password", "-email address | -id n [-password-stdin]", "set a new password and log the user out everywhere", runResetPassword},
+		{"purge-user", "-email address | -id n -yes", "delete a user with everything they own, files included", runPurgeUser},
+		{"export", "-email address | -id n [-o file]", "write everything stored about a user as JSON", runExport},
+		{"backfill-variants", "", "generate the missing variants of uploaded photos", runBackfillVariants},
+		{"rebuild-feed", "", "recompute the precomputed feeds", runRebuildFeed},
+		{"help", "", "list the commands", runHelp},
+	}
+}
+
+// Execute runs the command named by the first argument, without arguments the API is served. The -config flag
+// before the command names a config file for every command, CONFIG_FILE does the same.
+func Execute(args []string) {
+	global := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	global.Usage = printCommands
+	global.StringVar(&configFile, "config", os.Getenv("CONFIG_FILE"), "a config file in the .env format, read after the environment")
+	// stops at the command name, the flags after it belong to the command
+	global.Parse(args)
+	args = global.Args()
+
+	if len(args) == 0 {
+		Run()
+		return
+	}
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			cmd.run(cmd.name, args[1:])
+			return
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", args[0])
+	printCommands()
+	os.Exit(2)
+}
+
+func printCommands() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [-config file] <command> [arguments]\n\nCommands:\n", os.Args[0])
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-18s %s\n", cmd.name, cmd.summary)
+		if cmd.args != "" {
+			fmt.Fprintf(os.Stderr, "  %-18s   %s %s\n", "", cmd.name, cmd.args)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "\nRun %s <command> -h for the flags of a command.\n", os.Args[0])
+}
+
+// newFlags returns the flag set of a command, -h prints its usage line and flags
+func newFlags(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	flags.Usage = func() {
+		for _, cmd := range commands {
+			if cmd.name == name {
+				fmt.Fprintf(flags.Output(), "Usage: %s %s %s\n\n%s\n\n", os.Args[0], cmd.name, cmd.args, cmd.summary)
+			}
+		}
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// userFlags adds -email and -id, the two ways of naming the user a command works on
+func userFlags(flags *flag.FlagSet) (email *string, id *uint) {
+	email = flags.String("email", "", "the email address of the user")
+	id = flags.Uint("id", 0, "the id of the user")
+	return email, id
+}
+
+// usageError prints what was wrong and the usage of the command, then exits like a flag error does
+func usageError(flags *flag.FlagSet, format string, v ...interface{}) {
+	fmt.Fprintf(flags.Output(), format+"\n", v...)
+	flags.Usage()
+	os.Exit(2)
+}
+
+func runServe(name string, args []string) {
+	parseNoArgs(name, args)
+	Run()
+}
+
+func runMigrate(name string, args []string) {
+	flags := newFlags(name)
+	steps := flags.Int("steps", 0, "how many migrations to apply or roll back: 0 applies all, or rolls back one")
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		flags.Parse(args)
+		usageError(flags, "migrate needs up, down or status")
+	}
+	// the direction comes first, its flags after it
+	flags.Parse(args[1:])
+	if flags.NArg() > 0 || *steps < 0 {
+		usageError(flags, "migrate takes a direction and -steps only")
+	}
+	Migrate(args[0], *steps)
+}
+
+func runSeed(name string, args []string) {
+	flags := newFlags(name)
+	reset := flags.Bool("reset", false, "roll back and reapply every migration first: deletes ALL the data")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		usageError(flags, "seed needs the name of one seed set")
+	}
+	Seed(flags.Arg(0), *reset)
+}
+
+func runCreateAdmin(name string, args []string) {
+	flags := newFlags(name)
+	username := flags.String("username", "", "the username of the account")
+	email := flags.String("email", "", "the email address of the account, it is marked verified")
+	age := flags.Uint("age", 0, "the age of the account holder")
+	passwordStdin := flags.Bool("password-stdin", false, "read the password from the first line of stdin instead of generating one")
+	flags.Parse(args)
+	if flags.NArg() > 0 || *username == "" || *email == "" || *age == 0 {
+		usageError(flags, "create-admin needs -username, -email and -age")
+	}
+	CreateAdmin(*username, *email, uint32(*age), *passwordStdin)
+}
+
+func runResetPassword(name string, args []string) {
+	flags := newFlags(name)
+	email, id := userFlags(flags)
+	passwordStdin := flags.Bool("password-stdin", false, "read the password from the first line of stdin instead of generating one")
+	flags.Parse(args)
+	requireUser(flags, *email, *id)
+	ResetPassword(*email, uint32(*id), *passwordStdin)
+}
+
+func runPurgeUser(name string, args []string) {
+	flags := newFlags(name)
+	email, id := userFlags(flags)
+	yes := flags.Bool("yes", false, "confirm the deletion, it cannot be undone")
+	flags.Parse(args)
+	requireUser(flags, *email, *id)
+	if !*yes {
+		usageError(flags, "purge-user deletes the account for good, add -yes to confirm")
+	}
+	PurgeUser(*email, uint32(*id))
+}
+
+func runExport(name string, args []string) {
+	flags := newFlags(name)
+	email, id := userFlags(flags)
+	out := flags.String("o", "", "the file to write, stdout when empty")
+	flags.Parse(args)
+	requireUser(flags, *email, *id)
+	Export(*email, uint32(*id), *out)
+}
+
+func runBackfillVariants(name string, args []string) {
+	parseNoArgs(name, args)
+	BackfillVariants()
+}
+
+func runRebuildFeed(name string, args []string) {
+	parseNoArgs(name, args)
+	RebuildFeed()
+}
+
+func runHelp(name string, args []string) {
+	printCommands()
+}
+
+// parseNoArgs is the parsing of a command without flags, only -h is understood
+func parseNoArgs(name string, args []string) {
+	flags := newFlags(name)
+	flags.Parse(args)
+	if flags.NArg() > 0 {
+		usageError(flags, "%s takes no arguments", name)
+	}
+}
+
+// requireUser checks that exactly one of -email and -id names the user
+func requireUser(flags *flag.FlagSet, email string, id uint) {
+	if flags.NArg() > 0 || (email == "") == (id == 0) {
+		usageError(flags, "name the user with either -email or -id")
+	}
+	if uint64(id) > uint64(^uint32(0)) {
+		log.Fatalf("There is no user %d", id)
+	}
+}
@@ -0,0 +1,376 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// Config is everything the API can be configured with. Each setting is named by the env tag of its field
+// and looked up, first match wins, in the environment, in the config file, in .env and in Default.
+// The config file uses the .env format and the same names.
+type Config struct {
+	App      App
+	HTTP     HTTP
+	Database Database
+	Auth     Auth
+	Mail     Mail
+	Storage  Storage
+	Uploads  Uploads
+	Feed     Feed
+	Realtime Realtime
+}
+
+type App struct {
+	// Env is local, test, staging or production. Production refuses the commands that delete data.
+	Env  string `env:"APP_ENV"`
+	Host string `env:"APP_HOST"`
+	Port string `env:"PORT"`
+	// URL is where clients reach the API, the links of the emails and of local uploads start with it
+	URL string `env:"APP_URL"`
+	// PasswordResetURL is the frontend page the password reset emails link to, with the token as the token query
+	// parameter. The page asks for the new password and posts both to POST /api/v1/password/reset.
+	PasswordResetURL string `env:"PASSWORD_RESET_URL"`
+}
+
+type HTTP struct {
+	// ReadHeaderTimeout limits reading the headers of a request, ReadTimeout the whole request with its upload.
+	// A zero timeout does not limit.
+	ReadHeaderTimeout time.Duration `env:"HTTP_READ_HEADER_TIMEOUT"`
+	ReadTimeout       time.Duration `env:"HTTP_READ_TIMEOUT"`
+	// WriteTimeout limits writing a response, the event streams are left open
+	WriteTimeout time.Duration `env:"HTTP_WRITE_TIMEOUT"`
+	// IdleTimeout is how long a keep-alive connection waits for its next request
+	IdleTimeout time.Duration `env:"HTTP_IDLE_TIMEOUT"`
+	// ShutdownTimeout is how long the requests in flight get to finish after SIGINT or SIGTERM
+	ShutdownTimeout time.Duration `env:"HTTP_SHUTDOWN_TIMEOUT"`
+	// TLSCertFile and TLSKeyFile serve HTTPS when both are set, SIGHUP reloads them
+	TLSCertFile string `env:"TLS_CERT_FILE"`
+	TLSKeyFile  string `env:"TLS_KEY_FILE"`
+}
+
+// TLS reports whether the API is served over HTTPS
+func (h HTTP) TLS() bool {
+	return h.TLSCertFile != ""
+}
+
+type Database struct {
+	// Driver is postgres or mysql
+	Driver   string `env:"DB_DRIVER"`
+	Host     string `env:"PGHOST"`
+	Port     string `env:"PGPORT"`
+	User     string `env:"PGUSER"`
+	Password string `env:"PGPASSWORD" secret:"true"`
+	Name     string `env:"PGDATABASE"`
+}
+
+type Auth struct {
+	// Secret signs the access tokens
+	Secret               string        `env:"API_SECRET" secret:"true"`
+	AccessTokenTTL       time.Duration `env:"ACCESS_TOKEN_TTL"`
+	RefreshTokenTTL      time.Duration `env:"REFRESH_TOKEN_TTL"`
+	PasswordResetTTL     time.Duration `env:"PASSWORD_RESET_TTL"`
+	EmailVerificationTTL time.Duration `env:"EMAIL_VERIFICATION_TTL"`
+	// RequireVerifiedEmail blocks unverified accounts from creating content
+	RequireVerifiedEmail bool `env:"REQUIRE_EMAIL_VERIFICATION"`
+}
+
+type Mail struct {
+	// Driver is sendgrid, smtp or memory
+	Driver         string `env:"MAIL_DRIVER"`
+	FromAddress    string `env:"MAIL_FROM_ADDRESS"`
+	FromName       string `env:"MAIL_FROM_NAME"`
+	SendGridAPIKey string `env:"SENDGRID_API_KEY" secret:"true"`
+	SMTPHost       string `env:"SMTP_HOST"`
+	SMTPPort       string `env:"SMTP_PORT"`
+	SMTPUsername   string `env:"SMTP_USERNAME"`
+	SMTPPassword   string `env:"SMTP_PASSWORD" secret:"true"`
+}
+
+type Storage struct {
+	// Driver is local, s3 or memory
+	Driver   string `env:"STORAGE_DRIVER"`
+	LocalDir string `env:"STORAGE_LOCAL_DIR"`
+	// PublicURL is where the stored files are downloaded from, for local storage it is APP_URL/uploads by default
+	PublicURL        string `env:"STORAGE_PUBLIC_URL"`
+	S3Endpoint       string `env:"S3_ENDPOINT"`
+	S3Region         string `env:"S3_REGION"`
+	S3Bucket         string `env:"S3_BUCKET"`
+	S3AccessKey      string `env:"S3_ACCESS_KEY"`
+	S3SecretKey      string `env:"S3_SECRET_KEY" secret:"true"`
+	S3ForcePathStyle bool   `env:"S3_FORCE_PATH_STYLE"`
+}
+
+type Uploads struct {
+	// MaxSize is the largest photo accepted, in bytes
+	MaxSize int64 `env:"MAX_UPLOAD_SIZE"`
+	// MaxPixels is the largest width times height accepted, a small file can declare a huge image
+	MaxPixels       int64 `env:"MAX_UPLOAD_PIXELS"`
+	StripMetadata   bool  `env:"STRIP_METADATA"`
+	ExtractMetadata bool  `env:"EXTRACT_METADATA"`
+	AutoOrient      bool  `env:"AUTO_ORIENT"`
+	// VariantWorkers is how many photos get their resized copies generated at the same time
+	VariantWorkers int `env:"VARIANT_WORKERS"`
+}
+
+type Feed struct {
+	// Strategy is read (fan-out on read) or write (fan-out on write)
+	Strategy string `env:"FEED_STRATEGY"`
+	// BackfillLimit is how many photos a new follow copies into a precomputed feed
+	BackfillLimit int `env:"FEED_BACKFILL_LIMIT"`
+}
+
+type Realtime struct {
+	// Broker is memory (a single instance) or postgres (several instances)
+	Broker string `env:"REALTIME_BROKER"`
+}
+
+// Default is the configuration before anything is read, every setting without a default has to be given
+func Default() Config {
+	return Config{
+		App: App{Env: "local", Port: "8080"},
+		HTTP: HTTP{
+			ReadHeaderTimeout: 10 * time.Second,
+			ReadTimeout:       time.Minute,
+			WriteTimeout:      time.Minute,
+			IdleTimeout:       2 * time.Minute,
+			ShutdownTimeout:   30 * time.Second,
+		},
+		Database: Database{Driver: "postgres", Host: "127.0.0.1", Port: "5432", User: "postgres"},
+		Auth: Auth{
+			AccessTokenTTL:       15 * time.Minute,
+			RefreshTokenTTL:      7 * 24 * time.Hour,
+			PasswordResetTTL:     time.Hour,
+			EmailVerificationTTL: 48 * time.Hour,
+		},
+		Mail:     Mail{Driver: "memory", FromName: "MyGram"},
+		Storage:  Storage{Driver: "local", LocalDir: "uploads"},
+		Uploads:  Uploads{MaxSize: 10 << 20, MaxPixels: 40000000, StripMetadata: true, ExtractMetadata: true, AutoOrient: true, VariantWorkers: 2},
+		Feed:     Feed{Strategy: "read", BackfillLimit: 100},
+		Realtime: Realtime{Broker: "memory"},
+	}
+}
+
+// Load reads the configuration and validates it. file is the optional config file, .env is read when it exists.
+func Load(file string) (*Config, error) {
+	dotenv, err := godotenv.Read(".env")
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("cannot read .env: %v", err)
+	}
+	fromFile := map[string]string{}
+	if file != "" {
+		if fromFile, err = godotenv.Read(file); err != nil {
+			return nil, fmt.Errorf("cannot read the config file: %v", err)
+		}
+	}
+	// lookup also tells whether the value came from .env
+	lookup := func(name string) (string, bool, bool) {
+		// an empty value keeps the default, like an unset one
+		for i, value := range []string{os.Getenv(name), fromFile[name], dotenv[name]} {
+			if value = strings.TrimSpace(value); value != "" {
+				return value, i == 2, true
+			}
+		}
+		return "", false, false
+	}
+
+	cfg := Default()
+	problems := []string{}
+	secretsFromDotenv := []string{}
+	fields(reflect.ValueOf(&cfg).Elem(), func(field reflect.Value, name string, secret bool) {
+		value, fromDotenv, ok := lookup(name)
+		if !ok {
+			return
+		}
+		if secret && fromDotenv {
+			secretsFromDotenv = append(secretsFromDotenv, name)
+		}
+		if err := set(field, value); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", name, err))
+		}
+	})
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid configuration: %s", strings.Join(problems, "; "))
+	}
+	cfg.normalize()
+	if err = cfg.Validate(); err != nil {
+		return nil, err
+	}
+	// .env is the local development setup copied from .env.example, the secrets in it are not meant to be kept
+	if cfg.App.IsProduction() && len(secretsFromDotenv) > 0 {
+		return nil, fmt.Errorf("invalid configuration: %s cannot come from .env in production, set them in the environment or the config file",
+			strings.Join(secretsFromDotenv, ", "))
+	}
+	return &cfg, nil
+}
+
+// normalize fills in the settings that default to others
+func (c *Config) normalize() {
+	c.App.Env = strings.ToLower(c.App.Env)
+	c.Database.Driver = strings.ToLower(c.Database.Driver)
+	c.Mail.Driver = strings.ToLower(c.Mail.Driver)
+	c.Storage.Driver = strings.ToLower(c.Storage.Driver)
+	c.Feed.Strategy = strings.ToLower(c.Feed.Strategy)
+	c.Realtime.Broker = strings.ToLower(c.Realtime.Broker)
+	if c.App.URL == "" && c.HTTP.TLS() {
+		c.App.URL = "https://localhost:" + c.App.Port
+	}
+	if c.App.URL == "" {
+		c.App.URL = "http://localhost:" + c.App.Port
+	}
+	c.App.URL = strings.TrimRight(c.App.URL, "/")
+	// the memory mail driver delivers nothing, its links are only read by tests
+	if c.App.PasswordResetURL == "" && c.Mail.Driver == "memory" {
+		c.App.PasswordResetURL = c.App.URL + "/password/reset"
+	}
+	if c.Storage.Driver == "local" && c.Storage.PublicURL == "" {
+		c.Storage.PublicURL = c.App.URL + "/uploads"
+	}
+}
+
+// publishedSecrets are the API_SECRET values that were committed with the code, they sign nothing in production
+var publishedSecrets = map[string]bool{
+	"local-development-secret-change-me": true,
+}
+
+// Validate lists every setting that is missing or wrong, rather than stopping at the first one
+func (c *Config) Validate() error {
+	problems := []string{}
+	check := func(ok bool, format string, v ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, v...))
+		}
+	}
+
+	check(c.App.Port != "", "PORT is required")
+	if _, err := strconv.ParseUint(c.App.Port, 10, 16); c.App.Port != "" && err != nil {
+		check(false, "PORT %q is not a port number", c.App.Port)
+	}
+
+	// without it a client sending its headers a byte at a time holds the connection forever
+	check(c.HTTP.ReadHeaderTimeout > 0, "HTTP_READ_HEADER_TIMEOUT should be positive")
+	check(c.HTTP.ReadTimeout >= 0, "HTTP_READ_TIMEOUT cannot be negative")
+	check(c.HTTP.WriteTimeout >= 0, "HTTP_WRITE_TIMEOUT cannot be negative")
+	check(c.HTTP.IdleTimeout >= 0, "HTTP_IDLE_TIMEOUT cannot be negative")
+	check(c.HTTP.ShutdownTimeout > 0, "HTTP_SHUTDOWN_TIMEOUT should be positive")
+	check((c.HTTP.TLSCertFile == "") == (c.HTTP.TLSKeyFile == ""), "TLS_CERT_FILE and TLS_KEY_FILE are set together")
+
+	check(c.Database.Driver == "postgres" || c.Database.Driver == "mysql", "DB_DRIVER %q is neither postgres nor mysql", c.Database.Driver)
+	check(c.Database.Host != "", "PGHOST is required")
+	check(c.Database.Name != "", "PGDATABASE is required")
+
+	check(c.Auth.Secret != "", "API_SECRET is required, access tokens cannot be signed without it")
+	check(!c.App.IsProduction() || len(c.Auth.Secret) >= 32, "API_SECRET should be at least 32 characters in production")
+	check(!c.App.IsProduction() || !publishedSecrets[c.Auth.Secret], "API_SECRET is an example value anyone can sign tokens with, generate one for production")
+	check(c.Auth.AccessTokenTTL > 0, "ACCESS_TOKEN_TTL should be positive")
+	check(c.Auth.RefreshTokenTTL > 0, "REFRESH_TOKEN_TTL should be positive")
+	check(c.Auth.PasswordResetTTL > 0, "PASSWORD_RESET_TTL should be positive")
+	check(c.Auth.EmailVerificationTTL > 0, "EMAIL_VERIFICATION_TTL should be positive")
+
+	switch c.Mail.Driver {
+	case "memory":
+	case "sendgrid":
+		check(c.Mail.SendGridAPIKey != "", "SENDGRID_API_KEY is required by the sendgrid mail driver")
+	case "smtp":
+		check(c.Mail.SMTPHost != "", "SMTP_HOST is required by the smtp mail driver")
+	default:
+		check(false, "MAIL_DRIVER %q is not sendgrid, smtp or memory", c.Mail.Driver)
+	}
+	check(c.Mail.Driver == "memory" || c.Mail.FromAddress != "", "MAIL_FROM_ADDRESS is required to send emails")
+	// the API has no page to open the link with, only the frontend has
+	check(c.Mail.Driver == "memory" || c.App.PasswordResetURL != "", "PASSWORD_RESET_URL is required to send password reset emails")
+
+	switch c.Storage.Driver {
+	case "local":
+		check(c.Storage.LocalDir != "", "STORAGE_LOCAL_DIR is required by the local storage driver")
+	case "s3":
+		check(c.Storage.S3Bucket != "", "S3_BUCKET is required by the s3 storage driver")
+	case "memory":
+	default:
+		check(false, "STORAGE_DRIVER %q is not local, s3 or memory", c.Storage.Driver)
+	}
+
+	check(c.Uploads.MaxSize > 0, "MAX_UPLOAD_SIZE should be positive")
+	check(c.Uploads.MaxPixels > 0, "MAX_UPLOAD_PIXELS should be positive")
+	check(c.Uploads.VariantWorkers > 0, "VARIANT_WORKERS should be positive")
+
+	check(c.Feed.Strategy == "read" || c.Feed.Strategy == "write", "FEED_STRATEGY %q is neither read nor write", c.Feed.Strategy)
+	check(c.Feed.BackfillLimit >= 0, "FEED_BACKFILL_LIMIT cannot be negative")
+
+	check(c.Realtime.Broker == "memory" || c.Realtime.Broker == "postgres", "REALTIME_BROKER %q is neither memory nor postgres", c.Realtime.Broker)
+	check(c.Realtime.Broker != "postgres" || c.Database.Driver == "postgres", "REALTIME_BROKER postgres needs DB_DRIVER postgres")
+
+	if len(problems) > 0 {
+		return errors.New("invalid configuration: " + strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// IsProduction reports whether APP_ENV is production
+func (a App) IsProduction() bool {
+	return a.Env == "production"
+}
+
+// Addr is the address the API listens on
+func (a App) Addr() string {
+	return a.Host + ":" + a.Port
+}
+
+// String lists the settings one per line, the secrets are redacted so the result can be logged
+func (c Config) String() string {
+	var b strings.Builder
+	fields(reflect.ValueOf(&c).Elem(), func(field reflect.Value, name string, secret bool) {
+		value := fmt.Sprint(field.Interface())
+		if secret && value != "" {
+			value = "[redacted]"
+		}
+		fmt.Fprintf(&b, "%s=%s\n", name, value)
+	})
+	return b.String()
+}
+
+// fields calls visit with every setting of the sections of v, in the order they are declared
+func fields(v reflect.Value, visit func(field reflect.Value, name string, secret bool)) {
+	for i := 0; i < v.NumField(); i++ {
+		section := v.Field(i)
+		for j := 0; j < section.NumField(); j++ {
+			tag := section.Type().Field(j).Tag
+			visit(section.Field(j), tag.Get("env"), tag.Get("secret") == "true")
+		}
+	}
+}
+
+// set parses value into the field according to its type
+func set(field reflect.Value, value string) error {
+	switch field.Interface().(type) {
+	case string:
+		field.SetString(value)
+	case bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", value)
+		}
+		field.SetBool(b)
+	case time.Duration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration like 15m or 48h", value)
+		}
+		field.SetInt(int64(d))
+	case int, int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a whole number", value)
+		}
+		field.SetInt(n)
+	default:
+		return fmt.Errorf("unsupported setting type %s", field.Type())
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// inDir runs the test from a directory holding the given .env
+func inDir(t *testing.T, dotenv string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte(dotenv), 0600); err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+const generatedSecret = "9f2c4e1a7b3d8f6e0c5a2b9d4e7f1a3c"
+
+func TestProductionSecrets(t *testing.T) {
+	t.Setenv("PGDATABASE", "mygram")
+	t.Setenv("APP_ENV", "production")
+	// an empty variable is the same as an unset one
+	t.Setenv("API_SECRET", "")
+
+	inDir(t, "API_SECRET="+generatedSecret+"\n")
+	if _, err := Load(""); err == nil || !strings.Contains(err.Error(), "API_SECRET cannot come from .env") {
+		t.Errorf("production secret from .env: got %v", err)
+	}
+
+	t.Setenv("API_SECRET", "local-development-secret-change-me")
+	if _, err := Load(""); err == nil || !strings.Contains(err.Error(), "example value") {
+		t.Errorf("published secret in production: got %v", err)
+	}
+
+	t.Setenv("API_SECRET", generatedSecret)
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("production secret from the environment: %v", err)
+	}
+	if cfg.Auth.Secret != generatedSecret {
+		t.Errorf("got the secret %q, want the one of the environment", cfg.Auth.Secret)
+	}
+}
+
+func TestLocalSecretFromDotenv(t *testing.T) {
+	t.Setenv("PGDATABASE", "mygram")
+	t.Setenv("APP_ENV", "local")
+	t.Setenv("API_SECRET", "")
+	inDir(t, "API_SECRET=local-development-secret-change-me\n")
+
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("local setup from .env: %v", err)
+	}
+	if cfg.Auth.Secret != "local-development-secret-change-me" {
+		t.Errorf("got the secret %q, want the one of .env", cfg.Auth.Secret)
+	}
+}
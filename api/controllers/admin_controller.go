@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/models"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/policy"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/responses"
+	"github.com/gin-gonic/gin"
+)
+
+// UpdateUserRole godoc
+// @Summary     Update User Role
+// @Description Change the role (user, moderator, admin) of a user. Admin only.
+// @Tags        Admin
+// @Accept      json
+// @Produce     json
+// @Param       id path int true "User ID"
+// @Param       UpdateRole body models.UpdateRole true "Role"
+// @Security ApiKeyAuth
+// @Success     200  {object} responses.User
+// @Router      /admin/users/{id}/role [put]
+func (server *Server) UpdateUserRole(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	userID := c.Param("id")
+	uid, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		errList["Invalid_request"] = "Invalid Request"
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  errList,
+		})
+		return
+	}
+
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		errList["Invalid_body"] = "Unable to get request"
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+	input := models.UpdateRole{}
+	err = json.Unmarshal(body, &input)
+	if err != nil {
+		errList["Unmarshal_error"] = "Cannot unmarshal body"
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+	role := strings.ToLower(strings.TrimSpace(input.Role))
+	if !policy.ValidRole(role) {
+		errList["Invalid_role"] = "Role should be one of user, moderator or admin"
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+
+	user := models.User{}
+	_, err = user.FindUserByID(server.DB, uint32(uid))
+	if err != nil {
+		errList["No_user"] = "No User Found"
+		c.JSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  errList,
+		})
+		return
+	}
+	err = user.UpdateRole(server.DB, role)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+
+	// the role lives in the access token, end the user's sessions so it takes effect now
+	refreshToken := models.RefreshToken{}
+	_, err = refreshToken.RevokeUserTokens(server.DB, user.ID)
+	if err != nil {
+		fmt.Println("this is the error revoking the user sessions: ", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":   http.StatusOK,
+		"response": responses.NewUser(user, server.viewer(c)),
+	})
+}
@@ -0,0 +1,33 @@
+package controllers
+
+import (
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/auth"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/middlewares"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/policy"
+	"github.com/gin-gonic/gin"
+)
+
+// canModify applies policy.CanModify to the user authenticated by TokenAuthMiddleware
+func (server *Server) canModify(c *gin.Context, ownerID uint32, permission policy.Permission) bool {
+	actor, ok := middlewares.CurrentActor(c)
+	if !ok {
+		return false
+	}
+	return policy.CanModify(actor, ownerID, permission)
+}
+
+// viewer is the user a response is rendered for. Public routes do not run TokenAuthMiddleware,
+// so a token is honoured when one is sent but a missing or invalid token just means anonymous.
+func (server *Server) viewer(c *gin.Context) policy.Actor {
+	if actor, ok := middlewares.CurrentActor(c); ok {
+		return actor
+	}
+	if auth.ExtractToken(c.Request) == "" {
+		return policy.Actor{}
+	}
+	claims, err := server.Tokens.ExtractTokenClaims(c.Request)
+	if err != nil {
+		return policy.Actor{}
+	}
+	return policy.Actor{ID: claims.UserID, Role: claims.Role}
+}
@@ -3,9 +3,17 @@ package controllers
 import (
 	"fmt"
 	"log"
-	"net/http"
+	"sync"
 
-	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/models"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/auth"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/config"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/feed"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/mailer"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/migrations"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/notify"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/realtime"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/storage"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/variants"
 	"github.com/gin-gonic/gin"
 	"github.com/jinzhu/gorm"
 	_ "github.com/jinzhu/gorm/dialects/mysql"    //mysql database driver
@@ -15,18 +23,104 @@ import (
 type Server struct {
 	DB     *gorm.DB
 	Router *gin.Engine
+	// Tokens issues the access tokens and validates those of the requests
+	Tokens  *auth.Tokens
+	Mailer  mailer.Sender
+	Storage storage.Storage
+	// Variants generates resized copies of uploaded photos in the background
+	Variants *variants.Generator
+	Feed     feed.Feed
+	// Notifier turns what users do into notifications for the users concerned
+	Notifier *notify.Notifier
+	// Hub pushes new comments, like counts and notifications to the clients of GET /stream
+	Hub *realtime.Hub
+
+	// Config is the configuration the server was initialized with
+	Config *config.Config
+
+	// dbURL is the connection string the database was opened with
+	dbURL string
+	// mail tracks the emails being sent
+	mail sync.WaitGroup
+	// handlers tracks the requests being handled
+	handlers sync.WaitGroup
 }
 
 var errList = make(map[string]string)
 
-func (server *Server) Initialize(Dbdriver, DbUser, DbPassword, DbPort, DbHost, DbName string) {
+// Initialize opens the database and builds the API on top of it, what serving and the background commands need
+func (server *Server) Initialize(cfg *config.Config) {
+
+	server.Open(cfg)
+	server.setup(cfg)
+}
+
+// Open connects to the database and refuses a schema that is behind, nothing else is started: it is all the
+// admin commands need, they do not wait for workers, mail or the realtime broker
+func (server *Server) Open(cfg *config.Config) {
+
+	server.Connect(cfg.Database)
+
+	// the schema is only changed by the migrate command, never by serving
+	pending, err := migrations.Pending(server.DB)
+	if err != nil {
+		log.Fatal("This is the error reading the schema migrations:", err)
+	}
+	if len(pending) > 0 {
+		log.Fatalf("The database is %d migrations behind, from %d %s: run the migrate up command first", len(pending), pending[0].Version, pending[0].Name)
+	}
+	server.Config = cfg
+}
+
+// setup builds everything the API needs on top of the open database
+func (server *Server) setup(cfg *config.Config) {
+
+	var err error
+	server.Config = cfg
+
+	// access tokens of a logged out session are rejected as well
+	server.Tokens = auth.NewTokens(cfg.Auth, revokedFamilies{db: server.DB})
+
+	mailer.SetProductLink(cfg.App.URL)
+	server.Mailer = mailer.NewSender(cfg.Mail)
+
+	server.Storage, err = storage.New(cfg.Storage)
+	if err != nil {
+		log.Fatal("This is the error setting up the photo storage:", err)
+	}
+	server.Variants = variants.NewGenerator(server.DB, server.Storage, cfg.Uploads.VariantWorkers, cfg.Uploads.MaxPixels)
+	server.Feed, err = feed.New(server.DB, cfg.Feed)
+	if err != nil {
+		log.Fatal("This is the error setting up the feed:", err)
+	}
+	server.Notifier = notify.NewNotifier(server.DB)
+
+	broker, err := realtime.NewBroker(server.DB, server.dbURL, cfg.Realtime)
+	if err != nil {
+		log.Fatal("This is the error setting up the realtime broker:", err)
+	}
+	server.Hub, err = realtime.NewHub(broker)
+	if err != nil {
+		log.Fatal("This is the error setting up the realtime hub:", err)
+	}
+	server.Notifier.Listen(server.publishNotification)
+
+	server.Router = gin.Default()
+	server.Router.Use(server.inFlight, server.writeDeadline)
+
+	server.initializeRoutes()
+}
+
+// Connect opens the database and nothing else, it is all the migrate and seed commands need
+func (server *Server) Connect(cfg config.Database) {
 
 	var err error
+	Dbdriver, DbUser, DbPassword, DbPort, DbHost, DbName := cfg.Driver, cfg.User, cfg.Password, cfg.Port, cfg.Host, cfg.Name
 
 	// If you are using mysql, i added support for you here(dont forgot to edit the .env file)
 	if Dbdriver == "mysql" {
-		DBURL := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8&parseTime=True&loc=Local", DbUser, DbPassword, DbHost, DbPort, DbName)
-		server.DB, err = gorm.Open(Dbdriver, DBURL)
+		server.dbURL = fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8&parseTime=True&loc=Local", DbUser, DbPassword, DbHost, DbPort, DbName)
+		server.DB, err = gorm.Open(Dbdriver, server.dbURL)
 		if err != nil {
 			fmt.Printf("Cannot connect to %s database", Dbdriver)
 			log.Fatal("This is the error:", err)
@@ -34,8 +128,8 @@ func (server *Server) Initialize(Dbdriver, DbUser, DbPassword, DbPort, DbHost, D
 			fmt.Printf("We are connected to the %s database", Dbdriver)
 		}
 	} else if Dbdriver == "postgres" {
-		DBURL := fmt.Sprintf("host=%s port=%s user=%s dbname=%s sslmode=disable password=%s", DbHost, DbPort, DbUser, DbName, DbPassword)
-		server.DB, err = gorm.Open(Dbdriver, DBURL)
+		server.dbURL = fmt.Sprintf("host=%s port=%s user=%s dbname=%s sslmode=disable password=%s", DbHost, DbPort, DbUser, DbName, DbPassword)
+		server.DB, err = gorm.Open(Dbdriver, server.dbURL)
 		if err != nil {
 			fmt.Printf("Cannot connect to %s database", Dbdriver)
 			log.Fatal("This is the error connecting to postgres:", err)
@@ -43,20 +137,17 @@ func (server *Server) Initialize(Dbdriver, DbUser, DbPassword, DbPort, DbHost, D
 			fmt.Printf("We are connected to the %s database", Dbdriver)
 		}
 	} else {
-		fmt.Println("Unknown Driver")
+		log.Fatalf("Unknown Driver %q", Dbdriver)
 	}
-
-	//database migration
-	server.DB.Debug().AutoMigrate(
-		&models.User{},
-	)
-
-	server.Router = gin.Default()
-
-	server.initializeRoutes()
-
 }
 
-func (server *Server) Run(addr string) {
-	log.Fatal(http.ListenAndServe(addr, server.Router))
+// sendMail delivers in the background so slow mail providers do not hold up the request, Close waits for it
+func (server *Server) sendMail(msg mailer.Message) {
+	server.mail.Add(1)
+	go func() {
+		defer server.mail.Done()
+		if err := server.Mailer.Send(msg); err != nil {
+			fmt.Printf("Cannot send %q to %s: %v\n", msg.Subject, msg.ToEmail, err)
+		}
+	}()
 }
@@ -6,13 +6,19 @@ import (
 	"io/ioutil"
 	"net/http"
 	"strconv"
+	"strings"
 
-	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/auth"
 	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/models"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/policy"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/responses"
 	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/utils/formaterror"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/utils/pagination"
 	"github.com/gin-gonic/gin"
 )
 
+// embeddedComments is how many comments ?include=comments embeds in every photo
+var embeddedComments = 3
+
 // CreateComment godoc
 // @Summary     Create Comment
 // @Description Add a new Comment
@@ -22,7 +28,7 @@ import (
 // @Param       CreateComment body models.CreateComment true "Comment Data"
 // @Param id path int true "Comment ID"
 // @Security ApiKeyAuth
-// @Success     200  {object} models.Comment
+// @Success     200  {object} responses.Comment
 // @Router      /comments/{id} [post]
 func (server *Server) CreateComment(c *gin.Context) {
 
@@ -40,7 +46,7 @@ func (server *Server) CreateComment(c *gin.Context) {
 		return
 	}
 
-	uid, err := auth.ExtractTokenID(c.Request)
+	uid, err := server.Tokens.ExtractTokenID(c.Request)
 	if err != nil {
 		errList["Unauthorized"] = "Unauthorized"
 		c.JSON(http.StatusUnauthorized, gin.H{
@@ -97,6 +103,10 @@ func (server *Server) CreateComment(c *gin.Context) {
 	// enter the userid and the photoid. The comment body is automatically passed
 	comment.UserID = uid
 	comment.PhotoID = pid
+	// replies are made through POST /comments/:id/replies
+	comment.ParentID = nil
+	comment.Depth = 0
+	comment.Deleted = false
 
 	comment.Prepare()
 	errorMessages := comment.Validate()
@@ -118,9 +128,13 @@ func (server *Server) CreateComment(c *gin.Context) {
 		})
 		return
 	}
+	server.publishComment(*commentCreated)
+	if err = server.Notifier.CommentCreated(*commentCreated); err != nil {
+		fmt.Println("this is the error notifying about the comment: ", err)
+	}
 	c.JSON(http.StatusCreated, gin.H{
 		"status":   http.StatusCreated,
-		"response": commentCreated,
+		"response": responses.NewComment(*commentCreated, server.viewer(c)),
 	})
 }
 
@@ -130,13 +144,26 @@ func (server *Server) CreateComment(c *gin.Context) {
 // @Tags Comment
 // @Accept json
 // @Produce json
-// @Success 200 {array} models.Comment
+// @Success 200 {array} responses.Comment
+// @Param cursor query string false "Cursor from the pagination of a previous page"
+// @Param limit query int false "Page size, at most 100" default(20)
+// @Param sort query string false "created_at or id, prefixed with - for descending order" default(-created_at)
+// @Param user_id query int false "Only items of this user"
+// @Param created_after query string false "Only items created after this time (RFC3339 or YYYY-MM-DD)"
+// @Param created_before query string false "Only items created before this time (RFC3339 or YYYY-MM-DD)"
 // @Router /comments [get]
 func (server *Server) GetComments(c *gin.Context) {
 
+	//clear previous error if any
+	errList = map[string]string{}
+
+	page, filter, ok := listParams(c, models.CommentSortKeys)
+	if !ok {
+		return
+	}
 	comment := models.Comment{}
 
-	comments, err := comment.FindAllComments(server.DB)
+	comments, links, err := comment.FindAllComments(server.DB, page, filter)
 	if err != nil {
 		errList["No_comment"] = "No Comment Found"
 		c.JSON(http.StatusNotFound, gin.H{
@@ -145,9 +172,100 @@ func (server *Server) GetComments(c *gin.Context) {
 		})
 		return
 	}
+	if err = server.fillCommentLikes(c, *comments); err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
-		"status":   http.StatusOK,
-		"response": comments,
+		"status":     http.StatusOK,
+		"response":   responses.NewComments(*comments, server.viewer(c)),
+		"pagination": links,
+	})
+}
+
+// GetPhotoComments godoc
+// @Summary Get Photo Comments
+// @Description Retrieve the comments of a photo. With a view, the top level comments are paged and come with their first replies
+// @Tags Comment
+// @Accept json
+// @Produce json
+// @Param id path int true "Photo ID"
+// @Param view query string false "flat for the threads depth first, tree for the replies nested in their comment; by default every comment is listed on its own" Enums(flat, tree)
+// @Param cursor query string false "Cursor from the pagination of a previous page"
+// @Param limit query int false "Page size, at most 100" default(20)
+// @Param sort query string false "created_at or id, prefixed with - for descending order" default(-created_at)
+// @Param user_id query int false "Only items of this user"
+// @Param created_after query string false "Only items created after this time (RFC3339 or YYYY-MM-DD)"
+// @Param created_before query string false "Only items created before this time (RFC3339 or YYYY-MM-DD)"
+// @Success 200 {array} responses.Comment
+// @Router /photos/{id}/comments [get]
+func (server *Server) GetPhotoComments(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	pid, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		errList["Invalid_request"] = "Invalid Request"
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  errList,
+		})
+		return
+	}
+	view, ok := threadView(c, "")
+	if !ok {
+		return
+	}
+	page, filter, ok := listParams(c, models.CommentSortKeys)
+	if !ok {
+		return
+	}
+	err = server.DB.Debug().Model(models.Photo{}).Where("id = ?", pid).Take(&models.Photo{}).Error
+	if err != nil {
+		errList["No_photo"] = "No Photo Found"
+		c.JSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  errList,
+		})
+		return
+	}
+	comment := models.Comment{}
+
+	var comments *[]models.Comment
+	var links pagination.Links
+	if view == "" {
+		comments, links, err = comment.FindPhotoComments(server.DB, pid, page, filter)
+	} else {
+		comments, links, err = comment.FindPhotoThreads(server.DB, pid, page, filter)
+	}
+	if err != nil {
+		errList["No_comment"] = "No Comment Found"
+		c.JSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  errList,
+		})
+		return
+	}
+	if err = server.fillCommentLikes(c, *comments); err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	if view == "tree" {
+		*comments = models.NestThreads(*comments)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":     http.StatusOK,
+		"response":   responses.NewComments(*comments, server.viewer(c)),
+		"pagination": links,
 	})
 }
 
@@ -158,7 +276,7 @@ func (server *Server) GetComments(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path int true "Comment ID"
-// @Success 200 {object} models.Comment
+// @Success 200 {object} responses.Comment
 // @Router /comments/{id} [get]
 func (server *Server) GetComment(c *gin.Context) {
 
@@ -183,10 +301,18 @@ func (server *Server) GetComment(c *gin.Context) {
 		})
 		return
 	}
-
+	comments := []models.Comment{*commentReceived}
+	if err = server.fillCommentLikes(c, comments); err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"status":   http.StatusOK,
-		"response": commentReceived,
+		"response": responses.NewComment(comments[0], server.viewer(c)),
 	})
 }
 
@@ -199,7 +325,7 @@ func (server *Server) GetComment(c *gin.Context) {
 // @Param id path int true "Comment ID"
 // @Param UpdateComment body models.UpdateComment true "Comment Data"
 // @Security ApiKeyAuth
-// @Success 200 {object} models.Comment
+// @Success 200 {object} responses.Comment
 // @Router /comments/{id} [put]
 func (server *Server) UpdateComment(c *gin.Context) {
 
@@ -217,19 +343,9 @@ func (server *Server) UpdateComment(c *gin.Context) {
 		})
 		return
 	}
-	//CHeck if the auth token is valid and  get the user id from it
-	uid, err := auth.ExtractTokenID(c.Request)
-	if err != nil {
-		errList["Unauthorized"] = "Unauthorized"
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status": http.StatusUnauthorized,
-			"error":  errList,
-		})
-		return
-	}
 	//Check if the comment exist
 	origComment := models.Comment{}
-	err = server.DB.Debug().Model(models.Comment{}).Where("id = ?", pid).Take(&origComment).Error
+	err = server.DB.Debug().Model(models.Comment{}).Where("id = ? AND deleted = ?", pid, false).Take(&origComment).Error
 	if err != nil {
 		errList["No_comment"] = "No Comment Found"
 		c.JSON(http.StatusNotFound, gin.H{
@@ -238,7 +354,8 @@ func (server *Server) UpdateComment(c *gin.Context) {
 		})
 		return
 	}
-	if uid != origComment.UserID {
+	// owners can change their own comment, moderators can change anyone's
+	if !server.canModify(c, origComment.UserID, policy.ModerateComments) {
 		errList["Unauthorized"] = "Unauthorized"
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"status": http.StatusUnauthorized,
@@ -269,6 +386,10 @@ func (server *Server) UpdateComment(c *gin.Context) {
 	}
 	comment.ID = origComment.ID //this is important to tell the model the comment id to update, the other update field are set above
 	comment.UserID = origComment.UserID
+	comment.PhotoID = origComment.PhotoID
+	comment.ParentID = origComment.ParentID
+	comment.Depth = origComment.Depth
+	comment.Deleted = false
 
 	comment.Prepare()
 	errorMessages := comment.Validate()
@@ -289,9 +410,21 @@ func (server *Server) UpdateComment(c *gin.Context) {
 		})
 		return
 	}
+	if err = server.Notifier.Mentioned(commentUpdated.Mentions); err != nil {
+		fmt.Println("this is the error notifying the mentioned users: ", err)
+	}
+	comments := []models.Comment{*commentUpdated}
+	if err = server.fillCommentLikes(c, comments); err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"status":   http.StatusOK,
-		"response": commentUpdated,
+		"response": responses.NewComment(comments[0], server.viewer(c)),
 	})
 }
 
@@ -321,16 +454,6 @@ func (server *Server) DeleteComment(c *gin.Context) {
 
 	fmt.Println("this is delete comment sir")
 
-	// Is this user authenticated?
-	uid, err := auth.ExtractTokenID(c.Request)
-	if err != nil {
-		errList["Unauthorized"] = "Unauthorized"
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status": http.StatusUnauthorized,
-			"error":  errList,
-		})
-		return
-	}
 	// Check if the comment exist
 	comment := models.Comment{}
 	err = server.DB.Debug().Model(models.Comment{}).Where("id = ?", pid).Take(&comment).Error
@@ -342,8 +465,8 @@ func (server *Server) DeleteComment(c *gin.Context) {
 		})
 		return
 	}
-	// Is the authenticated user, the owner of this comment?
-	if uid != comment.UserID {
+	// Is the authenticated user the owner of this comment, or a moderator?
+	if !server.canModify(c, comment.UserID, policy.ModerateComments) {
 		errList["Unauthorized"] = "Unauthorized"
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"status": http.StatusUnauthorized,
@@ -391,8 +514,50 @@ func (server *Server) GetUserComments(c *gin.Context) {
 		})
 		return
 	}
+	if err = server.fillCommentLikes(c, *comments); err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"status":   http.StatusOK,
-		"response": comments,
+		"response": responses.NewComments(*comments, server.viewer(c)),
 	})
 }
+
+// includeComments embeds the first comments of every photo when the request asks for them with ?include=comments
+func (server *Server) includeComments(c *gin.Context, photos []models.Photo) error {
+	if !includes(c, "comments") {
+		return nil
+	}
+	comment := models.Comment{}
+	if err := comment.FindFirstComments(server.DB, photos, embeddedComments); err != nil {
+		return err
+	}
+	// the likes of all the embedded comments are filled in at once, then handed back to their photos
+	comments := []models.Comment{}
+	for _, photo := range photos {
+		comments = append(comments, photo.Comments...)
+	}
+	if err := server.fillCommentLikes(c, comments); err != nil {
+		return err
+	}
+	for i := range photos {
+		n := len(photos[i].Comments)
+		photos[i].Comments, comments = comments[:n:n], comments[n:]
+	}
+	return nil
+}
+
+// includes reports whether the comma separated include parameter names the association
+func includes(c *gin.Context, association string) bool {
+	for _, name := range strings.Split(c.Query("include"), ",") {
+		if strings.TrimSpace(name) == association {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,241 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/models"
+)
+
+// postComment comments on the photo, or replies to the comment when reply is set, and returns the new id
+func postComment(t *testing.T, server *Server, token string, id uint64, reply bool, message string) uint64 {
+	t.Helper()
+	path := fmt.Sprintf("/api/v1/comments/%d", id)
+	if reply {
+		path += "/replies"
+	}
+	w := serve(server, http.MethodPost, path, token, `{"message":"`+message+`"}`)
+	if w.Code != http.StatusCreated && w.Code != http.StatusOK {
+		t.Fatalf("POST %s: %d %s", path, w.Code, w.Body)
+	}
+	body := struct {
+		Response struct {
+			ID uint64 `json:"id"`
+		} `json:"response"`
+	}{}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	return body.Response.ID
+}
+
+// postPhoto adds a photo by URL and returns its id
+func postPhoto(t *testing.T, server *Server, token, title string) uint64 {
+	t.Helper()
+	w := serve(server, http.MethodPost, "/api/v1/photos", token, `{"title":"`+title+`","caption":"a caption","photo_url":"https://example.com/photo.jpg"}`)
+	if w.Code != http.StatusCreated && w.Code != http.StatusOK {
+		t.Fatalf("POST /photos: %d %s", w.Code, w.Body)
+	}
+	body := struct {
+		Response struct {
+			ID uint64 `json:"id"`
+		} `json:"response"`
+	}{}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	return body.Response.ID
+}
+
+func TestIncludeComments(t *testing.T) {
+	server := newTestServer(t)
+	token := login(t, server, createTestUser(t, server, "alice"))
+
+	first, second := postPhoto(t, server, token, "first"), postPhoto(t, server, token, "second")
+	want := map[uint64][]string{first: {}, second: {}}
+	for i := 0; i < 5; i++ {
+		id := postComment(t, server, token, first, false, fmt.Sprintf("comment %d", i))
+		// replies are not embedded, only top level comments are
+		postComment(t, server, token, id, true, "a reply")
+		if i < embeddedComments {
+			want[first] = append(want[first], fmt.Sprintf("comment %d", i))
+		}
+	}
+	postComment(t, server, token, second, false, "the only comment")
+	want[second] = []string{"the only comment"}
+
+	w := serve(server, http.MethodGet, "/api/v1/photos?include=comments", "", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /photos: %d %s", w.Code, w.Body)
+	}
+	body := struct {
+		Response []struct {
+			ID       uint64 `json:"id"`
+			Comments []struct {
+				Message string `json:"message"`
+			} `json:"comments"`
+		} `json:"response"`
+	}{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Response) != 2 {
+		t.Fatalf("got %d photos, want 2", len(body.Response))
+	}
+	for _, photo := range body.Response {
+		got := []string{}
+		for _, comment := range photo.Comments {
+			got = append(got, comment.Message)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want[photo.ID]) {
+			t.Errorf("photo %d embeds %q, want %q", photo.ID, got, want[photo.ID])
+		}
+	}
+}
+
+type threadComment struct {
+	ID          uint64 `json:"id"`
+	Message     string `json:"message"`
+	MoreReplies string `json:"more_replies"`
+}
+
+// getThread returns the comments of a thread response and its more_replies
+func getThread(t *testing.T, server *Server, path string) ([]threadComment, string) {
+	t.Helper()
+	w := serve(server, http.MethodGet, path, "", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET %s: %d %s", path, w.Code, w.Body)
+	}
+	body := struct {
+		Response    []threadComment `json:"response"`
+		MoreReplies string          `json:"more_replies"`
+	}{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	return body.Response, body.MoreReplies
+}
+
+func messages(comments []threadComment) string {
+	list := []string{}
+	for _, comment := range comments {
+		list = append(list, comment.Message)
+	}
+	return fmt.Sprint(list)
+}
+
+func TestThreadRepliesCap(t *testing.T) {
+	defer func(max int) { models.MaxThreadReplies = max }(models.MaxThreadReplies)
+	models.MaxThreadReplies = 3
+
+	server := newTestServer(t)
+	token := login(t, server, createTestUser(t, server, "alice"))
+	photo := postPhoto(t, server, token, "photo")
+
+	long := postComment(t, server, token, photo, false, "long")
+	r1 := postComment(t, server, token, long, true, "r1")
+	postComment(t, server, token, r1, true, "r1a")
+	for _, message := range []string{"r2", "r3", "r4", "r5"} {
+		postComment(t, server, token, long, true, message)
+	}
+	short := postComment(t, server, token, photo, false, "short")
+	postComment(t, server, token, short, true, "s1")
+
+	thread, _ := getThread(t, server, fmt.Sprintf("/api/v1/photos/%d/comments?view=flat&sort=id", photo))
+	if got, want := messages(thread), "[long r1 r1a r2 short s1]"; got != want {
+		t.Fatalf("thread: got %s, want %s", got, want)
+	}
+	if thread[0].MoreReplies == "" || thread[4].MoreReplies != "" {
+		t.Fatalf("more_replies: got %q on the long thread and %q on the short one", thread[0].MoreReplies, thread[4].MoreReplies)
+	}
+
+	more, next := getThread(t, server, fmt.Sprintf("/api/v1/comments/%d/thread?cursor=%s", long, thread[0].MoreReplies))
+	if got, want := messages(more), "[r3 r4 r5]"; got != want || next != "" {
+		t.Fatalf("more replies: got %s with cursor %q, want %s and no cursor", got, next, want)
+	}
+
+	models.MaxThreadReplies = 2
+	more, next = getThread(t, server, fmt.Sprintf("/api/v1/comments/%d/thread?cursor=%s", long, thread[0].MoreReplies))
+	if got, want := messages(more), "[r3 r4]"; got != want || next == "" {
+		t.Fatalf("more replies: got %s with cursor %q, want %s and a cursor", got, next, want)
+	}
+	more, next = getThread(t, server, fmt.Sprintf("/api/v1/comments/%d/thread?cursor=%s", long, next))
+	if got, want := messages(more), "[r5]"; got != want || next != "" {
+		t.Fatalf("last replies: got %s with cursor %q, want %s and no cursor", got, next, want)
+	}
+
+	// a cursor only continues the thread it was made for
+	w := serve(server, http.MethodGet, fmt.Sprintf("/api/v1/comments/%d/thread?cursor=%s", short, thread[0].MoreReplies), "", "")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("cursor of another comment: got %d, want 400", w.Code)
+	}
+}
+
+func TestDeleteComment(t *testing.T) {
+	server := newTestServer(t)
+	token := login(t, server, createTestUser(t, server, "alice"))
+	photo := postPhoto(t, server, token, "photo")
+
+	parent := postComment(t, server, token, photo, false, "parent")
+	reply := postComment(t, server, token, parent, true, "reply")
+	if w := serve(server, http.MethodPost, fmt.Sprintf("/api/v1/comments/%d/like", parent), token, ""); w.Code != http.StatusOK && w.Code != http.StatusCreated {
+		t.Fatalf("like: %d %s", w.Code, w.Body)
+	}
+
+	if w := serve(server, http.MethodDelete, fmt.Sprintf("/api/v1/comments/%d", parent), token, ""); w.Code != http.StatusOK {
+		t.Fatalf("delete the parent: %d %s", w.Code, w.Body)
+	}
+	thread, _ := getThread(t, server, fmt.Sprintf("/api/v1/photos/%d/comments?view=flat", photo))
+	if got, want := messages(thread), "[ reply]"; got != want {
+		t.Fatalf("after deleting the parent: got %s, want the tombstone and the reply", got)
+	}
+	var likes int
+	server.DB.Model(&models.CommentLike{}).Where("comment_id = ?", parent).Count(&likes)
+	if likes != 0 {
+		t.Errorf("the tombstone kept %d likes", likes)
+	}
+
+	// the tombstone goes with its last reply
+	if w := serve(server, http.MethodDelete, fmt.Sprintf("/api/v1/comments/%d", reply), token, ""); w.Code != http.StatusOK {
+		t.Fatalf("delete the reply: %d %s", w.Code, w.Body)
+	}
+	var left int
+	server.DB.Model(&models.Comment{}).Where("photo_id = ?", photo).Count(&left)
+	if left != 0 {
+		t.Errorf("%d comments are left on the photo, want none", left)
+	}
+}
+
+func TestDeleteUserComments(t *testing.T) {
+	server := newTestServer(t)
+	aliceToken := login(t, server, createTestUser(t, server, "alice"))
+	bobToken := login(t, server, createTestUser(t, server, "bob"))
+	carolToken := login(t, server, createTestUser(t, server, "carol"))
+	photo := postPhoto(t, server, aliceToken, "photo")
+
+	postComment(t, server, bobToken, photo, false, "leaf")
+	answered := postComment(t, server, bobToken, photo, false, "answered")
+	postComment(t, server, carolToken, answered, true, "answer")
+	own := postComment(t, server, bobToken, photo, false, "own thread")
+	postComment(t, server, bobToken, own, true, "own reply")
+	// carol's comment only stays as a tombstone for bob's reply
+	tombstone := postComment(t, server, carolToken, photo, false, "tombstone")
+	postComment(t, server, bobToken, tombstone, true, "last reply")
+	if w := serve(server, http.MethodDelete, fmt.Sprintf("/api/v1/comments/%d", tombstone), carolToken, ""); w.Code != http.StatusOK {
+		t.Fatalf("delete carol's comment: %d %s", w.Code, w.Body)
+	}
+
+	if w := serve(server, http.MethodDelete, "/api/v1/users/me", bobToken, ""); w.Code != http.StatusOK {
+		t.Fatalf("delete bob: %d %s", w.Code, w.Body)
+	}
+	thread, _ := getThread(t, server, fmt.Sprintf("/api/v1/photos/%d/comments?view=flat&sort=id", photo))
+	if got, want := messages(thread), "[ answer]"; got != want {
+		t.Fatalf("thread: got %s, want the tombstone of bob's answered comment and carol's answer", got)
+	}
+	if thread[0].ID != answered {
+		t.Errorf("the tombstone is comment %d, want %d", thread[0].ID, answered)
+	}
+	var orphans int
+	server.DB.Model(&models.Comment{}).Where("id = ? AND user_id IS NULL", answered).Count(&orphans)
+	if orphans != 1 {
+		t.Errorf("the tombstone still points at bob, the foreign key would delete it with him")
+	}
+}
@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/models"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/responses"
+	"github.com/gin-gonic/gin"
+)
+
+// GetFeed godoc
+// @Summary     Get Feed
+// @Description Retrieve the home feed: the photos of the authenticated user and of the accounts they follow, newest first
+// @Tags        Feed
+// @Accept      json
+// @Produce     json
+// @Param       cursor query string false "Cursor from the pagination of a previous page"
+// @Param       limit query int false "Page size, at most 100" default(20)
+// @Param       include query string false "Set to comments to embed the first comments of every photo"
+// @Security ApiKeyAuth
+// @Success     200  {array} responses.Photo
+// @Router      /feed [get]
+func (server *Server) GetFeed(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	uid, err := server.Tokens.ExtractTokenID(c.Request)
+	if err != nil {
+		errList["Unauthorized"] = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  errList,
+		})
+		return
+	}
+	page, ok := pageParams(c, models.FeedSortKeys)
+	if !ok {
+		return
+	}
+
+	photos, links, err := server.Feed.Photos(uid, page)
+	if err == nil {
+		err = server.fillPhotoLikes(c, *photos)
+	}
+	if err == nil {
+		err = server.includeComments(c, *photos)
+	}
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":     http.StatusOK,
+		"response":   responses.NewPhotos(*photos, server.viewer(c)),
+		"pagination": links,
+	})
+}
@@ -0,0 +1,238 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/models"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/responses"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/utils/formaterror"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/utils/pagination"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+// FollowUser godoc
+// @Summary     Follow User
+// @Description Follow a user, a user can only be followed once and cannot follow themselves
+// @Tags        Follow
+// @Accept      json
+// @Produce     json
+// @Param       id path int true "User ID"
+// @Security ApiKeyAuth
+// @Success     201  {object} responses.FollowStatus
+// @Router      /users/{id}/follow [post]
+func (server *Server) FollowUser(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	followee, follower, ok := server.followTarget(c)
+	if !ok {
+		return
+	}
+	err := server.DB.Debug().Model(models.User{}).Where("id = ?", followee).Take(&models.User{}).Error
+	if err != nil {
+		errList["No_user"] = "No User Found"
+		c.JSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  errList,
+		})
+		return
+	}
+
+	follow := models.Follow{FollowerID: follower, FolloweeID: followee}
+	_, err = follow.SaveFollow(server.DB)
+	if err != nil {
+		errList = formaterror.FormatError(err.Error())
+		status := http.StatusInternalServerError
+		if _, self := errList["Self_follow"]; self {
+			status = http.StatusUnprocessableEntity
+		}
+		if _, double := errList["Double_follow"]; double {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{
+			"status": status,
+			"error":  errList,
+		})
+		return
+	}
+	if err = server.Feed.Followed(follower, followee); err != nil {
+		fmt.Println("this is the error adding the followed photos to the feed: ", err)
+	}
+	if err = server.Notifier.Followed(follow); err != nil {
+		fmt.Println("this is the error notifying the followed user: ", err)
+	}
+	server.respondFollowStatus(c, http.StatusCreated, followee, follower)
+}
+
+// UnfollowUser godoc
+// @Summary     Unfollow User
+// @Description Stop following a user
+// @Tags        Follow
+// @Accept      json
+// @Produce     json
+// @Param       id path int true "User ID"
+// @Security ApiKeyAuth
+// @Success     200  {object} responses.FollowStatus
+// @Router      /users/{id}/follow [delete]
+func (server *Server) UnfollowUser(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	followee, follower, ok := server.followTarget(c)
+	if !ok {
+		return
+	}
+	follow := models.Follow{FollowerID: follower, FolloweeID: followee}
+	_, err := follow.DeleteFollow(server.DB)
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			errList["No_follow"] = "You do not follow this user"
+			c.JSON(http.StatusNotFound, gin.H{
+				"status": http.StatusNotFound,
+				"error":  errList,
+			})
+			return
+		}
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	if err = server.Feed.Unfollowed(follower, followee); err != nil {
+		fmt.Println("this is the error removing the unfollowed photos from the feed: ", err)
+	}
+	server.respondFollowStatus(c, http.StatusOK, followee, follower)
+}
+
+// GetFollowers godoc
+// @Summary     Get Followers
+// @Description Retrieve the users following a user, most recent follow first
+// @Tags        Follow
+// @Accept      json
+// @Produce     json
+// @Param       id path int true "User ID"
+// @Param       cursor query string false "Cursor from the pagination of a previous page"
+// @Param       limit query int false "Page size, at most 100" default(20)
+// @Param       sort query string false "created_at or id, prefixed with - for descending order" default(-created_at)
+// @Success     200  {array} responses.User
+// @Router      /users/{id}/followers [get]
+func (server *Server) GetFollowers(c *gin.Context) {
+	server.listFollows(c, true)
+}
+
+// GetFollowing godoc
+// @Summary     Get Following
+// @Description Retrieve the users a user follows, most recent follow first
+// @Tags        Follow
+// @Accept      json
+// @Produce     json
+// @Param       id path int true "User ID"
+// @Param       cursor query string false "Cursor from the pagination of a previous page"
+// @Param       limit query int false "Page size, at most 100" default(20)
+// @Param       sort query string false "created_at or id, prefixed with - for descending order" default(-created_at)
+// @Success     200  {array} responses.User
+// @Router      /users/{id}/following [get]
+func (server *Server) GetFollowing(c *gin.Context) {
+	server.listFollows(c, false)
+}
+
+func (server *Server) listFollows(c *gin.Context, followers bool) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	uid, err := resolveUserID(c)
+	if err != nil {
+		errList["Invalid_request"] = "Invalid Request"
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  errList,
+		})
+		return
+	}
+	page, ok := pageParams(c, models.FollowSortKeys)
+	if !ok {
+		return
+	}
+	err = server.DB.Debug().Model(models.User{}).Where("id = ?", uid).Take(&models.User{}).Error
+	if err != nil {
+		errList["No_user"] = "No User Found"
+		c.JSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  errList,
+		})
+		return
+	}
+
+	follow := models.Follow{}
+	var follows *[]models.Follow
+	var links pagination.Links
+	if followers {
+		follows, links, err = follow.FindFollowers(server.DB, uid, page)
+	} else {
+		follows, links, err = follow.FindFollowing(server.DB, uid, page)
+	}
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	users := responses.NewFollowing(*follows, server.viewer(c))
+	if followers {
+		users = responses.NewFollowers(*follows, server.viewer(c))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":     http.StatusOK,
+		"response":   users,
+		"pagination": links,
+	})
+}
+
+// followTarget reads the :id of the followed user and the authenticated user
+func (server *Server) followTarget(c *gin.Context) (uint32, uint32, bool) {
+	followee, err := resolveUserID(c)
+	if err != nil {
+		errList["Invalid_request"] = "Invalid Request"
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  errList,
+		})
+		return 0, 0, false
+	}
+	follower, err := server.Tokens.ExtractTokenID(c.Request)
+	if err != nil {
+		errList["Unauthorized"] = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  errList,
+		})
+		return 0, 0, false
+	}
+	return followee, follower, true
+}
+
+func (server *Server) respondFollowStatus(c *gin.Context, status int, followee uint32, follower uint32) {
+	users := []models.User{{ID: followee}}
+	follow := models.Follow{}
+	if err := follow.FillUserFollows(server.DB, users, follower); err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	c.JSON(status, gin.H{
+		"status":   status,
+		"response": responses.FollowStatus{FollowerCount: users[0].FollowerCount, FollowedByMe: users[0].FollowedByMe},
+	})
+}
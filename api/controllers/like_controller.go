@@ -0,0 +1,266 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/models"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/realtime"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/responses"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/utils/formaterror"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+// LikePhoto godoc
+// @Summary     Like Photo
+// @Description Like a photo, a photo can only be liked once
+// @Tags        Like
+// @Accept      json
+// @Produce     json
+// @Param       id path int true "Photo ID"
+// @Security ApiKeyAuth
+// @Success     201  {object} responses.LikeStatus
+// @Router      /photos/{id}/like [post]
+func (server *Server) LikePhoto(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	pid, uid, ok := server.likeTarget(c)
+	if !ok {
+		return
+	}
+	photo := models.Photo{}
+	err := server.DB.Debug().Model(models.Photo{}).Where("id = ?", pid).Take(&photo).Error
+	if err != nil {
+		errList["No_photo"] = "No Photo Found"
+		c.JSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  errList,
+		})
+		return
+	}
+
+	like := models.Like{UserID: uid, PhotoID: pid}
+	_, err = like.SaveLike(server.DB)
+	if err != nil {
+		errList = formaterror.FormatError(err.Error())
+		status := http.StatusInternalServerError
+		if _, double := errList["Double_like"]; double {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{
+			"status": status,
+			"error":  errList,
+		})
+		return
+	}
+	if err = server.Notifier.PhotoLiked(like, photo); err != nil {
+		fmt.Println("this is the error notifying about the like: ", err)
+	}
+	server.respondPhotoLikes(c, http.StatusCreated, photo, uid)
+}
+
+// UnlikePhoto godoc
+// @Summary     Unlike Photo
+// @Description Remove the like of the current user from a photo
+// @Tags        Like
+// @Accept      json
+// @Produce     json
+// @Param       id path int true "Photo ID"
+// @Security ApiKeyAuth
+// @Success     200  {object} responses.LikeStatus
+// @Router      /photos/{id}/like [delete]
+func (server *Server) UnlikePhoto(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	pid, uid, ok := server.likeTarget(c)
+	if !ok {
+		return
+	}
+	like := models.Like{UserID: uid, PhotoID: pid}
+	_, err := like.DeleteLike(server.DB)
+	if err != nil {
+		server.respondUnlikeError(c, err)
+		return
+	}
+	server.respondPhotoLikes(c, http.StatusOK, models.Photo{ID: pid}, uid)
+}
+
+// LikeComment godoc
+// @Summary     Like Comment
+// @Description Like a comment, a comment can only be liked once
+// @Tags        Like
+// @Accept      json
+// @Produce     json
+// @Param       id path int true "Comment ID"
+// @Security ApiKeyAuth
+// @Success     201  {object} responses.LikeStatus
+// @Router      /comments/{id}/like [post]
+func (server *Server) LikeComment(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	cid, uid, ok := server.likeTarget(c)
+	if !ok {
+		return
+	}
+	comment := models.Comment{}
+	err := server.DB.Debug().Model(models.Comment{}).Where("id = ? AND deleted = ?", cid, false).Take(&comment).Error
+	if err != nil {
+		errList["No_comment"] = "No Comment Found"
+		c.JSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  errList,
+		})
+		return
+	}
+
+	like := models.CommentLike{UserID: uid, CommentID: cid}
+	_, err = like.SaveCommentLike(server.DB)
+	if err != nil {
+		errList = formaterror.FormatError(err.Error())
+		status := http.StatusInternalServerError
+		if _, double := errList["Double_like"]; double {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{
+			"status": status,
+			"error":  errList,
+		})
+		return
+	}
+	if err = server.Notifier.CommentLiked(like, comment); err != nil {
+		fmt.Println("this is the error notifying about the like: ", err)
+	}
+	server.respondCommentLikes(c, http.StatusCreated, comment, uid)
+}
+
+// UnlikeComment godoc
+// @Summary     Unlike Comment
+// @Description Remove the like of the current user from a comment
+// @Tags        Like
+// @Accept      json
+// @Produce     json
+// @Param       id path int true "Comment ID"
+// @Security ApiKeyAuth
+// @Success     200  {object} responses.LikeStatus
+// @Router      /comments/{id}/like [delete]
+func (server *Server) UnlikeComment(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	cid, uid, ok := server.likeTarget(c)
+	if !ok {
+		return
+	}
+	like := models.CommentLike{UserID: uid, CommentID: cid}
+	_, err := like.DeleteCommentLike(server.DB)
+	if err != nil {
+		server.respondUnlikeError(c, err)
+		return
+	}
+	// the photo of the comment tells which streams get the new count
+	comment := models.Comment{}
+	err = server.DB.Debug().Model(models.Comment{}).Where("id = ?", cid).Take(&comment).Error
+	if err != nil {
+		comment = models.Comment{ID: cid}
+	}
+	server.respondCommentLikes(c, http.StatusOK, comment, uid)
+}
+
+// likeTarget reads the :id of the liked photo or comment and the authenticated user
+func (server *Server) likeTarget(c *gin.Context) (uint64, uint32, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		errList["Invalid_request"] = "Invalid Request"
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  errList,
+		})
+		return 0, 0, false
+	}
+	uid, err := server.Tokens.ExtractTokenID(c.Request)
+	if err != nil {
+		errList["Unauthorized"] = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  errList,
+		})
+		return 0, 0, false
+	}
+	return id, uid, true
+}
+
+func (server *Server) respondUnlikeError(c *gin.Context, err error) {
+	if gorm.IsRecordNotFoundError(err) {
+		errList["No_like"] = "No Like Found"
+		c.JSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  errList,
+		})
+		return
+	}
+	errList["Other_error"] = "Please try again later"
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"status": http.StatusInternalServerError,
+		"error":  errList,
+	})
+}
+
+// respondPhotoLikes answers with the like count of the photo and pushes it to the streams of the photo
+func (server *Server) respondPhotoLikes(c *gin.Context, status int, photo models.Photo, uid uint32) {
+	photos := []models.Photo{photo}
+	like := models.Like{}
+	if err := like.FillPhotoLikes(server.DB, photos, uid); err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	server.publish(realtime.PhotoTopic(photo.ID), "likes", responses.LikeCount{PhotoID: photo.ID, LikeCount: photos[0].LikeCount})
+	c.JSON(status, gin.H{
+		"status":   status,
+		"response": responses.LikeStatus{LikeCount: photos[0].LikeCount, LikedByMe: photos[0].LikedByMe},
+	})
+}
+
+// respondCommentLikes answers with the like count of the comment and pushes it to the streams of its photo
+func (server *Server) respondCommentLikes(c *gin.Context, status int, comment models.Comment, uid uint32) {
+	comments := []models.Comment{comment}
+	like := models.CommentLike{}
+	if err := like.FillCommentLikes(server.DB, comments, uid); err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	cid := comment.ID
+	server.publish(realtime.PhotoTopic(comment.PhotoID), "likes", responses.LikeCount{PhotoID: comment.PhotoID, CommentID: &cid, LikeCount: comments[0].LikeCount})
+	c.JSON(status, gin.H{
+		"status":   status,
+		"response": responses.LikeStatus{LikeCount: comments[0].LikeCount, LikedByMe: comments[0].LikedByMe},
+	})
+}
+
+// fillPhotoLikes sets the like counts of photos, and whether the viewer of the request liked them
+func (server *Server) fillPhotoLikes(c *gin.Context, photos []models.Photo) error {
+	like := models.Like{}
+	return like.FillPhotoLikes(server.DB, photos, server.viewer(c).ID)
+}
+
+// fillCommentLikes sets the like counts of comments, and whether the viewer of the request liked them
+func (server *Server) fillCommentLikes(c *gin.Context, comments []models.Comment) error {
+	like := models.CommentLike{}
+	return like.FillCommentLikes(server.DB, comments, server.viewer(c).ID)
+}
@@ -5,12 +5,13 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"time"
 
-	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/auth"
 	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/models"
 	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/security"
 	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/utils/formaterror"
 	"github.com/gin-gonic/gin"
+	"github.com/twinj/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -83,19 +84,53 @@ func (server *Server) SignIn(email, password string) (map[string]interface{}, er
 		return nil, err
 	}
 	err = security.VerifyPassword(user.Password, password)
-	if err != nil && err == bcrypt.ErrMismatchedHashAndPassword {
+	if err != nil {
 		fmt.Println("this is the error hashing the password: ", err)
-		return nil, err
+		// a stored hash bcrypt cannot read fails the login the same way a wrong password does
+		return nil, bcrypt.ErrMismatchedHashAndPassword
 	}
-	token, err := auth.CreateToken(user.ID)
+	// every login starts a new refresh token family
+	tokens, err := server.issueTokens(&user, uuid.NewV4().String())
 	if err != nil {
 		fmt.Println("this is the error creating the token: ", err)
 		return nil, err
 	}
-	userData["token"] = token
+	for key, value := range tokens {
+		userData[key] = value
+	}
 	userData["id"] = user.ID
 	userData["email"] = user.Email
 	userData["username"] = user.Username
+	userData["email_verified"] = user.IsEmailVerified()
+	userData["role"] = user.Role
 
 	return userData, nil
 }
+
+// issueTokens creates an access token and a new refresh token in the given family
+func (server *Server) issueTokens(user *models.User, familyID string) (map[string]interface{}, error) {
+
+	accessToken, err := server.Tokens.CreateToken(user.ID, user.Role, familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken := security.TokenHash(fmt.Sprintf("%d:%s", user.ID, familyID))
+	stored := models.RefreshToken{
+		TokenHash: security.Digest(refreshToken),
+		FamilyID:  familyID,
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(server.Tokens.RefreshTokenTTL()),
+	}
+	_, err = stored.SaveRefreshToken(server.DB)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"token":         accessToken,
+		"token_type":    "Bearer",
+		"expires_in":    int64(server.Tokens.AccessTokenTTL().Seconds()),
+		"refresh_token": refreshToken,
+	}, nil
+}
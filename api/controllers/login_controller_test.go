@@ -0,0 +1,33 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/models"
+)
+
+func TestLoginRejectsWrongPassword(t *testing.T) {
+	server := newTestServer(t)
+	user := createTestUser(t, server, "alice")
+
+	w := serve(server, http.MethodPost, "/api/v1/login", "", `{"email":"`+user.Email+`","password":"not-the-password"}`)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("login with a wrong password: got %d, want 422", w.Code)
+	}
+}
+
+func TestLoginRejectsUnreadableHash(t *testing.T) {
+	server := newTestServer(t)
+	user := createTestUser(t, server, "alice")
+	// bcrypt fails with an error of its own, not the mismatch one, on a hash it cannot read
+	err := server.DB.Model(&models.User{}).Where("id = ?", user.ID).UpdateColumn("password", "not-a-bcrypt-hash").Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := serve(server, http.MethodPost, "/api/v1/login", "", `{"email":"`+user.Email+`","password":"password"}`)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("login over an unreadable hash: got %d, want 422 %s", w.Code, w.Body)
+	}
+}
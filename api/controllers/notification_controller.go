@@ -0,0 +1,278 @@
+package controllers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/models"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/responses"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+// GetNotifications godoc
+// @Summary     Get Notifications
+// @Description Retrieve the notifications of the authenticated user, newest first, with how many are unread
+// @Tags        Notification
+// @Accept      json
+// @Produce     json
+// @Param       unread query bool false "Only the unread notifications"
+// @Param       cursor query string false "Cursor from the pagination of a previous page"
+// @Param       limit query int false "Page size, at most 100" default(20)
+// @Param       sort query string false "created_at or id, prefixed with - for descending order" default(-created_at)
+// @Security ApiKeyAuth
+// @Success     200  {array} responses.Notification
+// @Router      /notifications [get]
+func (server *Server) GetNotifications(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	uid, ok := server.notificationUser(c)
+	if !ok {
+		return
+	}
+	unreadOnly, err := strconv.ParseBool(c.DefaultQuery("unread", "false"))
+	if err != nil {
+		errList["Invalid_request"] = "Invalid Request"
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  errList,
+		})
+		return
+	}
+	page, ok := pageParams(c, models.NotificationSortKeys)
+	if !ok {
+		return
+	}
+	notification := models.Notification{}
+
+	notifications, links, err := notification.FindNotifications(server.DB, uid, unreadOnly, page)
+	var unread map[string]int64
+	if err == nil {
+		unread, err = notification.CountUnread(server.DB, uid)
+	}
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":     http.StatusOK,
+		"response":   responses.NewNotifications(*notifications, server.viewer(c)),
+		"unread":     responses.NewUnreadCount(unread),
+		"pagination": links,
+	})
+}
+
+// MarkNotificationRead godoc
+// @Summary     Mark Notification Read
+// @Description Mark a notification of the authenticated user as read
+// @Tags        Notification
+// @Accept      json
+// @Produce     json
+// @Param       id path int true "Notification ID"
+// @Security ApiKeyAuth
+// @Success     200  {object} responses.UnreadCount
+// @Router      /notifications/{id}/read [post]
+func (server *Server) MarkNotificationRead(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	nid, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		errList["Invalid_request"] = "Invalid Request"
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  errList,
+		})
+		return
+	}
+	uid, ok := server.notificationUser(c)
+	if !ok {
+		return
+	}
+	notification := models.Notification{}
+
+	_, err = notification.MarkRead(server.DB, uid, nid)
+	if err != nil {
+		// the notifications of other users are not found either
+		if gorm.IsRecordNotFoundError(err) {
+			errList["No_notification"] = "No Notification Found"
+			c.JSON(http.StatusNotFound, gin.H{
+				"status": http.StatusNotFound,
+				"error":  errList,
+			})
+			return
+		}
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	server.respondUnread(c, uid)
+}
+
+// MarkAllNotificationsRead godoc
+// @Summary     Mark All Notifications Read
+// @Description Mark every notification of the authenticated user as read
+// @Tags        Notification
+// @Accept      json
+// @Produce     json
+// @Security ApiKeyAuth
+// @Success     200  {object} responses.UnreadCount
+// @Router      /notifications/read [post]
+func (server *Server) MarkAllNotificationsRead(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	uid, ok := server.notificationUser(c)
+	if !ok {
+		return
+	}
+	notification := models.Notification{}
+
+	if _, err := notification.MarkAllRead(server.DB, uid); err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	server.respondUnread(c, uid)
+}
+
+// GetNotificationPreferences godoc
+// @Summary     Get Notification Preferences
+// @Description Retrieve the kinds of notification the authenticated user gets
+// @Tags        Notification
+// @Accept      json
+// @Produce     json
+// @Security ApiKeyAuth
+// @Success     200  {object} responses.NotificationPreference
+// @Router      /notifications/preferences [get]
+func (server *Server) GetNotificationPreferences(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	uid, ok := server.notificationUser(c)
+	if !ok {
+		return
+	}
+	pref := models.NotificationPreference{}
+
+	prefFound, err := pref.FindNotificationPreference(server.DB, uid)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":   http.StatusOK,
+		"response": responses.NewNotificationPreference(*prefFound),
+	})
+}
+
+// UpdateNotificationPreferences godoc
+// @Summary     Update Notification Preferences
+// @Description Turn kinds of notification on or off for the authenticated user, the kinds left out keep their setting
+// @Tags        Notification
+// @Accept      json
+// @Produce     json
+// @Param       preferences body models.UpdateNotificationPreference true "Update Notification Preferences"
+// @Security ApiKeyAuth
+// @Success     200  {object} responses.NotificationPreference
+// @Router      /notifications/preferences [put]
+func (server *Server) UpdateNotificationPreferences(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	uid, ok := server.notificationUser(c)
+	if !ok {
+		return
+	}
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		errList["Invalid_body"] = "Unable to get request"
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+	update := models.UpdateNotificationPreference{}
+	err = json.Unmarshal(body, &update)
+	if err != nil {
+		errList["Unmarshal_error"] = "Cannot unmarshal body"
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+	pref := models.NotificationPreference{}
+
+	prefFound, err := pref.FindNotificationPreference(server.DB, uid)
+	if err == nil {
+		update.Apply(prefFound)
+		prefFound, err = prefFound.SaveNotificationPreference(server.DB)
+	}
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":   http.StatusOK,
+		"response": responses.NewNotificationPreference(*prefFound),
+	})
+}
+
+// notificationUser reads the authenticated user, it answers 401 without one
+func (server *Server) notificationUser(c *gin.Context) (uint32, bool) {
+	uid, err := server.Tokens.ExtractTokenID(c.Request)
+	if err != nil {
+		errList["Unauthorized"] = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  errList,
+		})
+		return 0, false
+	}
+	return uid, true
+}
+
+func (server *Server) respondUnread(c *gin.Context, uid uint32) {
+	notification := models.Notification{}
+	unread, err := notification.CountUnread(server.DB, uid)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":   http.StatusOK,
+		"response": responses.NewUnreadCount(unread),
+	})
+}
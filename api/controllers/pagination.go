@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/utils/pagination"
+	"github.com/gin-gonic/gin"
+)
+
+// listParams reads the paging, sorting and filter parameters of a list request, it answers 400 when they are invalid
+func listParams(c *gin.Context, keys []pagination.SortKey) (pagination.Page, pagination.Filter, bool) {
+	page, ok := pageParams(c, keys)
+	if !ok {
+		return pagination.Page{}, pagination.Filter{}, false
+	}
+	filter, err := pagination.FilterFromQuery(c.Request.URL.Query())
+	if err != nil {
+		errList["Invalid_filter"] = "Invalid Filter"
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  errList,
+		})
+		return pagination.Page{}, pagination.Filter{}, false
+	}
+	return page, filter, true
+}
+
+// pageParams reads the paging and sorting parameters of a list request, it answers 400 when they are invalid
+func pageParams(c *gin.Context, keys []pagination.SortKey) (pagination.Page, bool) {
+	page, err := pagination.FromQuery(c.Request.URL.Query(), keys, "-created_at")
+	if err != nil {
+		switch err {
+		case pagination.ErrInvalidCursor:
+			errList["Invalid_cursor"] = "Invalid Cursor"
+		case pagination.ErrInvalidSort:
+			errList["Invalid_sort"] = "Invalid Sort"
+		default:
+			errList["Invalid_limit"] = "Invalid Limit"
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  errList,
+		})
+		return pagination.Page{}, false
+	}
+	return page, true
+}
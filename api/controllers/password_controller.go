@@ -0,0 +1,216 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/mailer"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/models"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/security"
+	"github.com/gin-gonic/gin"
+)
+
+// ForgotPassword godoc
+// @Summary     Forgot Password
+// @Description Send a password reset link to the given email. The response is the same whether or not the email is registered.
+// @Tags        User
+// @Accept      json
+// @Produce     json
+// @Param       ForgotPassword body models.ForgotPassword true "User Email"
+// @Success     200  {string} string "If the email is registered, a reset link has been sent"
+// @Router      /password/forgot [post]
+func (server *Server) ForgotPassword(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		errList["Invalid_body"] = "Unable to get request"
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+	user := models.User{}
+	err = json.Unmarshal(body, &user)
+	if err != nil {
+		errList["Unmarshal_error"] = "Cannot unmarshal body"
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+	user.Prepare()
+	errorMessages := user.Validate("forgotpassword")
+	if len(errorMessages) > 0 {
+		errList = errorMessages
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+
+	// do not tell the caller whether the address is registered
+	accepted := gin.H{
+		"status":   http.StatusOK,
+		"response": "If the email is registered, a reset link has been sent",
+	}
+
+	err = server.DB.Debug().Model(models.User{}).Where("email = ?", user.Email).Take(&user).Error
+	if err != nil {
+		c.JSON(http.StatusOK, accepted)
+		return
+	}
+
+	resetPassword := models.ResetPassword{}
+	_, err = resetPassword.InvalidateUserResets(server.DB, user.ID)
+	if err != nil {
+		fmt.Println("this is the error invalidating older reset tokens: ", err)
+	}
+
+	token := security.TokenHash(user.Email)
+	resetPassword = models.ResetPassword{
+		UserID:    user.ID,
+		Email:     user.Email,
+		TokenHash: security.Digest(token),
+		ExpiresAt: time.Now().Add(server.Config.Auth.PasswordResetTTL),
+	}
+	_, err = resetPassword.SaveResetPassword(server.DB)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+
+	// the frontend page asks for the new password, then posts it with the token to ResetPassword
+	link := passwordResetLink(server.Config.App.PasswordResetURL, token)
+	msg, err := mailer.PasswordResetEmail(user.Username, user.Email, link, server.Config.Auth.PasswordResetTTL)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	server.sendMail(msg)
+
+	c.JSON(http.StatusOK, accepted)
+}
+
+// ResetPassword godoc
+// @Summary     Reset Password
+// @Description Set a new password using the token from the reset email
+// @Tags        User
+// @Accept      json
+// @Produce     json
+// @Param       ResetPasswordInput body models.ResetPasswordInput true "Reset Data"
+// @Success     200  {string} string "Password reset"
+// @Router      /password/reset [post]
+func (server *Server) ResetPassword(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		errList["Invalid_body"] = "Unable to get request"
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+	input := models.ResetPasswordInput{}
+	err = json.Unmarshal(body, &input)
+	if err != nil {
+		errList["Unmarshal_error"] = "Cannot unmarshal body"
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+	errorMessages := input.Validate()
+	if len(errorMessages) > 0 {
+		errList = errorMessages
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+
+	resetPassword := models.ResetPassword{}
+	reset, err := resetPassword.FindResetPasswordByToken(server.DB, security.Digest(input.Token))
+	if err != nil || reset.UsedAt != nil || reset.IsExpired() {
+		errList["Invalid_token"] = "Invalid link. Try requesting again"
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+	consumed, err := reset.MarkUsed(server.DB)
+	if err != nil || !consumed {
+		errList["Invalid_token"] = "Invalid link. Try requesting again"
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+
+	user := models.User{}
+	_, err = user.FindUserByID(server.DB, reset.UserID)
+	if err != nil {
+		errList["No_user"] = "No User Found"
+		c.JSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  errList,
+		})
+		return
+	}
+	err = user.UpdatePassword(server.DB, input.NewPassword)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+
+	// whoever knew the old password should not stay logged in
+	refreshToken := models.RefreshToken{}
+	_, err = refreshToken.RevokeUserTokens(server.DB, user.ID)
+	if err != nil {
+		fmt.Println("this is the error revoking the user sessions: ", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":   http.StatusOK,
+		"response": "Password reset",
+	})
+}
+
+// passwordResetLink adds the token to the query of the reset page, which may have a query of its own
+func passwordResetLink(page, token string) string {
+	separator := "?"
+	if strings.Contains(page, "?") {
+		separator = "&"
+	}
+	return page + separator + "token=" + url.QueryEscape(token)
+}
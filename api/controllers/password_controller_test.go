@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/mailer"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/models"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/security"
+)
+
+var resetLink = regexp.MustCompile(`http://localhost:3000/password/reset\?token=([^\s"&<)\]]+)`)
+
+// requestReset asks for a reset email and returns the token of the link it contains
+func requestReset(t *testing.T, server *Server, email string) string {
+	t.Helper()
+	sender := server.Mailer.(*mailer.MemorySender)
+	sent := len(sender.Messages())
+
+	w := serve(server, http.MethodPost, "/api/v1/password/forgot", "", `{"email":"`+email+`"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("forgot password: %d %s", w.Code, w.Body)
+	}
+	server.mail.Wait()
+	messages := sender.Messages()
+	if len(messages) != sent+1 {
+		t.Fatalf("%d emails were sent, want 1", len(messages)-sent)
+	}
+	match := resetLink.FindStringSubmatch(messages[len(messages)-1].Text)
+	if match == nil {
+		t.Fatalf("the email has no link to the reset page:\n%s", messages[len(messages)-1].Text)
+	}
+	token, err := url.QueryUnescape(match[1])
+	if err != nil {
+		t.Fatalf("cannot read the token of the link: %v", err)
+	}
+	return token
+}
+
+func resetPassword(server *Server, token, password string) int {
+	body := `{"token":"` + token + `","new_password":"` + password + `","retype_password":"` + password + `"}`
+	return serve(server, http.MethodPost, "/api/v1/password/reset", "", body).Code
+}
+
+func TestPasswordReset(t *testing.T) {
+	server := newTestServer(t)
+	user := createTestUser(t, server, "alice")
+
+	token := requestReset(t, server, user.Email)
+	if code := resetPassword(server, token, "new-password"); code != http.StatusOK {
+		t.Fatalf("reset with a fresh token: got %d, want 200", code)
+	}
+	w := serve(server, http.MethodPost, "/api/v1/login", "", `{"email":"`+user.Email+`","password":"new-password"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("login with the new password: got %d, want 200", w.Code)
+	}
+
+	if code := resetPassword(server, token, "another-password"); code != http.StatusUnprocessableEntity {
+		t.Errorf("reset with a used token: got %d, want 422", code)
+	}
+}
+
+func TestPasswordResetExpiredToken(t *testing.T) {
+	server := newTestServer(t)
+	user := createTestUser(t, server, "alice")
+
+	token := requestReset(t, server, user.Email)
+	err := server.DB.Model(&models.ResetPassword{}).Where("token_hash = ?", security.Digest(token)).
+		UpdateColumn("expires_at", time.Now().Add(-time.Minute)).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := resetPassword(server, token, "new-password"); code != http.StatusUnprocessableEntity {
+		t.Errorf("reset with an expired token: got %d, want 422", code)
+	}
+}
+
+func TestPasswordResetOnlyLatestToken(t *testing.T) {
+	server := newTestServer(t)
+	user := createTestUser(t, server, "alice")
+
+	first := requestReset(t, server, user.Email)
+	latest := requestReset(t, server, user.Email)
+	if code := resetPassword(server, first, "new-password"); code != http.StatusUnprocessableEntity {
+		t.Errorf("reset with a replaced token: got %d, want 422", code)
+	}
+	if code := resetPassword(server, latest, "new-password"); code != http.StatusOK {
+		t.Errorf("reset with the latest token: got %d, want 200", code)
+	}
+}
+
+func TestForgotPasswordUnknownEmail(t *testing.T) {
+	server := newTestServer(t)
+
+	w := serve(server, http.MethodPost, "/api/v1/password/forgot", "", `{"email":"nobody@example.com"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("got %d, want the same 200 as for a registered email", w.Code)
+	}
+	server.mail.Wait()
+	if messages := server.Mailer.(*mailer.MemorySender).Messages(); len(messages) != 0 {
+		t.Errorf("%d emails were sent to an unknown address", len(messages))
+	}
+}
+
+func TestPasswordResetLink(t *testing.T) {
+	for page, want := range map[string]string{
+		"https://mygram.example/reset":         "https://mygram.example/reset?token=a%2Bb",
+		"https://mygram.example/#/reset?x=1":   "https://mygram.example/#/reset?x=1&token=a%2Bb",
+		"https://mygram.example/reset?lang=en": "https://mygram.example/reset?lang=en&token=a%2Bb",
+	} {
+		if got := passwordResetLink(page, "a+b"); got != want {
+			t.Errorf("passwordResetLink(%q): got %q, want %q", page, got, want)
+		}
+	}
+}
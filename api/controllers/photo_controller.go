@@ -7,48 +7,67 @@ import (
 	"net/http"
 	"strconv"
 
-	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/auth"
 	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/models"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/policy"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/responses"
 	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/utils/formaterror"
 	"github.com/gin-gonic/gin"
 )
 
 // CreatePhoto godoc
 // @Summary     Create Photo
-// @Description Add a new Photo
+// @Description Add a new Photo. Send JSON with a photo_url, or multipart/form-data with title, caption and a "photo" file (JPEG, PNG, GIF or WebP) to upload the image.
 // @Tags        Photo
 // @Accept      json
 // @Produce     json
 // @Param       CreatePhoto body models.CreatePhoto true "Photo Data"
 // @Security ApiKeyAuth
-// @Success     200  {object} models.Photo
+// @Success     200  {object} responses.Photo
 // @Router      /photos [post]
 func (server *Server) CreatePhoto(c *gin.Context) {
 
 	//clear previous error if any
 	errList = map[string]string{}
 
-	body, err := ioutil.ReadAll(c.Request.Body)
-	if err != nil {
-		errList["Invalid_body"] = "Unable to get request"
-		c.JSON(http.StatusUnprocessableEntity, gin.H{
-			"status": http.StatusUnprocessableEntity,
-			"error":  errList,
-		})
-		return
-	}
 	photo := models.Photo{}
+	var upload *photoUpload
+	var err error
 
-	err = json.Unmarshal(body, &photo)
-	if err != nil {
-		errList["Unmarshal_error"] = "Cannot unmarshal body"
-		c.JSON(http.StatusUnprocessableEntity, gin.H{
-			"status": http.StatusUnprocessableEntity,
-			"error":  errList,
-		})
-		return
+	if c.ContentType() == "multipart/form-data" {
+		upload, err = server.readPhotoUpload(c, &photo)
+		if err != nil {
+			status, uploadErrors := server.uploadErrorResponse(err)
+			errList = uploadErrors
+			c.JSON(status, gin.H{
+				"status": status,
+				"error":  errList,
+			})
+			return
+		}
+	} else {
+		body, err := ioutil.ReadAll(c.Request.Body)
+		if err != nil {
+			errList["Invalid_body"] = "Unable to get request"
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"status": http.StatusUnprocessableEntity,
+				"error":  errList,
+			})
+			return
+		}
+		err = json.Unmarshal(body, &photo)
+		if err != nil {
+			errList["Unmarshal_error"] = "Cannot unmarshal body"
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"status": http.StatusUnprocessableEntity,
+				"error":  errList,
+			})
+			return
+		}
+		// the client cannot point the photo at a file in our storage
+		photo.StorageKey, photo.MimeType, photo.Width, photo.Height, photo.ByteSize = "", "", 0, 0, 0
+		photo.TakenAt, photo.CameraModel, photo.Orientation = nil, "", 0
 	}
-	uid, err := auth.ExtractTokenID(c.Request)
+	uid, err := server.Tokens.ExtractTokenID(c.Request)
 	if err != nil {
 		errList["Unauthorized"] = "Unauthorized"
 		c.JSON(http.StatusUnauthorized, gin.H{
@@ -71,6 +90,9 @@ func (server *Server) CreatePhoto(c *gin.Context) {
 	}
 
 	photo.UserID = uid //the authenticated user is the one creating the photo
+	if upload != nil {
+		server.attach(&photo, upload)
+	}
 
 	photo.Prepare()
 	errorMessages := photo.Validate()
@@ -83,8 +105,22 @@ func (server *Server) CreatePhoto(c *gin.Context) {
 		return
 	}
 
+	if upload != nil {
+		err = server.storeUpload(c.Request.Context(), &photo, upload)
+		if err != nil {
+			fmt.Println("this is the error storing the photo: ", err)
+			errList["Other_error"] = "Please try again later"
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status": http.StatusInternalServerError,
+				"error":  errList,
+			})
+			return
+		}
+	}
+
 	photoCreated, err := photo.SavePhoto(server.DB)
 	if err != nil {
+		server.removeStoredFiles(photo.StorageKey)
 		errList := formaterror.FormatError(err.Error())
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"status": http.StatusInternalServerError,
@@ -92,9 +128,19 @@ func (server *Server) CreatePhoto(c *gin.Context) {
 		})
 		return
 	}
+	if photoCreated.IsUploaded() {
+		server.Variants.Enqueue(photoCreated.ID)
+	}
+	// the photo is saved either way, a feed that missed it is fixed by rebuilding the timelines
+	if err = server.Feed.PhotoCreated(*photoCreated); err != nil {
+		fmt.Println("this is the error adding the photo to the feeds: ", err)
+	}
+	if err = server.Notifier.Mentioned(photoCreated.Mentions); err != nil {
+		fmt.Println("this is the error notifying the mentioned users: ", err)
+	}
 	c.JSON(http.StatusCreated, gin.H{
 		"status":   http.StatusCreated,
-		"response": photoCreated,
+		"response": responses.NewPhoto(*photoCreated, server.viewer(c)),
 	})
 }
 
@@ -104,13 +150,27 @@ func (server *Server) CreatePhoto(c *gin.Context) {
 // @Tags Photo
 // @Accept json
 // @Produce json
-// @Success 200 {array} models.Photo
+// @Success 200 {array} responses.Photo
+// @Param cursor query string false "Cursor from the pagination of a previous page"
+// @Param limit query int false "Page size, at most 100" default(20)
+// @Param sort query string false "created_at, id or title, prefixed with - for descending order" default(-created_at)
+// @Param user_id query int false "Only items of this user"
+// @Param created_after query string false "Only items created after this time (RFC3339 or YYYY-MM-DD)"
+// @Param created_before query string false "Only items created before this time (RFC3339 or YYYY-MM-DD)"
+// @Param include query string false "Set to comments to embed the first comments of every photo"
 // @Router /photos [get]
 func (server *Server) GetPhotos(c *gin.Context) {
 
+	//clear previous error if any
+	errList = map[string]string{}
+
+	page, filter, ok := listParams(c, models.PhotoSortKeys)
+	if !ok {
+		return
+	}
 	photo := models.Photo{}
 
-	photos, err := photo.FindAllPhotos(server.DB)
+	photos, links, err := photo.FindAllPhotos(server.DB, page, filter)
 	if err != nil {
 		errList["No_photo"] = "No Photo Found"
 		c.JSON(http.StatusNotFound, gin.H{
@@ -119,9 +179,21 @@ func (server *Server) GetPhotos(c *gin.Context) {
 		})
 		return
 	}
+	if err = server.fillPhotoLikes(c, *photos); err == nil {
+		err = server.includeComments(c, *photos)
+	}
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
-		"status":   http.StatusOK,
-		"response": photos,
+		"status":     http.StatusOK,
+		"response":   responses.NewPhotos(*photos, server.viewer(c)),
+		"pagination": links,
 	})
 }
 
@@ -132,7 +204,8 @@ func (server *Server) GetPhotos(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path int true "Photo ID"
-// @Success 200 {object} models.Photo
+// @Success 200 {object} responses.Photo
+// @Param include query string false "Set to comments to embed the first comments of every photo"
 // @Router /photos/{id} [get]
 func (server *Server) GetPhoto(c *gin.Context) {
 
@@ -157,10 +230,22 @@ func (server *Server) GetPhoto(c *gin.Context) {
 		})
 		return
 	}
+	photos := []models.Photo{*photoReceived}
+	if err = server.fillPhotoLikes(c, photos); err == nil {
+		err = server.includeComments(c, photos)
+	}
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"status":   http.StatusOK,
-		"response": photoReceived,
+		"response": responses.NewPhoto(photos[0], server.viewer(c)),
 	})
 }
 
@@ -173,7 +258,7 @@ func (server *Server) GetPhoto(c *gin.Context) {
 // @Param id path int true "Photo ID"
 // @Param UpdatePhoto body models.UpdatePhoto true "Photo Data"
 // @Security ApiKeyAuth
-// @Success 200 {object} models.Photo
+// @Success 200 {object} responses.Photo
 // @Router /photos/{id} [put]
 func (server *Server) UpdatePhoto(c *gin.Context) {
 
@@ -191,16 +276,6 @@ func (server *Server) UpdatePhoto(c *gin.Context) {
 		})
 		return
 	}
-	//CHeck if the auth token is valid and  get the user id from it
-	uid, err := auth.ExtractTokenID(c.Request)
-	if err != nil {
-		errList["Unauthorized"] = "Unauthorized"
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status": http.StatusUnauthorized,
-			"error":  errList,
-		})
-		return
-	}
 	//Check if the photo exist
 	origPhoto := models.Photo{}
 	err = server.DB.Debug().Model(models.Photo{}).Where("id = ?", pid).Take(&origPhoto).Error
@@ -212,7 +287,8 @@ func (server *Server) UpdatePhoto(c *gin.Context) {
 		})
 		return
 	}
-	if uid != origPhoto.UserID {
+	// owners can change their own photo, moderators can change anyone's
+	if !server.canModify(c, origPhoto.UserID, policy.ModeratePhotos) {
 		errList["Unauthorized"] = "Unauthorized"
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"status": http.StatusUnauthorized,
@@ -263,9 +339,21 @@ func (server *Server) UpdatePhoto(c *gin.Context) {
 		})
 		return
 	}
+	if err = server.Notifier.Mentioned(photoUpdated.Mentions); err != nil {
+		fmt.Println("this is the error notifying the mentioned users: ", err)
+	}
+	photos := []models.Photo{*photoUpdated}
+	if err = server.fillPhotoLikes(c, photos); err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"status":   http.StatusOK,
-		"response": photoUpdated,
+		"response": responses.NewPhoto(photos[0], server.viewer(c)),
 	})
 }
 
@@ -295,16 +383,6 @@ func (server *Server) DeletePhoto(c *gin.Context) {
 
 	fmt.Println("this is delete photo sir")
 
-	// Is this user authenticated?
-	uid, err := auth.ExtractTokenID(c.Request)
-	if err != nil {
-		errList["Unauthorized"] = "Unauthorized"
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status": http.StatusUnauthorized,
-			"error":  errList,
-		})
-		return
-	}
 	// Check if the photo exist
 	photo := models.Photo{}
 	err = server.DB.Debug().Model(models.Photo{}).Where("id = ?", pid).Take(&photo).Error
@@ -316,8 +394,8 @@ func (server *Server) DeletePhoto(c *gin.Context) {
 		})
 		return
 	}
-	// Is the authenticated user, the owner of this photo?
-	if uid != photo.UserID {
+	// Is the authenticated user the owner of this photo, or a moderator?
+	if !server.canModify(c, photo.UserID, policy.ModeratePhotos) {
 		errList["Unauthorized"] = "Unauthorized"
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"status": http.StatusUnauthorized,
@@ -326,6 +404,19 @@ func (server *Server) DeletePhoto(c *gin.Context) {
 		return
 	}
 
+	// the variant files are removed together with the original
+	variant := models.PhotoVariant{}
+	variants, err := variant.FindPhotoVariants(server.DB, []uint64{photo.ID})
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	photo.Variants = variants[photo.ID]
+
 	_, err = photo.DeleteAPhoto(server.DB)
 	if err != nil {
 		errList["Other_error"] = "Please try again later"
@@ -335,6 +426,7 @@ func (server *Server) DeletePhoto(c *gin.Context) {
 		})
 		return
 	}
+	server.removeStoredFiles(photo.StorageKeys()...)
 
 	c.JSON(http.StatusOK, gin.H{
 		"status":   http.StatusOK,
@@ -365,8 +457,16 @@ func (server *Server) GetUserPhotos(c *gin.Context) {
 		})
 		return
 	}
+	if err = server.fillPhotoLikes(c, *photos); err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"status":   http.StatusOK,
-		"response": photos,
+		"response": responses.NewPhotos(*photos, server.viewer(c)),
 	})
 }
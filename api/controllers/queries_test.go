@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+)
+
+// countQueries counts every statement reading the database of the server from now on
+func countQueries(server *Server) *int64 {
+	var count int64
+	increment := func(*gorm.Scope) { atomic.AddInt64(&count, 1) }
+	server.DB.Callback().Query().After("gorm:query").Register("test:count_queries", increment)
+	server.DB.Callback().RowQuery().After("gorm:row_query").Register("test:count_row_queries", increment)
+	return &count
+}
+
+// seedFeed has alice follow n users who post a tagged photo each, that alice likes and comments on
+// and its poster likes and replies to. Every poster links a social media account and alice posts n photos too.
+func seedFeed(t *testing.T, server *Server, n int) (aliceToken string) {
+	t.Helper()
+	aliceToken = login(t, server, createTestUser(t, server, "alice"))
+
+	mustServe := func(method, path, token, body string) map[string]interface{} {
+		w := serve(server, method, path, token, body)
+		if w.Code != http.StatusOK && w.Code != http.StatusCreated {
+			t.Fatalf("%s %s: %d %s", method, path, w.Code, w.Body)
+		}
+		response := struct {
+			Response map[string]interface{} `json:"response"`
+		}{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return response.Response
+	}
+	for i := 0; i < n; i++ {
+		poster := createTestUser(t, server, fmt.Sprintf("poster%d", i))
+		posterToken := login(t, server, poster)
+		mustServe(http.MethodPost, fmt.Sprintf("/api/v1/users/%d/follow", poster.ID), aliceToken, "")
+
+		photo := mustServe(http.MethodPost, "/api/v1/photos", posterToken,
+			fmt.Sprintf(`{"title":"photo %d","caption":"#sunset %d with @alice","photo_url":"https://example.com/%d.jpg"}`, i, i, i))
+		pid := uint64(photo["id"].(float64))
+		mustServe(http.MethodPost, fmt.Sprintf("/api/v1/photos/%d/like", pid), aliceToken, "")
+		comment := mustServe(http.MethodPost, fmt.Sprintf("/api/v1/comments/%d", pid), aliceToken, fmt.Sprintf(`{"message":"nice one @poster%d"}`, i))
+		cid := uint64(comment["id"].(float64))
+		mustServe(http.MethodPost, fmt.Sprintf("/api/v1/comments/%d/like", cid), posterToken, "")
+		mustServe(http.MethodPost, fmt.Sprintf("/api/v1/comments/%d/replies", cid), posterToken, `{"message":"thanks"}`)
+
+		mustServe(http.MethodPost, "/api/v1/social-media", posterToken,
+			fmt.Sprintf(`{"name":"poster%d","socialMediaURL":"https://example.com/poster%d"}`, i, i))
+		mustServe(http.MethodPost, "/api/v1/photos", aliceToken,
+			fmt.Sprintf(`{"title":"alice %d","caption":"#sunset by alice","photo_url":"https://example.com/alice%d.jpg"}`, i, i))
+	}
+	return aliceToken
+}
+
+// TestListQueryCounts guards the list endpoints against N+1 queries: the number of queries of a page is fixed,
+// whatever the number of photos and comments on it
+func TestListQueryCounts(t *testing.T) {
+	want := map[string]int64{
+		"/api/v1/photos":           11,
+		"/api/v1/comments":         9,
+		"/api/v1/feed":             11,
+		"/api/v1/social-media-all": 3,
+		"/api/v1/users/1/photos":   10,
+	}
+	for path, queries := range want {
+		for _, n := range []int{2, 8} {
+			server := newTestServer(t)
+			// GetUserPhotos has no route of its own yet
+			server.Router.GET("/api/v1/users/:id/photos", server.GetUserPhotos)
+			token := seedFeed(t, server, n)
+			count := countQueries(server)
+
+			w := serve(server, http.MethodGet, path, token, "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("GET %s: %d %s", path, w.Code, w.Body)
+			}
+			if *count != queries {
+				t.Errorf("GET %s with %d photos ran %d queries, want %d", path, n, *count, queries)
+			}
+		}
+	}
+}
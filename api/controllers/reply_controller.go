@@ -0,0 +1,288 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/models"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/responses"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/utils/formaterror"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/utils/pagination"
+	"github.com/gin-gonic/gin"
+)
+
+// CreateReply godoc
+// @Summary     Reply to Comment
+// @Description Reply to a comment, replies can be nested up to a maximum depth
+// @Tags        Comment
+// @Accept      json
+// @Produce     json
+// @Param       id path int true "Comment ID"
+// @Param       CreateComment body models.CreateComment true "Comment Data"
+// @Security ApiKeyAuth
+// @Success     201  {object} responses.Comment
+// @Router      /comments/{id}/replies [post]
+func (server *Server) CreateReply(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	cid, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		errList["Invalid_request"] = "Invalid Request"
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  errList,
+		})
+		return
+	}
+	uid, err := server.Tokens.ExtractTokenID(c.Request)
+	if err != nil {
+		errList["Unauthorized"] = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  errList,
+		})
+		return
+	}
+	// check if the user exist:
+	user := models.User{}
+	err = server.DB.Debug().Model(models.User{}).Where("id = ?", uid).Take(&user).Error
+	if err != nil {
+		errList["Unauthorized"] = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  errList,
+		})
+		return
+	}
+	// check if the comment replied to exist:
+	parent := models.Comment{}
+	err = server.DB.Debug().Model(models.Comment{}).Where("id = ?", cid).Take(&parent).Error
+	if err != nil {
+		errList["No_comment"] = "No Comment Found"
+		c.JSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  errList,
+		})
+		return
+	}
+
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		errList["Invalid_body"] = "Unable to get request"
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+	reply := models.Comment{}
+	err = json.Unmarshal(body, &reply)
+	if err != nil {
+		errList["Unmarshal_error"] = "Cannot unmarshal body"
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+	reply.UserID = uid
+	reply.Deleted = false
+	// the photo, the parent and the depth all come from the comment replied to
+	if err = reply.ReplyTo(parent); err != nil {
+		errList = formaterror.FormatError(err.Error())
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+
+	reply.Prepare()
+	errorMessages := reply.Validate()
+	if len(errorMessages) > 0 {
+		errList = errorMessages
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+
+	replyCreated, err := reply.SaveComment(server.DB)
+	if err != nil {
+		errList := formaterror.FormatError(err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	server.publishComment(*replyCreated)
+	if err = server.Notifier.CommentCreated(*replyCreated); err != nil {
+		fmt.Println("this is the error notifying about the reply: ", err)
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"status":   http.StatusCreated,
+		"response": responses.NewComment(*replyCreated, server.viewer(c)),
+	})
+}
+
+// GetCommentReplies godoc
+// @Summary Get Comment Replies
+// @Description Retrieve the replies to a comment. The direct replies are paged and come with the first replies under them
+// @Tags Comment
+// @Accept json
+// @Produce json
+// @Param id path int true "Comment ID"
+// @Param view query string false "flat for the thread depth first, tree for the replies nested in their comment" Enums(flat, tree) default(flat)
+// @Param cursor query string false "Cursor from the pagination of a previous page"
+// @Param limit query int false "Page size, at most 100" default(20)
+// @Param sort query string false "created_at or id, prefixed with - for descending order" default(-created_at)
+// @Param user_id query int false "Only items of this user"
+// @Param created_after query string false "Only items created after this time (RFC3339 or YYYY-MM-DD)"
+// @Param created_before query string false "Only items created before this time (RFC3339 or YYYY-MM-DD)"
+// @Success 200 {array} responses.Comment
+// @Router /comments/{id}/replies [get]
+func (server *Server) GetCommentReplies(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	cid, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		errList["Invalid_request"] = "Invalid Request"
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  errList,
+		})
+		return
+	}
+	view, ok := threadView(c, "flat")
+	if !ok {
+		return
+	}
+	page, filter, ok := listParams(c, models.CommentSortKeys)
+	if !ok {
+		return
+	}
+	err = server.DB.Debug().Model(models.Comment{}).Where("id = ?", cid).Take(&models.Comment{}).Error
+	if err != nil {
+		errList["No_comment"] = "No Comment Found"
+		c.JSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  errList,
+		})
+		return
+	}
+	comment := models.Comment{}
+
+	replies, links, err := comment.FindReplyThreads(server.DB, cid, page, filter)
+	if err == nil {
+		err = server.fillCommentLikes(c, *replies)
+	}
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	if view == "tree" {
+		*replies = models.NestThreads(*replies)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":     http.StatusOK,
+		"response":   responses.NewComments(*replies, server.viewer(c)),
+		"pagination": links,
+	})
+}
+
+// GetCommentThread godoc
+// @Summary Get More Replies
+// @Description Retrieve the replies under a comment that a thread left out, depth first, from the more_replies cursor of the comment
+// @Tags Comment
+// @Accept json
+// @Produce json
+// @Param id path int true "Comment ID"
+// @Param cursor query string true "more_replies of the comment, or of the previous response"
+// @Param view query string false "flat for the thread depth first, tree for the replies nested in their comment" Enums(flat, tree) default(flat)
+// @Success 200 {array} responses.Comment
+// @Router /comments/{id}/thread [get]
+func (server *Server) GetCommentThread(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	cid, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		errList["Invalid_request"] = "Invalid Request"
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  errList,
+		})
+		return
+	}
+	view, ok := threadView(c, "flat")
+	if !ok {
+		return
+	}
+	comment := models.Comment{}
+	err = server.DB.Debug().Model(models.Comment{}).Where("id = ?", cid).Take(&comment).Error
+	if err != nil {
+		errList["No_comment"] = "No Comment Found"
+		c.JSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  errList,
+		})
+		return
+	}
+
+	replies, next, err := comment.FindMoreReplies(server.DB, comment, c.Query("cursor"))
+	if err == pagination.ErrInvalidCursor {
+		errList["Invalid_cursor"] = "Invalid Cursor"
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  errList,
+		})
+		return
+	}
+	if err == nil {
+		err = server.fillCommentLikes(c, *replies)
+	}
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	if view == "tree" {
+		*replies = models.NestThreads(*replies)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":       http.StatusOK,
+		"response":     responses.NewComments(*replies, server.viewer(c)),
+		"more_replies": next,
+	})
+}
+
+// threadView reads how a thread is returned: flat, depth first, or as a tree. It answers 400 for anything else
+func threadView(c *gin.Context, defaultView string) (string, bool) {
+	view := c.DefaultQuery("view", defaultView)
+	if view == defaultView || view == "flat" || view == "tree" {
+		return view, true
+	}
+	errList["Invalid_view"] = "Invalid View"
+	c.JSON(http.StatusBadRequest, gin.H{
+		"status": http.StatusBadRequest,
+		"error":  errList,
+	})
+	return "", false
+}
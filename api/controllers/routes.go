@@ -2,6 +2,8 @@ package controllers
 
 import (
 	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/middlewares"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/policy"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/storage"
 	docs "github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/docs"
 	swaggerfiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
@@ -14,27 +16,83 @@ func (s *Server) initializeRoutes() {
 		// Login Route
 		v1.POST("/login", s.Login)
 		v1.POST("/users", s.Register)
+		v1.POST("/token/refresh", s.RefreshToken)
+		v1.POST("/logout", middlewares.TokenAuthMiddleware(s.Tokens), s.Logout)
+		v1.POST("/password/forgot", s.ForgotPassword)
+		v1.POST("/password/reset", s.ResetPassword)
+		v1.GET("/users/verify", s.VerifyEmail)
+		v1.POST("/users/verify/resend", middlewares.TokenAuthMiddleware(s.Tokens), s.ResendVerification)
+
+		//User routes
+		v1.GET("/users/me", middlewares.TokenAuthMiddleware(s.Tokens), s.GetMe)
+		v1.PUT("/users/me", middlewares.TokenAuthMiddleware(s.Tokens), s.UpdateUser)
+		v1.DELETE("/users/me", middlewares.TokenAuthMiddleware(s.Tokens), s.DeleteUser)
+		v1.PUT("/users/me/password", middlewares.TokenAuthMiddleware(s.Tokens), s.ChangePassword)
+		v1.GET("/users/:id", s.GetUser)
+		v1.PUT("/users/:id", middlewares.TokenAuthMiddleware(s.Tokens), s.UpdateUser)
+		v1.DELETE("/users/:id", middlewares.TokenAuthMiddleware(s.Tokens), s.DeleteUser)
+		v1.POST("/users/:id/follow", s.writeAuth(), s.FollowUser)
+		v1.DELETE("/users/:id/follow", middlewares.TokenAuthMiddleware(s.Tokens), s.UnfollowUser)
+		v1.GET("/users/:id/followers", s.GetFollowers)
+		v1.GET("/users/:id/following", s.GetFollowing)
 
 		//Photos routes
 		v1.GET("/photos", s.GetPhotos)
 		v1.GET("/photos/:id", s.GetPhoto)
-		v1.POST("/photos", middlewares.TokenAuthMiddleware(), s.CreatePhoto)
-		v1.PUT("/photos/:id", middlewares.TokenAuthMiddleware(), s.UpdatePhoto)
-		v1.DELETE("/photos/:id", middlewares.TokenAuthMiddleware(), s.DeletePhoto)
+		v1.POST("/photos", s.writeAuth(), s.CreatePhoto)
+		v1.PUT("/photos/:id", middlewares.TokenAuthMiddleware(s.Tokens), s.UpdatePhoto)
+		v1.DELETE("/photos/:id", middlewares.TokenAuthMiddleware(s.Tokens), s.DeletePhoto)
+		v1.POST("/photos/:id/like", s.writeAuth(), s.LikePhoto)
+		v1.DELETE("/photos/:id/like", middlewares.TokenAuthMiddleware(s.Tokens), s.UnlikePhoto)
+		v1.GET("/photos/:id/comments", s.GetPhotoComments)
+
+		//Feed routes
+		v1.GET("/feed", middlewares.TokenAuthMiddleware(s.Tokens), s.GetFeed)
+
+		//Stream routes
+		v1.GET("/stream", middlewares.TokenAuthMiddleware(s.Tokens), s.GetStream)
+
+		//Notification routes
+		v1.GET("/notifications", middlewares.TokenAuthMiddleware(s.Tokens), s.GetNotifications)
+		v1.POST("/notifications/read", middlewares.TokenAuthMiddleware(s.Tokens), s.MarkAllNotificationsRead)
+		v1.POST("/notifications/:id/read", middlewares.TokenAuthMiddleware(s.Tokens), s.MarkNotificationRead)
+		v1.GET("/notifications/preferences", middlewares.TokenAuthMiddleware(s.Tokens), s.GetNotificationPreferences)
+		v1.PUT("/notifications/preferences", middlewares.TokenAuthMiddleware(s.Tokens), s.UpdateNotificationPreferences)
+
+		//Tag routes
+		v1.GET("/tags", s.GetTags)
+		v1.GET("/tags/trending", s.GetTrendingTags)
+		v1.GET("/tags/:name/photos", s.GetTagPhotos)
 
 		//Comment routes
 		v1.GET("/comments", s.GetComments)
 		v1.GET("/comments/:id", s.GetComment)
-		v1.POST("/comments/:id", middlewares.TokenAuthMiddleware(), s.CreateComment)
-		v1.PUT("/comments/:id", middlewares.TokenAuthMiddleware(), s.UpdateComment)
-		v1.DELETE("/comments/:id", middlewares.TokenAuthMiddleware(), s.DeleteComment)
+		v1.POST("/comments/:id", s.writeAuth(), s.CreateComment)
+		v1.PUT("/comments/:id", middlewares.TokenAuthMiddleware(s.Tokens), s.UpdateComment)
+		v1.DELETE("/comments/:id", middlewares.TokenAuthMiddleware(s.Tokens), s.DeleteComment)
+		v1.POST("/comments/:id/like", s.writeAuth(), s.LikeComment)
+		v1.DELETE("/comments/:id/like", middlewares.TokenAuthMiddleware(s.Tokens), s.UnlikeComment)
+		v1.GET("/comments/:id/replies", s.GetCommentReplies)
+		v1.POST("/comments/:id/replies", s.writeAuth(), s.CreateReply)
+		v1.GET("/comments/:id/thread", s.GetCommentThread)
 
 		//SocialMedia routes
 		v1.GET("/social-media-all", s.GetSocialMediaAll)
 		v1.GET("/social-media/:id", s.GetSocialMedia)
-		v1.POST("/social-media", middlewares.TokenAuthMiddleware(), s.CreateSocialMedia)
-		v1.PUT("/social-media/:id", middlewares.TokenAuthMiddleware(), s.UpdateSocialMedia)
-		v1.DELETE("/social-media/:id", middlewares.TokenAuthMiddleware(), s.DeleteSocialMedia)
+		v1.POST("/social-media", s.writeAuth(), s.CreateSocialMedia)
+		v1.PUT("/social-media/:id", middlewares.TokenAuthMiddleware(s.Tokens), s.UpdateSocialMedia)
+		v1.DELETE("/social-media/:id", middlewares.TokenAuthMiddleware(s.Tokens), s.DeleteSocialMedia)
+
+		//Admin routes
+		admin := v1.Group("/admin", middlewares.TokenAuthMiddleware(s.Tokens))
+		{
+			admin.PUT("/users/:id/role", middlewares.RequirePermission(policy.AssignRoles), s.UpdateUserRole)
+		}
+	}
+
+	// uploaded photos are served by the API itself when they are kept on the local disk
+	if local, ok := s.Storage.(*storage.LocalStorage); ok {
+		s.Router.Static("/uploads", local.Dir)
 	}
 
 	s.Router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerfiles.Handler))
@@ -0,0 +1,188 @@
+package controllers
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Run serves the API until SIGINT or SIGTERM, then lets the requests in flight finish and closes what
+// Initialize opened. It only returns an error when the API cannot be served at all.
+func (server *Server) Run() error {
+
+	cfg := server.Config.HTTP
+	httpServer := &http.Server{
+		Addr:              server.Config.App.Addr(),
+		Handler:           server.Router,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		// no WriteTimeout, writeDeadline sets it per request so GET /stream can stay open
+		IdleTimeout: cfg.IdleTimeout,
+		ConnContext: withConn,
+		// HTTP/2 multiplexes the requests over one connection, the deadlines of which are set per request
+		TLSNextProto: map[string]func(*http.Server, *tls.Conn, http.Handler){},
+	}
+	// the streams only end when the hub closes, Shutdown would otherwise wait for them until it gives up
+	httpServer.RegisterOnShutdown(func() {
+		if err := server.Hub.Close(); err != nil {
+			fmt.Println("this is the error closing the realtime hub: ", err)
+		}
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	served := make(chan error, 1)
+	if cfg.TLS() {
+		cert, err := loadCertificate(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			server.Close()
+			return err
+		}
+		go cert.reloadOnHangup(ctx)
+		httpServer.TLSConfig = &tls.Config{GetCertificate: cert.get, MinVersion: tls.VersionTLS12}
+		go func() { served <- httpServer.ListenAndServeTLS("", "") }()
+	} else {
+		go func() { served <- httpServer.ListenAndServe() }()
+	}
+
+	select {
+	case err := <-served:
+		server.Close()
+		return err
+	case <-ctx.Done():
+	}
+	// a second signal stops the process right away
+	stop()
+
+	fmt.Printf("\nShutting down, the requests in flight get %s to finish\n", cfg.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		fmt.Println("this is the error waiting for the requests in flight: ", err)
+		httpServer.Close()
+	}
+	if err := <-served; !errors.Is(err, http.ErrServerClosed) {
+		fmt.Println("this is the error serving the API: ", err)
+	}
+	// closing the connections does not stop their handlers, what they use stays open until they return
+	server.handlers.Wait()
+	return server.Close()
+}
+
+// Close waits for the queued photo variants and emails, then disconnects the realtime hub and the database
+func (server *Server) Close() error {
+	if server.Variants != nil {
+		server.Variants.Close()
+	}
+	server.mail.Wait()
+	if server.Hub != nil {
+		if err := server.Hub.Close(); err != nil {
+			fmt.Println("this is the error closing the realtime hub: ", err)
+		}
+	}
+	if server.DB == nil {
+		return nil
+	}
+	return server.DB.Close()
+}
+
+// connKey is the context key of the connection a request came in on
+type connKey struct{}
+
+// withConn is the http.Server ConnContext that gives the requests access to their connection
+func withConn(ctx context.Context, conn net.Conn) context.Context {
+	return context.WithValue(ctx, connKey{}, conn)
+}
+
+// inFlight tracks the handlers running, Run waits for them before it closes what they use
+func (server *Server) inFlight(c *gin.Context) {
+	server.handlers.Add(1)
+	defer server.handlers.Done()
+	c.Next()
+}
+
+// writeDeadline limits how long the response may take to write, like http.Server.WriteTimeout does
+// but set per request so that keepOpen can lift it
+func (server *Server) writeDeadline(c *gin.Context) {
+	conn, ok := c.Request.Context().Value(connKey{}).(net.Conn)
+	if ok && server.Config.HTTP.WriteTimeout > 0 {
+		conn.SetWriteDeadline(time.Now().Add(server.Config.HTTP.WriteTimeout))
+	}
+	c.Next()
+}
+
+// keepOpen lifts the deadlines of the connection for a response that stays open, the read timeout would
+// cancel the request and the write timeout cut the response. The next request on the connection sets them again.
+func keepOpen(c *gin.Context) {
+	if conn, ok := c.Request.Context().Value(connKey{}).(net.Conn); ok {
+		conn.SetReadDeadline(time.Time{})
+		conn.SetWriteDeadline(time.Time{})
+	}
+}
+
+// certificate is the TLS certificate the API presents. It is read again on SIGHUP, so a renewed certificate
+// is picked up without a restart.
+type certificate struct {
+	certFile string
+	keyFile  string
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+}
+
+func loadCertificate(certFile, keyFile string) (*certificate, error) {
+	c := &certificate{certFile: certFile, keyFile: keyFile}
+	if err := c.reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// reload reads the files, the certificate in use is kept when they cannot be loaded
+func (c *certificate) reload() error {
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("cannot load the TLS certificate: %v", err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cert = &cert
+	return nil
+}
+
+// get is the tls.Config GetCertificate of the server
+func (c *certificate) get(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cert, nil
+}
+
+// reloadOnHangup reloads the certificate on every SIGHUP until ctx is done
+func (c *certificate) reloadOnHangup(ctx context.Context) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+			if err := c.reload(); err != nil {
+				fmt.Println("this is the error reloading, the previous certificate is kept: ", err)
+			} else {
+				fmt.Println("Reloaded the TLS certificate")
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/config"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/models"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite" //sqlite database driver of the tests
+)
+
+// newTestServer serves the API from an in-memory sqlite database, with the mail, storage and realtime drivers
+// in memory as well
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("cannot open the test database: %v", err)
+	}
+	// every connection to :memory: opens a database of its own
+	db.DB().SetMaxOpenConns(1)
+	err = db.AutoMigrate(&models.User{}, &models.RefreshToken{}, &models.ResetPassword{}, &models.EmailVerification{},
+		&models.Photo{}, &models.PhotoVariant{}, &models.SocialMedia{}, &models.Comment{}, &models.Like{},
+		&models.CommentLike{}, &models.Follow{}, &models.TimelineEntry{}, &models.Tag{}, &models.PhotoTag{},
+		&models.Mention{}, &models.Notification{}, &models.NotificationPreference{}).Error
+	if err != nil {
+		t.Fatalf("cannot create the test tables: %v", err)
+	}
+
+	cfg := config.Default()
+	cfg.App.URL = "http://localhost:8080"
+	cfg.App.PasswordResetURL = "http://localhost:3000/password/reset"
+	cfg.Auth.Secret = "a-secret-of-the-tests-only"
+	cfg.Storage.Driver = "memory"
+
+	server := &Server{DB: db}
+	server.setup(&cfg)
+	t.Cleanup(func() {
+		server.Close()
+	})
+	return server
+}
+
+// createTestUser saves a user with the password "password"
+func createTestUser(t *testing.T, server *Server, username string) models.User {
+	t.Helper()
+	user := models.User{Username: username, Email: username + "@example.com", Password: "password", Age: 20}
+	user.Prepare()
+	if _, err := user.SaveUser(server.DB); err != nil {
+		t.Fatalf("cannot create the user %s: %v", username, err)
+	}
+	return user
+}
+
+// login returns an access token of the user created by createTestUser
+func login(t *testing.T, server *Server, user models.User) string {
+	t.Helper()
+	w := serve(server, http.MethodPost, "/api/v1/login", "", `{"email":"`+user.Email+`","password":"password"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("cannot log in %s: %d %s", user.Username, w.Code, w.Body)
+	}
+	body := struct {
+		Response struct {
+			Token string `json:"token"`
+		} `json:"response"`
+	}{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body.Response.Token == "" {
+		t.Fatalf("cannot read the token of %s: %v %s", user.Username, err, w.Body)
+	}
+	return body.Response.Token
+}
+
+// serve runs one request through the router, with the access token when it is given
+func serve(server *Server, method, path, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	server.Router.ServeHTTP(w, req)
+	return w
+}
@@ -7,8 +7,9 @@ import (
 	"net/http"
 	"strconv"
 
-	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/auth"
 	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/models"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/policy"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/responses"
 	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/utils/formaterror"
 	"github.com/gin-gonic/gin"
 )
@@ -21,7 +22,7 @@ import (
 // @Produce     json
 // @Param       CreateSocialMedia body models.CreateSocialMedia true "SocialMedia Data"
 // @Security ApiKeyAuth
-// @Success     200  {object} models.SocialMedia
+// @Success     200  {object} responses.SocialMedia
 // @Router      /social-media [post]
 func (server *Server) CreateSocialMedia(c *gin.Context) {
 
@@ -48,7 +49,7 @@ func (server *Server) CreateSocialMedia(c *gin.Context) {
 		})
 		return
 	}
-	uid, err := auth.ExtractTokenID(c.Request)
+	uid, err := server.Tokens.ExtractTokenID(c.Request)
 	if err != nil {
 		errList["Unauthorized"] = "Unauthorized"
 		c.JSON(http.StatusUnauthorized, gin.H{
@@ -94,7 +95,7 @@ func (server *Server) CreateSocialMedia(c *gin.Context) {
 	}
 	c.JSON(http.StatusCreated, gin.H{
 		"status":   http.StatusCreated,
-		"response": socialMediaCreated,
+		"response": responses.NewSocialMedia(*socialMediaCreated, server.viewer(c)),
 	})
 }
 
@@ -104,13 +105,26 @@ func (server *Server) CreateSocialMedia(c *gin.Context) {
 // @Tags Social Media
 // @Accept json
 // @Produce json
-// @Success 200 {array} models.SocialMedia
+// @Success 200 {array} responses.SocialMedia
+// @Param cursor query string false "Cursor from the pagination of a previous page"
+// @Param limit query int false "Page size, at most 100" default(20)
+// @Param sort query string false "created_at, id or name, prefixed with - for descending order" default(-created_at)
+// @Param user_id query int false "Only items of this user"
+// @Param created_after query string false "Only items created after this time (RFC3339 or YYYY-MM-DD)"
+// @Param created_before query string false "Only items created before this time (RFC3339 or YYYY-MM-DD)"
 // @Router /social-media-all [get]
 func (server *Server) GetSocialMediaAll(c *gin.Context) {
 
+	//clear previous error if any
+	errList = map[string]string{}
+
+	page, filter, ok := listParams(c, models.SocialMediaSortKeys)
+	if !ok {
+		return
+	}
 	socialMedia := models.SocialMedia{}
 
-	socialMedias, err := socialMedia.FindAllSocialMedia(server.DB)
+	socialMedias, links, err := socialMedia.FindAllSocialMedia(server.DB, page, filter)
 	if err != nil {
 		errList["No_socialMedia"] = "No Social Media Found"
 		c.JSON(http.StatusNotFound, gin.H{
@@ -120,8 +134,9 @@ func (server *Server) GetSocialMediaAll(c *gin.Context) {
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{
-		"status":   http.StatusOK,
-		"response": socialMedias,
+		"status":     http.StatusOK,
+		"response":   responses.NewSocialMediaList(*socialMedias, server.viewer(c)),
+		"pagination": links,
 	})
 }
 
@@ -132,7 +147,7 @@ func (server *Server) GetSocialMediaAll(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path int true "SocialMedia ID"
-// @Success 200 {object} models.SocialMedia
+// @Success 200 {object} responses.SocialMedia
 // @Router /social-media/{id} [get]
 func (server *Server) GetSocialMedia(c *gin.Context) {
 
@@ -160,7 +175,7 @@ func (server *Server) GetSocialMedia(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"status":   http.StatusOK,
-		"response": socialMediaReceived,
+		"response": responses.NewSocialMedia(*socialMediaReceived, server.viewer(c)),
 	})
 }
 
@@ -173,7 +188,7 @@ func (server *Server) GetSocialMedia(c *gin.Context) {
 // @Param id path int true "SocialMedia ID"
 // @Param UpdateSocialMedia body models.UpdateSocialMedia true "SocialMedia Data"
 // @Security ApiKeyAuth
-// @Success 200 {object} models.SocialMedia
+// @Success 200 {object} responses.SocialMedia
 // @Router /social-media/{id} [put]
 func (server *Server) UpdateSocialMedia(c *gin.Context) {
 
@@ -191,16 +206,6 @@ func (server *Server) UpdateSocialMedia(c *gin.Context) {
 		})
 		return
 	}
-	//CHeck if the auth token is valid and  get the user id from it
-	uid, err := auth.ExtractTokenID(c.Request)
-	if err != nil {
-		errList["Unauthorized"] = "Unauthorized"
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status": http.StatusUnauthorized,
-			"error":  errList,
-		})
-		return
-	}
 	//Check if the socialMedia exist
 	origSocialMedia := models.SocialMedia{}
 	err = server.DB.Debug().Model(models.SocialMedia{}).Where("id = ?", pid).Take(&origSocialMedia).Error
@@ -212,7 +217,8 @@ func (server *Server) UpdateSocialMedia(c *gin.Context) {
 		})
 		return
 	}
-	if uid != origSocialMedia.UserID {
+	// owners can change their own socialMedia, moderators can change anyone's
+	if !server.canModify(c, origSocialMedia.UserID, policy.ModerateSocialMedia) {
 		errList["Unauthorized"] = "Unauthorized"
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"status": http.StatusUnauthorized,
@@ -265,7 +271,7 @@ func (server *Server) UpdateSocialMedia(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, gin.H{
 		"status":   http.StatusOK,
-		"response": socialMediaUpdated,
+		"response": responses.NewSocialMedia(*socialMediaUpdated, server.viewer(c)),
 	})
 }
 
@@ -295,16 +301,6 @@ func (server *Server) DeleteSocialMedia(c *gin.Context) {
 
 	fmt.Println("this is delete socialMedia sir")
 
-	// Is this user authenticated?
-	uid, err := auth.ExtractTokenID(c.Request)
-	if err != nil {
-		errList["Unauthorized"] = "Unauthorized"
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status": http.StatusUnauthorized,
-			"error":  errList,
-		})
-		return
-	}
 	// Check if the socialMedia exist
 	socialMedia := models.SocialMedia{}
 	err = server.DB.Debug().Model(models.SocialMedia{}).Where("id = ?", pid).Take(&socialMedia).Error
@@ -316,8 +312,8 @@ func (server *Server) DeleteSocialMedia(c *gin.Context) {
 		})
 		return
 	}
-	// Is the authenticated user, the owner of this socialMedia?
-	if uid != socialMedia.UserID {
+	// Is the authenticated user the owner of this socialMedia, or a moderator?
+	if !server.canModify(c, socialMedia.UserID, policy.ModerateSocialMedia) {
 		errList["Unauthorized"] = "Unauthorized"
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"status": http.StatusUnauthorized,
@@ -367,6 +363,6 @@ func (server *Server) GetUserSocialMedias(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, gin.H{
 		"status":   http.StatusOK,
-		"response": socialMedias,
+		"response": responses.NewSocialMediaList(*socialMedias, server.viewer(c)),
 	})
 }
@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/models"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/policy"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/realtime"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/responses"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// maxStreamPhotos caps how many photos one stream can follow
+	maxStreamPhotos = 50
+	// streamKeepAlive is how often an idle stream gets a comment line, so proxies do not close it
+	streamKeepAlive = 25 * time.Second
+)
+
+// GetStream godoc
+// @Summary     Stream Updates
+// @Description Server-Sent Events stream of the authenticated user: their new notifications ("notification" events),
+// @Description and the new comments ("comment") and like counts ("likes") of the photos given in photo_id.
+// @Description Browsers can pass the access token as the token query parameter, EventSource cannot set headers.
+// @Tags        Stream
+// @Produce     text/event-stream
+// @Param       photo_id query []int false "Photos to follow, at most 50" collectionFormat(multi)
+// @Security ApiKeyAuth
+// @Success     200  {object} realtime.Message
+// @Router      /stream [get]
+func (server *Server) GetStream(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	uid, err := server.Tokens.ExtractTokenID(c.Request)
+	if err != nil {
+		errList["Unauthorized"] = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  errList,
+		})
+		return
+	}
+	photoIDs := c.QueryArray("photo_id")
+	if len(photoIDs) > maxStreamPhotos {
+		errList["Too_many_photos"] = fmt.Sprintf("A stream can follow at most %d photos", maxStreamPhotos)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  errList,
+		})
+		return
+	}
+	topics := []string{realtime.UserTopic(uid)}
+	for _, id := range photoIDs {
+		pid, err := strconv.ParseUint(id, 10, 64)
+		if err != nil {
+			errList["Invalid_request"] = "Invalid Request"
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status": http.StatusBadRequest,
+				"error":  errList,
+			})
+			return
+		}
+		topics = append(topics, realtime.PhotoTopic(pid))
+	}
+
+	// the stream stays open until the client goes or the server shuts down
+	keepOpen(c)
+	sub := server.Hub.Subscribe(topics...)
+	defer sub.Close()
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// nginx buffers responses unless told otherwise
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.WriteHeaderNow()
+	c.Writer.Flush()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case msg, ok := <-sub.C:
+			if !ok {
+				return false
+			}
+			c.SSEvent(msg.Event, msg)
+			return true
+		case <-keepAlive.C:
+			_, err := io.WriteString(w, ": keep-alive\n\n")
+			return err == nil
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
+
+// publish pushes a message to the streams, failing only costs the clients a live update so it is just logged
+func (server *Server) publish(topic, event string, data interface{}) {
+	if err := server.Hub.Publish(topic, event, data); err != nil {
+		fmt.Println("this is the error publishing to the streams: ", err)
+	}
+}
+
+// publishComment pushes a new comment to the streams of its photo. Streams are shared by every viewer,
+// so the comment is shown as to an anonymous one
+func (server *Server) publishComment(comment models.Comment) {
+	server.publish(realtime.PhotoTopic(comment.PhotoID), "comment", responses.NewComment(comment, policy.Actor{}))
+}
+
+// publishNotification pushes a new notification to the streams of its recipient, Notifier calls it
+func (server *Server) publishNotification(notification models.Notification) {
+	actor := models.User{}
+	err := server.DB.Debug().Model(models.User{}).Where("id = ?", notification.ActorID).Take(&actor).Error
+	if err != nil {
+		fmt.Println("this is the error publishing the notification: ", err)
+		return
+	}
+	notification.Actor = actor
+	list := responses.NewNotifications([]models.Notification{notification}, policy.Actor{ID: notification.UserID})
+	server.publish(realtime.UserTopic(notification.UserID), "notification", list[0])
+}
@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/realtime"
+)
+
+// stream is a GET /stream connection, events yields the name and data of the events it receives
+type stream struct {
+	cancel context.CancelFunc
+	events chan [2]string
+}
+
+func openStream(t *testing.T, api *httptest.Server, token, query string) *stream {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, api.URL+"/api/v1/stream?"+query, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		cancel()
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		cancel()
+		t.Fatalf("GET /stream: %d", resp.StatusCode)
+	}
+	s := &stream{cancel: cancel, events: make(chan [2]string, 16)}
+	go func() {
+		defer resp.Body.Close()
+		defer close(s.events)
+		scanner := bufio.NewScanner(resp.Body)
+		event := ""
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "event:"):
+				event = line[len("event:"):]
+			case strings.HasPrefix(line, "data:"):
+				s.events <- [2]string{event, line[len("data:"):]}
+			}
+		}
+	}()
+	t.Cleanup(cancel)
+	return s
+}
+
+// next returns the data of the next event with the name, skipping the others
+func (s *stream) next(t *testing.T, name string) string {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event, ok := <-s.events:
+			if !ok {
+				t.Fatalf("the stream ended before a %s event", name)
+			}
+			if event[0] == name {
+				return event[1]
+			}
+		case <-timeout:
+			t.Fatalf("no %s event on the stream", name)
+		}
+	}
+}
+
+// ended waits for the stream to end, it fails when more events come first
+func (s *stream) ended(t *testing.T) {
+	t.Helper()
+	select {
+	case event, ok := <-s.events:
+		if ok {
+			t.Fatalf("got a %s event, want the stream to end", event[0])
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the stream is still open")
+	}
+}
+
+// waitFor polls until cond holds
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !cond(); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+	}
+}
+
+func TestStream(t *testing.T) {
+	server := newTestServer(t)
+	api := httptest.NewServer(server.Router)
+	t.Cleanup(api.Close)
+
+	aliceToken := login(t, server, createTestUser(t, server, "alice"))
+	bobToken := login(t, server, createTestUser(t, server, "bob"))
+	carolToken := login(t, server, createTestUser(t, server, "carol"))
+	photo := postPhoto(t, server, aliceToken, "photo")
+	topic := realtime.PhotoTopic(photo)
+
+	alice := openStream(t, api, aliceToken, fmt.Sprintf("photo_id=%d", photo))
+	bob := openStream(t, api, bobToken, fmt.Sprintf("photo_id=%d", photo))
+	if n := server.Hub.Subscribers(topic); n != 2 {
+		t.Fatalf("the photo has %d subscribers, want the 2 streams", n)
+	}
+
+	// every stream of the photo gets the comment, the owner of the photo a notification too
+	postComment(t, server, carolToken, photo, false, "hello from carol")
+	for name, s := range map[string]*stream{"alice": alice, "bob": bob} {
+		if data := s.next(t, "comment"); !strings.Contains(data, `"message":"hello from carol"`) {
+			t.Errorf("comment event of %s: %s", name, data)
+		}
+	}
+	if data := alice.next(t, "notification"); !strings.Contains(data, `"carol"`) {
+		t.Errorf("notification event of alice: %s", data)
+	}
+
+	// a client that goes away is unsubscribed
+	bob.cancel()
+	waitFor(t, "bob's stream to unsubscribe", func() bool { return server.Hub.Subscribers(topic) == 1 })
+	serve(server, http.MethodPost, fmt.Sprintf("/api/v1/photos/%d/like", photo), carolToken, "")
+	if data := alice.next(t, "likes"); !strings.Contains(data, `"like_count":1`) {
+		t.Errorf("likes event of alice: %s", data)
+	}
+
+	// shutting the hub down ends the streams still open
+	if err := server.Hub.Close(); err != nil {
+		t.Fatal(err)
+	}
+	alice.ended(t)
+	if n := server.Hub.Subscribers(topic); n != 0 {
+		t.Errorf("the photo has %d subscribers after the shutdown", n)
+	}
+}
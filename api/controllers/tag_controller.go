@@ -0,0 +1,167 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/models"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/responses"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/utils/hashtag"
+	"github.com/gin-gonic/gin"
+)
+
+// maxTagResults caps the limit of the tag autocompletion and trending lists
+const maxTagResults = 50
+
+// GetTagPhotos godoc
+// @Summary     Get Tag Photos
+// @Description Retrieve the photos whose caption carries a hashtag
+// @Tags        Tag
+// @Accept      json
+// @Produce     json
+// @Param       name path string true "Tag, with or without the #"
+// @Param       cursor query string false "Cursor from the pagination of a previous page"
+// @Param       limit query int false "Page size, at most 100" default(20)
+// @Param       sort query string false "created_at, id or title, prefixed with - for descending order" default(-created_at)
+// @Param       user_id query int false "Only items of this user"
+// @Param       created_after query string false "Only items created after this time (RFC3339 or YYYY-MM-DD)"
+// @Param       created_before query string false "Only items created before this time (RFC3339 or YYYY-MM-DD)"
+// @Success     200  {array} responses.Photo
+// @Router      /tags/{name}/photos [get]
+func (server *Server) GetTagPhotos(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	page, filter, ok := listParams(c, models.PhotoSortKeys)
+	if !ok {
+		return
+	}
+	photo := models.Photo{}
+
+	photos, links, err := photo.FindTagPhotos(server.DB, hashtag.Normalize(c.Param("name")), page, filter)
+	if err == nil {
+		err = server.fillPhotoLikes(c, *photos)
+	}
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":     http.StatusOK,
+		"response":   responses.NewPhotos(*photos, server.viewer(c)),
+		"pagination": links,
+	})
+}
+
+// GetTags godoc
+// @Summary     Complete Tag
+// @Description Retrieve the tags starting with a prefix, the most used first
+// @Tags        Tag
+// @Accept      json
+// @Produce     json
+// @Param       q query string true "Start of the tag, with or without the #"
+// @Param       limit query int false "How many tags, at most 50" default(10)
+// @Success     200  {array} responses.Tag
+// @Router      /tags [get]
+func (server *Server) GetTags(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	limit, ok := tagLimit(c)
+	if !ok {
+		return
+	}
+	prefix := hashtag.Normalize(c.Query("q"))
+	if prefix == "" {
+		errList["Required_q"] = "Required Prefix"
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  errList,
+		})
+		return
+	}
+	tag := models.Tag{}
+
+	tags, err := tag.FindTagsByPrefix(server.DB, prefix, limit)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":   http.StatusOK,
+		"response": responses.NewTags(*tags),
+	})
+}
+
+// GetTrendingTags godoc
+// @Summary     Get Trending Tags
+// @Description Retrieve the tags put on the most photos lately
+// @Tags        Tag
+// @Accept      json
+// @Produce     json
+// @Param       window query string false "How far back to look, as a duration such as 24h" default(24h)
+// @Param       limit query int false "How many tags, at most 50" default(10)
+// @Success     200  {array} responses.TrendingTag
+// @Router      /tags/trending [get]
+func (server *Server) GetTrendingTags(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	limit, ok := tagLimit(c)
+	if !ok {
+		return
+	}
+	window, err := time.ParseDuration(c.DefaultQuery("window", "24h"))
+	if err != nil || window <= 0 {
+		errList["Invalid_window"] = "Invalid Window"
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  errList,
+		})
+		return
+	}
+	tag := models.Tag{}
+
+	tags, err := tag.FindTrendingTags(server.DB, time.Now().Add(-window), limit)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":   http.StatusOK,
+		"response": responses.NewTrendingTags(*tags),
+	})
+}
+
+// tagLimit reads the limit of a tag list, it answers 400 when it is invalid
+func tagLimit(c *gin.Context) (int, bool) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 {
+		errList["Invalid_limit"] = "Invalid Limit"
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  errList,
+		})
+		return 0, false
+	}
+	if limit > maxTagResults {
+		limit = maxTagResults
+	}
+	return limit, true
+}
@@ -0,0 +1,172 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/models"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/security"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+// revokedFamilies lets the auth package check the refresh_tokens table
+type revokedFamilies struct {
+	db *gorm.DB
+}
+
+func (r revokedFamilies) FamilyRevoked(familyID string) bool {
+	refreshToken := models.RefreshToken{}
+	return refreshToken.IsFamilyRevoked(r.db, familyID)
+}
+
+// RefreshToken godoc
+// @Summary     Refresh Token
+// @Description Exchange a refresh token for a new access token and refresh token
+// @Tags        User
+// @Accept      json
+// @Produce     json
+// @Param       TokenRefresh body models.TokenRefresh true "Refresh Token"
+// @Success     200  {object} models.TokenRefresh
+// @Router      /token/refresh [post]
+func (server *Server) RefreshToken(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		errList["Invalid_body"] = "Unable to get request"
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+	input := models.TokenRefresh{}
+	err = json.Unmarshal(body, &input)
+	if err != nil {
+		errList["Unmarshal_error"] = "Cannot unmarshal body"
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+	if input.RefreshToken == "" {
+		errList["Required_refresh_token"] = "Required Refresh Token"
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+
+	refreshToken := models.RefreshToken{}
+	stored, err := refreshToken.FindRefreshToken(server.DB, security.Digest(input.RefreshToken))
+	if err != nil || stored.RevokedAt != nil || stored.IsExpired() {
+		errList["Unauthorized"] = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  errList,
+		})
+		return
+	}
+
+	// A refresh token can only be used once. Seeing it again means it was stolen,
+	// so the whole family is revoked and the legitimate user has to log in again.
+	consumed := false
+	if stored.UsedAt == nil {
+		consumed, err = stored.MarkUsed(server.DB)
+		if err != nil {
+			errList["Other_error"] = "Please try again later"
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status": http.StatusInternalServerError,
+				"error":  errList,
+			})
+			return
+		}
+	}
+	if !consumed {
+		fmt.Println("refresh token reuse detected, revoking family ", stored.FamilyID)
+		_, err = stored.RevokeFamily(server.DB, stored.FamilyID)
+		if err != nil {
+			fmt.Println("this is the error revoking the token family: ", err)
+		}
+		errList["Unauthorized"] = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  errList,
+		})
+		return
+	}
+
+	// reload the user so role changes and deleted accounts take effect on refresh
+	user := models.User{}
+	_, err = user.FindUserByID(server.DB, stored.UserID)
+	if err != nil {
+		errList["Unauthorized"] = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  errList,
+		})
+		return
+	}
+
+	tokens, err := server.issueTokens(&user, stored.FamilyID)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":   http.StatusOK,
+		"response": tokens,
+	})
+}
+
+// Logout godoc
+// @Summary     Logout
+// @Description Revoke the refresh token family of the current session
+// @Tags        User
+// @Accept      json
+// @Produce     json
+// @Security ApiKeyAuth
+// @Success     200  {string} string "Logged out"
+// @Router      /logout [post]
+func (server *Server) Logout(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	familyID, err := server.Tokens.ExtractTokenFamilyID(c.Request)
+	if err != nil {
+		errList["Unauthorized"] = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  errList,
+		})
+		return
+	}
+
+	refreshToken := models.RefreshToken{}
+	_, err = refreshToken.RevokeFamily(server.DB, familyID)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":   http.StatusOK,
+		"response": "Logged out",
+	})
+}
@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/imaging"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/models"
+	"github.com/gin-gonic/gin"
+	"github.com/twinj/uuid"
+)
+
+var errUploadTooLarge = errors.New("upload too large")
+
+// photoUpload is an image read from a multipart request, it is not stored yet
+type photoUpload struct {
+	data     []byte
+	info     imaging.Info
+	metadata imaging.Metadata
+}
+
+// readPhotoUpload reads the title, caption and "photo" file of a multipart request
+func (server *Server) readPhotoUpload(c *gin.Context, photo *models.Photo) (*photoUpload, error) {
+	maxUploadSize := server.Config.Uploads.MaxSize
+	// leave some room for the other form fields
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxUploadSize+1<<20)
+
+	file, header, err := c.Request.FormFile("photo")
+	if err != nil {
+		if err.Error() == "http: request body too large" {
+			return nil, errUploadTooLarge
+		}
+		return nil, err
+	}
+	defer file.Close()
+	if header.Size > maxUploadSize {
+		return nil, errUploadTooLarge
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(file, maxUploadSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxUploadSize {
+		return nil, errUploadTooLarge
+	}
+
+	// checked before anything decodes the pixels
+	info, err := imaging.Inspect(data, server.Config.Uploads.MaxPixels)
+	if err != nil {
+		return nil, err
+	}
+	// the metadata handling of uploads, see imaging.Ingest
+	data, info, metadata, err := imaging.Ingest(data, info, imaging.IngestOptions{
+		StripMetadata:   server.Config.Uploads.StripMetadata,
+		ExtractMetadata: server.Config.Uploads.ExtractMetadata,
+		AutoOrient:      server.Config.Uploads.AutoOrient,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	photo.Title = c.Request.FormValue("title")
+	photo.Caption = c.Request.FormValue("caption")
+	return &photoUpload{data: data, info: info, metadata: metadata}, nil
+}
+
+// uploadErrorResponse maps readPhotoUpload errors to a status code and error list
+func (server *Server) uploadErrorResponse(err error) (int, map[string]string) {
+	switch {
+	case errors.Is(err, errUploadTooLarge):
+		return http.StatusRequestEntityTooLarge, map[string]string{
+			"Too_large": fmt.Sprintf("Photo should be at most %d MB", server.Config.Uploads.MaxSize>>20),
+		}
+	case errors.Is(err, imaging.ErrTooManyPixels):
+		return http.StatusRequestEntityTooLarge, map[string]string{
+			"Too_many_pixels": fmt.Sprintf("Photo should be at most %d megapixels", server.Config.Uploads.MaxPixels/1000000),
+		}
+	case errors.Is(err, imaging.ErrUnsupportedType):
+		return http.StatusUnsupportedMediaType, map[string]string{
+			"Unsupported_type": "Photo should be a JPEG, PNG, GIF or WebP image",
+		}
+	case errors.Is(err, http.ErrMissingFile):
+		return http.StatusUnprocessableEntity, map[string]string{
+			"Required_photo": "Required Photo",
+		}
+	default:
+		return http.StatusUnprocessableEntity, map[string]string{
+			"Invalid_photo": "Unable to read photo",
+		}
+	}
+}
+
+// attach points the photo at the storage key the upload is going to be written to
+func (server *Server) attach(photo *models.Photo, upload *photoUpload) {
+	key := fmt.Sprintf("photos/%d/%s%s", photo.UserID, uuid.NewV4().String(), imaging.Extensions[upload.info.MimeType])
+	photo.StorageKey = key
+	photo.PhotoURL = server.Storage.URL(key)
+	photo.MimeType = upload.info.MimeType
+	photo.Width = upload.info.Width
+	photo.Height = upload.info.Height
+	photo.ByteSize = int64(len(upload.data))
+	photo.TakenAt = upload.metadata.TakenAt
+	photo.CameraModel = upload.metadata.CameraModel
+	photo.Orientation = upload.metadata.Orientation
+}
+
+func (server *Server) storeUpload(ctx context.Context, photo *models.Photo, upload *photoUpload) error {
+	return server.Storage.Put(ctx, photo.StorageKey, bytes.NewReader(upload.data), int64(len(upload.data)), photo.MimeType)
+}
+
+// removeStoredFiles deletes files whose database rows are gone, failures only leave orphans behind
+func (server *Server) removeStoredFiles(keys ...string) {
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		if err := server.Storage.Delete(context.Background(), key); err != nil {
+			fmt.Printf("Cannot delete stored file %s: %v\n", key, err)
+		}
+	}
+}
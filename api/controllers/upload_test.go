@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// decompressionBomb is a tiny PNG declaring 50000x50000 pixels, 10 GB once decoded
+func decompressionBomb(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	ihdr := data[12 : 12+4+13]
+	binary.BigEndian.PutUint32(ihdr[4:8], 50000)
+	binary.BigEndian.PutUint32(ihdr[8:12], 50000)
+	binary.BigEndian.PutUint32(data[12+4+13:], crc32.ChecksumIEEE(ihdr))
+	return data
+}
+
+func TestCreatePhotoPixelLimit(t *testing.T) {
+	server := newTestServer(t)
+	token := login(t, server, createTestUser(t, server, "alice"))
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("title", "bomb")
+	part, _ := form.CreateFormFile("photo", "bomb.png")
+	part.Write(decompressionBomb(t))
+	form.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/photos", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	server.Router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusRequestEntityTooLarge || !strings.Contains(w.Body.String(), "Too_many_pixels") {
+		t.Fatalf("got %d %s, want 413 Too_many_pixels", w.Code, w.Body)
+	}
+}
@@ -2,11 +2,17 @@ package controllers
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"strconv"
 
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/auth"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/middlewares"
 	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/models"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/policy"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/responses"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/security"
 	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/utils/formaterror"
 	"github.com/gin-gonic/gin"
 )
@@ -18,7 +24,7 @@ import (
 // @Accept      json
 // @Produce     json
 // @Param       UserRegister body models.UserRegister true "User Data"
-// @Success     200  {object} models.User
+// @Success     200  {object} responses.User
 // @Router      /users [post]
 func (server *Server) Register(c *gin.Context) {
 
@@ -47,6 +53,9 @@ func (server *Server) Register(c *gin.Context) {
 		return
 	}
 	user.Prepare()
+	// role and verification state are never taken from the request body
+	user.Role = policy.RoleUser
+	user.EmailVerifiedAt = nil
 	errorMessages := user.Validate("")
 	if len(errorMessages) > 0 {
 		errList = errorMessages
@@ -66,20 +75,32 @@ func (server *Server) Register(c *gin.Context) {
 		})
 		return
 	}
+	err = server.sendVerificationEmail(userCreated)
+	if err != nil {
+		// the account exists, the user can ask for another email
+		fmt.Println("this is the error sending the verification email: ", err)
+	}
 	c.JSON(http.StatusCreated, gin.H{
 		"status": http.StatusCreated,
-		"data":   userCreated,
+		"data":   responses.NewUser(*userCreated, policy.Actor{ID: userCreated.ID, Role: userCreated.Role}),
 	})
 }
 
+// GetUser godoc
+// @Summary     Get User by ID
+// @Description Retrieve a user by ID
+// @Tags        User
+// @Accept      json
+// @Produce     json
+// @Param       id path int true "User ID"
+// @Success     200  {object} responses.Profile
+// @Router      /users/{id} [get]
 func (server *Server) GetUser(c *gin.Context) {
 
 	//clear previous error if any
 	errList = map[string]string{}
 
-	userID := c.Param("id")
-
-	uid, err := strconv.ParseUint(userID, 10, 32)
+	uid, err := resolveUserID(c)
 	if err != nil {
 		errList["Invalid_request"] = "Invalid Request"
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -90,7 +111,185 @@ func (server *Server) GetUser(c *gin.Context) {
 	}
 	user := models.User{}
 
-	userGotten, err := user.FindUserByID(server.DB, uint32(uid))
+	userGotten, err := user.FindUserByID(server.DB, uid)
+	if err != nil {
+		errList["No_user"] = "No User Found"
+		c.JSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  errList,
+		})
+		return
+	}
+	users := []models.User{*userGotten}
+	follow := models.Follow{}
+	if err = follow.FillUserFollows(server.DB, users, server.viewer(c).ID); err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":   http.StatusOK,
+		"response": responses.NewProfile(users[0], server.viewer(c)),
+	})
+}
+
+// GetMe godoc
+// @Summary     Get Current User
+// @Description Retrieve the authenticated user
+// @Tags        User
+// @Accept      json
+// @Produce     json
+// @Security ApiKeyAuth
+// @Success     200  {object} responses.Profile
+// @Router      /users/me [get]
+func (server *Server) GetMe(c *gin.Context) {
+	server.GetUser(c)
+}
+
+// UpdateUser godoc
+// @Summary     Update User
+// @Description Update the profile of a user. Use /users/me for the authenticated user.
+// @Tags        User
+// @Accept      json
+// @Produce     json
+// @Param       id path int true "User ID"
+// @Param       UpdateUser body models.UpdateUser true "User Data"
+// @Security ApiKeyAuth
+// @Success     200  {object} responses.User
+// @Router      /users/{id} [put]
+func (server *Server) UpdateUser(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	uid, err := resolveUserID(c)
+	if err != nil {
+		errList["Invalid_request"] = "Invalid Request"
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  errList,
+		})
+		return
+	}
+	if !server.canModify(c, uid, policy.ManageUsers) {
+		errList["Unauthorized"] = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  errList,
+		})
+		return
+	}
+	origUser := models.User{}
+	_, err = origUser.FindUserByID(server.DB, uid)
+	if err != nil {
+		errList["No_user"] = "No User Found"
+		c.JSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  errList,
+		})
+		return
+	}
+
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		errList["Invalid_body"] = "Unable to get request"
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+	input := models.UpdateUser{}
+	err = json.Unmarshal(body, &input)
+	if err != nil {
+		errList["Unmarshal_error"] = "Cannot unmarshal body"
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+
+	user := models.User{
+		Username:        input.Username,
+		Email:           input.Email,
+		Age:             input.Age,
+		EmailVerifiedAt: origUser.EmailVerifiedAt,
+	}
+	user.Prepare()
+	errorMessages := user.Validate("update")
+	if len(errorMessages) > 0 {
+		errList = errorMessages
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+	emailChanged := user.Email != origUser.Email
+	if emailChanged {
+		user.EmailVerifiedAt = nil
+	}
+
+	userUpdated, err := user.UpdateAUser(server.DB, uid)
+	if err != nil {
+		formattedError := formaterror.FormatError(err.Error())
+		errList = formattedError
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	if emailChanged {
+		err = server.sendVerificationEmail(userUpdated)
+		if err != nil {
+			fmt.Println("this is the error sending the verification email: ", err)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":   http.StatusOK,
+		"response": responses.NewUser(*userUpdated, server.viewer(c)),
+	})
+}
+
+// DeleteUser godoc
+// @Summary     Delete User
+// @Description Delete a user together with their photos, comments and social media. Use /users/me for the authenticated user.
+// @Tags        User
+// @Accept      json
+// @Produce     json
+// @Param       id path int true "User ID"
+// @Security ApiKeyAuth
+// @Success     200  {string} string "User deleted"
+// @Router      /users/{id} [delete]
+func (server *Server) DeleteUser(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	uid, err := resolveUserID(c)
+	if err != nil {
+		errList["Invalid_request"] = "Invalid Request"
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  errList,
+		})
+		return
+	}
+	if !server.canModify(c, uid, policy.ManageUsers) {
+		errList["Unauthorized"] = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  errList,
+		})
+		return
+	}
+	user := models.User{}
+	_, err = user.FindUserByID(server.DB, uid)
 	if err != nil {
 		errList["No_user"] = "No User Found"
 		c.JSON(http.StatusNotFound, gin.H{
@@ -99,8 +298,146 @@ func (server *Server) GetUser(c *gin.Context) {
 		})
 		return
 	}
+
+	err = server.PurgeUser(uid)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"status":   http.StatusOK,
-		"response": userGotten,
+		"response": "User deleted",
 	})
 }
+
+// PurgeUser deletes the user with everything they own, the purge-user command shares it with DeleteUser
+func (server *Server) PurgeUser(uid uint32) error {
+	// the files are removed once the rows are gone for good
+	photo := models.Photo{}
+	storageKeys, err := photo.FindUserStorageKeys(server.DB, uid)
+	if err != nil {
+		return err
+	}
+	user := models.User{}
+	if _, err = user.DeleteAUser(server.DB, uid); err != nil {
+		return err
+	}
+	server.removeStoredFiles(storageKeys...)
+	return nil
+}
+
+// ChangePassword godoc
+// @Summary     Change Password
+// @Description Change the password of the authenticated user. Other sessions are logged out.
+// @Tags        User
+// @Accept      json
+// @Produce     json
+// @Param       ChangePassword body models.ChangePassword true "Passwords"
+// @Security ApiKeyAuth
+// @Success     200  {string} string "Password changed"
+// @Router      /users/me/password [put]
+func (server *Server) ChangePassword(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	claims, err := server.Tokens.ExtractTokenClaims(c.Request)
+	if err != nil {
+		errList["Unauthorized"] = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  errList,
+		})
+		return
+	}
+
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		errList["Invalid_body"] = "Unable to get request"
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+	input := models.ChangePassword{}
+	err = json.Unmarshal(body, &input)
+	if err != nil {
+		errList["Unmarshal_error"] = "Cannot unmarshal body"
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+	errorMessages := input.Validate()
+	if len(errorMessages) > 0 {
+		errList = errorMessages
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+
+	user := models.User{}
+	_, err = user.FindUserByID(server.DB, claims.UserID)
+	if err != nil {
+		errList["Unauthorized"] = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  errList,
+		})
+		return
+	}
+	err = security.VerifyPassword(user.Password, input.CurrentPassword)
+	if err != nil {
+		errList["Incorrect_password"] = "Incorrect Password"
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+	err = user.UpdatePassword(server.DB, input.NewPassword)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+
+	refreshToken := models.RefreshToken{}
+	_, err = refreshToken.RevokeOtherUserTokens(server.DB, user.ID, claims.FamilyID)
+	if err != nil {
+		fmt.Println("this is the error revoking the user sessions: ", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":   http.StatusOK,
+		"response": "Password changed",
+	})
+}
+
+// resolveUserID reads the :id route parameter, routes under /users/me resolve to the authenticated user
+func resolveUserID(c *gin.Context) (uint32, error) {
+	userID := c.Param("id")
+	if userID == "" {
+		actor, ok := middlewares.CurrentActor(c)
+		if !ok {
+			return 0, auth.ErrInvalidToken
+		}
+		return actor.ID, nil
+	}
+	uid, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	return uint32(uid), nil
+}
@@ -0,0 +1,192 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/mailer"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/middlewares"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/models"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/security"
+	"github.com/gin-gonic/gin"
+)
+
+// VerifyEmail godoc
+// @Summary     Verify Email
+// @Description Confirm the email address of an account with the token from the verification email
+// @Tags        User
+// @Accept      json
+// @Produce     json
+// @Param       token query string true "Verification Token"
+// @Success     200  {string} string "Email verified"
+// @Router      /users/verify [get]
+func (server *Server) VerifyEmail(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	token := c.Query("token")
+	if token == "" {
+		errList["Invalid_token"] = "Invalid link. Try requesting again"
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+
+	emailVerification := models.EmailVerification{}
+	verification, err := emailVerification.FindEmailVerificationByToken(server.DB, security.Digest(token))
+	if err != nil || verification.UsedAt != nil || verification.IsExpired() {
+		errList["Invalid_token"] = "Invalid link. Try requesting again"
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+
+	user := models.User{}
+	_, err = user.FindUserByID(server.DB, verification.UserID)
+	if err != nil || user.Email != verification.Email {
+		// the address changed since the link was sent
+		errList["Invalid_token"] = "Invalid link. Try requesting again"
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+
+	consumed, err := verification.MarkUsed(server.DB)
+	if err != nil || !consumed {
+		errList["Invalid_token"] = "Invalid link. Try requesting again"
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+	err = user.MarkEmailVerified(server.DB)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":   http.StatusOK,
+		"response": "Email verified",
+	})
+}
+
+// ResendVerification godoc
+// @Summary     Resend Verification Email
+// @Description Send a new verification email to the authenticated user
+// @Tags        User
+// @Accept      json
+// @Produce     json
+// @Security ApiKeyAuth
+// @Success     200  {string} string "Verification email sent"
+// @Router      /users/verify/resend [post]
+func (server *Server) ResendVerification(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	uid, err := server.Tokens.ExtractTokenID(c.Request)
+	if err != nil {
+		errList["Unauthorized"] = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  errList,
+		})
+		return
+	}
+	user := models.User{}
+	_, err = user.FindUserByID(server.DB, uid)
+	if err != nil {
+		errList["Unauthorized"] = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  errList,
+		})
+		return
+	}
+	if user.IsEmailVerified() {
+		errList["Already_verified"] = "Email already verified"
+		c.JSON(http.StatusConflict, gin.H{
+			"status": http.StatusConflict,
+			"error":  errList,
+		})
+		return
+	}
+
+	err = server.sendVerificationEmail(&user)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":   http.StatusOK,
+		"response": "Verification email sent",
+	})
+}
+
+// sendVerificationEmail replaces any outstanding verification token of the user and mails a new one
+func (server *Server) sendVerificationEmail(user *models.User) error {
+
+	emailVerification := models.EmailVerification{}
+	_, err := emailVerification.InvalidateUserVerifications(server.DB, user.ID)
+	if err != nil {
+		fmt.Println("this is the error invalidating older verification tokens: ", err)
+	}
+
+	token := security.TokenHash(user.Email)
+	emailVerification = models.EmailVerification{
+		UserID:    user.ID,
+		Email:     user.Email,
+		TokenHash: security.Digest(token),
+		ExpiresAt: time.Now().Add(server.Config.Auth.EmailVerificationTTL),
+	}
+	_, err = emailVerification.SaveEmailVerification(server.DB)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/api/v1/users/verify?token=%s", server.Config.App.URL, url.QueryEscape(token))
+	msg, err := mailer.VerificationEmail(user.Username, user.Email, link, server.Config.Auth.EmailVerificationTTL)
+	if err != nil {
+		return err
+	}
+	server.sendMail(msg)
+	return nil
+}
+
+func (server *Server) emailVerified(uid uint32) (bool, error) {
+	user := models.User{}
+	_, err := user.FindUserByID(server.DB, uid)
+	if err != nil {
+		return false, err
+	}
+	return user.IsEmailVerified(), nil
+}
+
+// writeAuth guards routes that publish content, unverified accounts are let through
+// unless REQUIRE_EMAIL_VERIFICATION is enabled
+func (server *Server) writeAuth() gin.HandlerFunc {
+	if !server.Config.Auth.RequireVerifiedEmail {
+		return middlewares.TokenAuthMiddleware(server.Tokens)
+	}
+	return middlewares.TokenAuthMiddleware(server.Tokens, middlewares.RequireVerifiedEmail(server.emailVerified))
+}
@@ -0,0 +1,91 @@
+package feed
+
+import (
+	"fmt"
+
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/config"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/models"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/utils/pagination"
+	"github.com/jinzhu/gorm"
+)
+
+// Feed builds the home feed of a user: their own photos and those of the accounts they follow, newest first.
+// The hooks keep a precomputed feed up to date, a feed built at read time ignores them.
+type Feed interface {
+	Photos(uid uint32, page pagination.Page) (*[]models.Photo, pagination.Links, error)
+
+	PhotoCreated(photo models.Photo) error
+	Followed(follower, followee uint32) error
+	Unfollowed(follower, followee uint32) error
+}
+
+// New picks the Feed of the strategy: read (fan-out on read) or write (fan-out on write)
+func New(db *gorm.DB, cfg config.Feed) (Feed, error) {
+	switch cfg.Strategy {
+	case "read":
+		return NewReadFeed(db), nil
+	case "write":
+		return NewWriteFeed(db, cfg.BackfillLimit), nil
+	default:
+		return nil, fmt.Errorf("unknown feed strategy %q", cfg.Strategy)
+	}
+}
+
+// ReadFeed looks up the photos of the followed accounts on every read. Nothing is stored,
+// but the query gets slower as a user follows more accounts.
+type ReadFeed struct {
+	db *gorm.DB
+}
+
+func NewReadFeed(db *gorm.DB) *ReadFeed {
+	return &ReadFeed{db: db}
+}
+
+func (f *ReadFeed) Photos(uid uint32, page pagination.Page) (*[]models.Photo, pagination.Links, error) {
+	photo := models.Photo{}
+	return photo.FindFollowedPhotos(f.db, uid, page)
+}
+
+func (f *ReadFeed) PhotoCreated(photo models.Photo) error      { return nil }
+func (f *ReadFeed) Followed(follower, followee uint32) error   { return nil }
+func (f *ReadFeed) Unfollowed(follower, followee uint32) error { return nil }
+
+// WriteFeed keeps a timeline table per user, written when photos are posted and accounts followed.
+// Reads are a single indexed range scan whatever the number of follows, at the cost of one row per follower
+// for every photo. Switching to it needs the timelines rebuilt once, see Rebuild.
+type WriteFeed struct {
+	db *gorm.DB
+	// backfillLimit is how many photos of a newly followed account are copied into the timeline
+	backfillLimit int
+}
+
+func NewWriteFeed(db *gorm.DB, backfillLimit int) *WriteFeed {
+	return &WriteFeed{db: db, backfillLimit: backfillLimit}
+}
+
+func (f *WriteFeed) Photos(uid uint32, page pagination.Page) (*[]models.Photo, pagination.Links, error) {
+	entry := models.TimelineEntry{}
+	return entry.FindTimeline(f.db, uid, page)
+}
+
+func (f *WriteFeed) PhotoCreated(photo models.Photo) error {
+	entry := models.TimelineEntry{}
+	return entry.FanOutPhoto(f.db, photo)
+}
+
+func (f *WriteFeed) Followed(follower, followee uint32) error {
+	entry := models.TimelineEntry{}
+	return entry.FanInFollow(f.db, follower, followee, f.backfillLimit)
+}
+
+func (f *WriteFeed) Unfollowed(follower, followee uint32) error {
+	entry := models.TimelineEntry{}
+	_, err := entry.RemoveFollow(f.db, follower, followee)
+	return err
+}
+
+// Rebuild recomputes every timeline from the photos and the follows
+func (f *WriteFeed) Rebuild() (int64, error) {
+	entry := models.TimelineEntry{}
+	return entry.RebuildTimelines(f.db)
+}
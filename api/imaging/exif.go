@@ -0,0 +1,399 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Metadata holds the EXIF fields we are willing to publish. GPS coordinates,
+// serial numbers and the like are never read.
+type Metadata struct {
+	TakenAt     *time.Time
+	CameraModel string
+	// Orientation is the EXIF orientation, 1 (or 0 when unknown) means the pixels are stored upright
+	Orientation int
+}
+
+const (
+	tagMake             = 0x010f
+	tagModel            = 0x0110
+	tagOrientation      = 0x0112
+	tagDateTime         = 0x0132
+	tagExifIFD          = 0x8769
+	tagDateTimeOriginal = 0x9003
+	tagOffsetOriginal   = 0x9011
+)
+
+var errNoExif = errors.New("no exif data")
+
+// ReadMetadata extracts the safe EXIF fields of a JPEG, PNG or WebP image.
+// Images without (readable) EXIF data return an empty Metadata.
+func ReadMetadata(data []byte, mimeType string) Metadata {
+	var tiff []byte
+	switch mimeType {
+	case "image/jpeg":
+		tiff = jpegExif(data)
+	case "image/png":
+		tiff = pngExif(data)
+	case "image/webp":
+		tiff = webpExif(data)
+	}
+	if tiff == nil {
+		return Metadata{}
+	}
+	metadata, err := parseExif(tiff)
+	if err != nil {
+		return Metadata{}
+	}
+	return metadata
+}
+
+// jpegExif returns the TIFF structure inside the "Exif" APP1 segment
+func jpegExif(data []byte) []byte {
+	var found []byte
+	walkJPEG(data, func(marker byte, segment []byte) bool {
+		if marker == 0xe1 && len(segment) > 10 && bytes.HasPrefix(segment[4:], []byte("Exif\x00\x00")) {
+			found = segment[10:]
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+func pngExif(data []byte) []byte {
+	var found []byte
+	walkPNG(data, func(chunkType string, chunk []byte) bool {
+		if chunkType == "eXIf" {
+			found = chunk[8 : len(chunk)-4]
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+func webpExif(data []byte) []byte {
+	var found []byte
+	walkWebP(data, func(chunkType string, chunk []byte) bool {
+		if chunkType == "EXIF" {
+			payload := chunk[8:]
+			if size := binary.LittleEndian.Uint32(chunk[4:8]); int(size) <= len(payload) {
+				payload = payload[:size]
+			}
+			// some writers keep the JPEG style prefix
+			found = bytes.TrimPrefix(payload, []byte("Exif\x00\x00"))
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+// parseExif reads the fields we need from a TIFF structure
+func parseExif(tiff []byte) (Metadata, error) {
+	if len(tiff) < 8 {
+		return Metadata{}, errNoExif
+	}
+	var order binary.ByteOrder
+	switch string(tiff[0:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return Metadata{}, errNoExif
+	}
+	if order.Uint16(tiff[2:4]) != 42 {
+		return Metadata{}, errNoExif
+	}
+
+	ifd0 := readIFD(tiff, order, order.Uint32(tiff[4:8]))
+	metadata := Metadata{}
+	if orientation, ok := ifd0.short(tagOrientation); ok && orientation >= 1 && orientation <= 8 {
+		metadata.Orientation = int(orientation)
+	}
+	metadata.CameraModel = ifd0.ascii(tagModel)
+	// most models already start with the make ("Canon EOS 5D"), some do not ("iPhone 12")
+	if maker := ifd0.ascii(tagMake); maker != "" && metadata.CameraModel != "" &&
+		!strings.HasPrefix(strings.ToLower(metadata.CameraModel), strings.ToLower(maker)) {
+		metadata.CameraModel = maker + " " + metadata.CameraModel
+	}
+
+	taken, offset := ifd0.ascii(tagDateTime), ""
+	if pointer, ok := ifd0.long(tagExifIFD); ok {
+		exif := readIFD(tiff, order, pointer)
+		if original := exif.ascii(tagDateTimeOriginal); original != "" {
+			taken, offset = original, exif.ascii(tagOffsetOriginal)
+		}
+	}
+	if taken != "" {
+		metadata.TakenAt = parseExifTime(taken, offset)
+	}
+	return metadata, nil
+}
+
+// parseExifTime reads "2006:01:02 15:04:05". EXIF has no time zone unless the offset tag is present,
+// without it the time is kept as UTC.
+func parseExifTime(value, offset string) *time.Time {
+	location := time.UTC
+	if offset != "" {
+		if t, err := time.Parse("-07:00", offset); err == nil {
+			location = t.Location()
+		}
+	}
+	t, err := time.ParseInLocation("2006:01:02 15:04:05", value, location)
+	if err != nil || t.Year() < 1900 {
+		return nil
+	}
+	return &t
+}
+
+type ifdEntry struct {
+	typ   uint16
+	count uint32
+	value []byte
+}
+
+type ifd struct {
+	order   binary.ByteOrder
+	entries map[uint16]ifdEntry
+}
+
+// readIFD reads an image file directory, out of range entries are skipped
+func readIFD(tiff []byte, order binary.ByteOrder, offset uint32) ifd {
+	dir := ifd{order: order, entries: map[uint16]ifdEntry{}}
+	if int64(offset)+2 > int64(len(tiff)) {
+		return dir
+	}
+	count := int(order.Uint16(tiff[offset:]))
+	for i := 0; i < count; i++ {
+		start := int64(offset) + 2 + int64(i)*12
+		if start+12 > int64(len(tiff)) {
+			break
+		}
+		raw := tiff[start : start+12]
+		entry := ifdEntry{typ: order.Uint16(raw[2:4]), count: order.Uint32(raw[4:8])}
+		size := int64(entry.count) * int64(typeSize(entry.typ))
+		if size == 0 {
+			continue
+		}
+		if size <= 4 {
+			entry.value = raw[8 : 8+size]
+		} else {
+			valueOffset := int64(order.Uint32(raw[8:12]))
+			if valueOffset+size > int64(len(tiff)) {
+				continue
+			}
+			entry.value = tiff[valueOffset : valueOffset+size]
+		}
+		dir.entries[order.Uint16(raw[0:2])] = entry
+	}
+	return dir
+}
+
+func typeSize(typ uint16) int {
+	switch typ {
+	case 1, 2, 6, 7: // BYTE, ASCII, SBYTE, UNDEFINED
+		return 1
+	case 3, 8: // SHORT, SSHORT
+		return 2
+	case 4, 9, 11: // LONG, SLONG, FLOAT
+		return 4
+	case 5, 10, 12: // RATIONAL, SRATIONAL, DOUBLE
+		return 8
+	}
+	return 0
+}
+
+func (d ifd) ascii(tag uint16) string {
+	entry, ok := d.entries[tag]
+	if !ok || entry.typ != 2 {
+		return ""
+	}
+	value := string(entry.value)
+	if i := strings.IndexByte(value, 0); i >= 0 {
+		value = value[:i]
+	}
+	return strings.TrimSpace(strings.ToValidUTF8(value, ""))
+}
+
+func (d ifd) short(tag uint16) (uint16, bool) {
+	entry, ok := d.entries[tag]
+	if !ok || entry.typ != 3 || len(entry.value) < 2 {
+		return 0, false
+	}
+	return d.order.Uint16(entry.value), true
+}
+
+func (d ifd) long(tag uint16) (uint32, bool) {
+	entry, ok := d.entries[tag]
+	if !ok || entry.typ != 4 || len(entry.value) < 4 {
+		return 0, false
+	}
+	return d.order.Uint32(entry.value), true
+}
+
+// StripMetadata removes EXIF, XMP, IPTC and comments without touching the image data.
+// Color profiles are kept. GIFs are returned as they are.
+func StripMetadata(data []byte, mimeType string) ([]byte, error) {
+	switch mimeType {
+	case "image/jpeg":
+		return stripJPEG(data)
+	case "image/png":
+		return stripPNG(data)
+	case "image/webp":
+		return stripWebP(data)
+	}
+	return data, nil
+}
+
+var errInvalidImage = errors.New("invalid image data")
+
+// walkJPEG calls fn with every marker segment (marker, length and payload included) before the image data
+func walkJPEG(data []byte, fn func(marker byte, segment []byte) bool) (int, error) {
+	if len(data) < 4 || data[0] != 0xff || data[1] != 0xd8 {
+		return 0, errInvalidImage
+	}
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xff {
+			return 0, errInvalidImage
+		}
+		marker := data[pos+1]
+		if marker == 0xff {
+			// fill byte
+			pos++
+			continue
+		}
+		if marker == 0xda {
+			// start of scan, the compressed image follows
+			return pos, nil
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if length < 2 || pos+2+length > len(data) {
+			return 0, errInvalidImage
+		}
+		if !fn(marker, data[pos:pos+2+length]) {
+			return pos, nil
+		}
+		pos += 2 + length
+	}
+	return 0, errInvalidImage
+}
+
+func stripJPEG(data []byte) ([]byte, error) {
+	out := make([]byte, 0, len(data))
+	out = append(out, 0xff, 0xd8)
+	scan, err := walkJPEG(data, func(marker byte, segment []byte) bool {
+		switch marker {
+		case 0xe1, 0xed, 0xfe:
+			// APP1 (EXIF, XMP), APP13 (IPTC) and comments
+		default:
+			out = append(out, segment...)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(out, data[scan:]...), nil
+}
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// walkPNG calls fn with every chunk, length, type and CRC included
+func walkPNG(data []byte, fn func(chunkType string, chunk []byte) bool) error {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return errInvalidImage
+	}
+	pos := len(pngSignature)
+	for pos < len(data) {
+		if pos+12 > len(data) {
+			return errInvalidImage
+		}
+		length := int64(binary.BigEndian.Uint32(data[pos:]))
+		end := int64(pos) + 12 + length
+		if end > int64(len(data)) {
+			return errInvalidImage
+		}
+		chunkType := string(data[pos+4 : pos+8])
+		if !fn(chunkType, data[pos:end]) || chunkType == "IEND" {
+			return nil
+		}
+		pos = int(end)
+	}
+	return nil
+}
+
+func stripPNG(data []byte) ([]byte, error) {
+	out := make([]byte, 0, len(data))
+	out = append(out, pngSignature...)
+	err := walkPNG(data, func(chunkType string, chunk []byte) bool {
+		switch chunkType {
+		case "eXIf", "tEXt", "zTXt", "iTXt", "tIME":
+		default:
+			out = append(out, chunk...)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// walkWebP calls fn with every RIFF chunk, header and padding included
+func walkWebP(data []byte, fn func(chunkType string, chunk []byte) bool) error {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return errInvalidImage
+	}
+	pos := 12
+	for pos < len(data) {
+		if pos+8 > len(data) {
+			return errInvalidImage
+		}
+		size := int64(binary.LittleEndian.Uint32(data[pos+4:]))
+		end := int64(pos) + 8 + size + size%2
+		if end > int64(len(data)) {
+			// an unpadded last chunk is common enough to accept
+			if int64(pos)+8+size != int64(len(data)) {
+				return errInvalidImage
+			}
+			end = int64(len(data))
+		}
+		if !fn(string(data[pos:pos+4]), data[pos:end]) {
+			return nil
+		}
+		pos = int(end)
+	}
+	return nil
+}
+
+func stripWebP(data []byte) ([]byte, error) {
+	out := make([]byte, 12, len(data))
+	copy(out, data[:12])
+	err := walkWebP(data, func(chunkType string, chunk []byte) bool {
+		switch chunkType {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			// clear the EXIF and XMP flags of the extended header
+			extended := append([]byte(nil), chunk...)
+			if len(extended) > 8 {
+				extended[8] &^= 0x08 | 0x04
+			}
+			out = append(out, extended...)
+		default:
+			out = append(out, chunk...)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	binary.LittleEndian.PutUint32(out[4:8], uint32(len(out)-8))
+	return out, nil
+}
@@ -0,0 +1,93 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	_ "image/gif"  // gif decoder
+	_ "image/jpeg" // jpeg decoder
+	_ "image/png"  // png decoder
+	"net/http"
+)
+
+// ErrUnsupportedType is returned for uploads that are not one of the accepted image formats
+var ErrUnsupportedType = errors.New("unsupported image type")
+
+// ErrTooManyPixels is returned for images larger than the pixel limit, decoding them could take gigabytes
+var ErrTooManyPixels = errors.New("image has too many pixels")
+
+// Extensions maps the accepted MIME types to the file extension used for storage keys
+var Extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// Info describes an uploaded image
+type Info struct {
+	MimeType string
+	Width    int
+	Height   int
+}
+
+// Inspect sniffs the real content type of data (the client supplied one is not trusted)
+// and reads the image dimensions without decoding the pixels. A small file can declare a huge image,
+// ErrTooManyPixels is returned when width times height is over maxPixels so it is never decoded.
+// A maxPixels of 0 does not limit.
+func Inspect(data []byte, maxPixels int64) (Info, error) {
+	mimeType := http.DetectContentType(data)
+	if _, ok := Extensions[mimeType]; !ok {
+		return Info{}, ErrUnsupportedType
+	}
+	info := Info{MimeType: mimeType}
+	if mimeType == "image/webp" {
+		width, height, err := webpSize(data)
+		if err != nil {
+			return Info{}, err
+		}
+		info.Width, info.Height = width, height
+	} else {
+		config, _, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return Info{}, err
+		}
+		info.Width, info.Height = config.Width, config.Height
+	}
+	if maxPixels > 0 && int64(info.Width)*int64(info.Height) > maxPixels {
+		return Info{}, ErrTooManyPixels
+	}
+	return info, nil
+}
+
+// webpSize reads the canvas size from the RIFF header, the standard library has no webp decoder
+func webpSize(data []byte) (int, int, error) {
+	invalid := errors.New("invalid webp header")
+	if len(data) < 30 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return 0, 0, invalid
+	}
+	chunk := data[12:]
+	switch string(chunk[0:4]) {
+	case "VP8X":
+		// 24 bit canvas width and height minus one
+		width := int(chunk[12]) | int(chunk[13])<<8 | int(chunk[14])<<16
+		height := int(chunk[15]) | int(chunk[16])<<8 | int(chunk[17])<<16
+		return width + 1, height + 1, nil
+	case "VP8L":
+		if chunk[8] != 0x2f {
+			return 0, 0, invalid
+		}
+		bits := binary.LittleEndian.Uint32(chunk[9:13])
+		return int(bits&0x3fff) + 1, int((bits>>14)&0x3fff) + 1, nil
+	case "VP8 ":
+		// frame tag (3 bytes) and start code (3 bytes) come before the 14 bit dimensions
+		if chunk[11] != 0x9d || chunk[12] != 0x01 || chunk[13] != 0x2a {
+			return 0, 0, invalid
+		}
+		width := int(binary.LittleEndian.Uint16(chunk[14:16]) & 0x3fff)
+		height := int(binary.LittleEndian.Uint16(chunk[16:18]) & 0x3fff)
+		return width, height, nil
+	}
+	return 0, 0, invalid
+}
@@ -0,0 +1,48 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"testing"
+)
+
+// pngDeclaring encodes a 1x1 PNG and rewrites its header to declare width x height pixels
+func pngDeclaring(t *testing.T, width, height uint32) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	// the signature (8 bytes) is followed by the IHDR length (4), type (4) and data (13), then its CRC
+	ihdr := data[12 : 12+4+13]
+	binary.BigEndian.PutUint32(ihdr[4:8], width)
+	binary.BigEndian.PutUint32(ihdr[8:12], height)
+	binary.BigEndian.PutUint32(data[12+4+13:], crc32.ChecksumIEEE(ihdr))
+	return data
+}
+
+func TestInspectPixelLimit(t *testing.T) {
+	bomb := pngDeclaring(t, 50000, 50000)
+	if _, err := Inspect(bomb, 40000000); !errors.Is(err, ErrTooManyPixels) {
+		t.Fatalf("50000x50000 PNG: got %v, want ErrTooManyPixels", err)
+	}
+	if _, _, err := Decode(bomb, 40000000); !errors.Is(err, ErrTooManyPixels) {
+		t.Fatalf("Decode of a 50000x50000 PNG: got %v, want ErrTooManyPixels", err)
+	}
+
+	info, err := Inspect(pngDeclaring(t, 8000, 5000), 40000000)
+	if err != nil {
+		t.Fatalf("8000x5000 PNG at the limit: %v", err)
+	}
+	if info.MimeType != "image/png" || info.Width != 8000 || info.Height != 5000 {
+		t.Errorf("got %+v, want an 8000x5000 image/png", info)
+	}
+	if _, err := Inspect(bomb, 0); err != nil {
+		t.Errorf("without a limit: %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"image/png"
+)
+
+// Orient returns img turned upright according to its EXIF orientation
+func Orient(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+	src := toRGBA(img)
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	dstW, dstH := w, h
+	if orientation >= 5 {
+		// 5 to 8 swap width and height
+		dstW, dstH = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		for x := 0; x < dstW; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // mirrored horizontally
+				sx, sy = w-1-x, y
+			case 3: // rotated 180
+				sx, sy = w-1-x, h-1-y
+			case 4: // mirrored vertically
+				sx, sy = x, h-1-y
+			case 5: // transposed
+				sx, sy = y, x
+			case 6: // needs a 90 degree clockwise turn
+				sx, sy = y, h-1-x
+			case 7: // transversed
+				sx, sy = w-1-y, h-1-x
+			case 8: // needs a 90 degree counter clockwise turn
+				sx, sy = w-1-y, x
+			}
+			copy(dst.Pix[dst.PixOffset(x, y):dst.PixOffset(x, y)+4], src.Pix[src.PixOffset(sx, sy):src.PixOffset(sx, sy)+4])
+		}
+	}
+	return dst
+}
+
+// IngestOptions controls what happens to an upload before it is stored
+type IngestOptions struct {
+	// StripMetadata removes EXIF, XMP and IPTC data, which can hold GPS coordinates and device serials
+	StripMetadata bool
+	// ExtractMetadata keeps the safe fields (taken at, camera model, orientation) for the photo record
+	ExtractMetadata bool
+	// AutoOrient rotates photos taken sideways so clients do not have to read the orientation themselves.
+	// Without it, stripping also drops the orientation tag and only Metadata.Orientation keeps it.
+	AutoOrient bool
+}
+
+// Ingest prepares an uploaded image for storage and returns the bytes to store, their description
+// and the extracted metadata (empty unless ExtractMetadata is set). info comes from Inspect, which has
+// checked the pixel limit already.
+func Ingest(data []byte, info Info, options IngestOptions) ([]byte, Info, Metadata, error) {
+	metadata := ReadMetadata(data, info.MimeType)
+
+	rotatable := info.MimeType == "image/jpeg" || info.MimeType == "image/png"
+	if options.AutoOrient && rotatable && metadata.Orientation > 1 {
+		img, _, err := Decode(data, 0)
+		if err != nil {
+			return nil, Info{}, Metadata{}, err
+		}
+		img = Orient(img, metadata.Orientation)
+
+		// re-encoding drops all metadata as well
+		var buf bytes.Buffer
+		if info.MimeType == "image/jpeg" {
+			err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 92})
+		} else {
+			err = png.Encode(&buf, img)
+		}
+		if err != nil {
+			return nil, Info{}, Metadata{}, err
+		}
+		bounds := img.Bounds()
+		data = buf.Bytes()
+		info = Info{MimeType: info.MimeType, Width: bounds.Dx(), Height: bounds.Dy()}
+		metadata.Orientation = 1
+	} else if options.StripMetadata {
+		stripped, err := StripMetadata(data, info.MimeType)
+		if err != nil {
+			return nil, Info{}, Metadata{}, err
+		}
+		data = stripped
+	}
+
+	if !options.ExtractMetadata {
+		metadata = Metadata{}
+	}
+	return data, info, metadata, nil
+}
@@ -0,0 +1,144 @@
+package imaging
+
+import (
+	"image"
+	"image/draw"
+	"math"
+)
+
+// Fit scales img down so it fits in width x height, keeping the aspect ratio.
+// Smaller images are never enlarged.
+func Fit(img image.Image, width, height int) *image.NRGBA {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w > width || h > height {
+		scale := math.Min(float64(width)/float64(w), float64(height)/float64(h))
+		w = maxInt(1, int(math.Round(float64(w)*scale)))
+		h = maxInt(1, int(math.Round(float64(h)*scale)))
+	}
+	return resample(toRGBA(img), w, h)
+}
+
+// Fill scales img to cover width x height and crops the overflow from the center,
+// the result is smaller when img itself is smaller than the crop.
+func Fill(img image.Image, width, height int) *image.NRGBA {
+	src := toRGBA(img)
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+
+	// the largest centered rectangle with the requested aspect ratio
+	cropW, cropH := w, int(math.Round(float64(w)*float64(height)/float64(width)))
+	if cropH > h {
+		cropW, cropH = int(math.Round(float64(h)*float64(width)/float64(height))), h
+	}
+	cropW, cropH = maxInt(1, cropW), maxInt(1, cropH)
+	x0 := bounds.Min.X + (w-cropW)/2
+	y0 := bounds.Min.Y + (h-cropH)/2
+	cropped := src.SubImage(image.Rect(x0, y0, x0+cropW, y0+cropH)).(*image.RGBA)
+
+	if cropW < width {
+		width, height = cropW, cropH
+	}
+	return resample(cropped, width, height)
+}
+
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok {
+		return rgba
+	}
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, bounds.Min, draw.Src)
+	return rgba
+}
+
+// resample resizes with an area average (box) filter, which is what downscaling photos needs.
+// It works on premultiplied colors so transparent pixels do not bleed into their neighbours.
+func resample(src *image.RGBA, width, height int) *image.NRGBA {
+	bounds := src.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+
+	// horizontal pass: srcH rows of width pixels
+	columns := boxWeights(srcW, width)
+	tmp := make([]float32, width*srcH*4)
+	for y := 0; y < srcH; y++ {
+		row := src.Pix[src.PixOffset(bounds.Min.X, bounds.Min.Y+y):]
+		for x, weights := range columns {
+			var r, g, b, a float32
+			for _, w := range weights {
+				p := row[w.index*4 : w.index*4+4]
+				r += float32(p[0]) * w.weight
+				g += float32(p[1]) * w.weight
+				b += float32(p[2]) * w.weight
+				a += float32(p[3]) * w.weight
+			}
+			t := tmp[(y*width+x)*4:]
+			t[0], t[1], t[2], t[3] = r, g, b, a
+		}
+	}
+
+	// vertical pass, straight into non premultiplied output
+	rows := boxWeights(srcH, height)
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y, weights := range rows {
+		for x := 0; x < width; x++ {
+			var r, g, b, a float32
+			for _, w := range weights {
+				t := tmp[(w.index*width+x)*4:]
+				r += t[0] * w.weight
+				g += t[1] * w.weight
+				b += t[2] * w.weight
+				a += t[3] * w.weight
+			}
+			d := dst.Pix[dst.PixOffset(x, y):]
+			if a <= 0 {
+				d[0], d[1], d[2], d[3] = 0, 0, 0, 0
+				continue
+			}
+			d[0] = clampByte(r * 255 / a)
+			d[1] = clampByte(g * 255 / a)
+			d[2] = clampByte(b * 255 / a)
+			d[3] = clampByte(a)
+		}
+	}
+	return dst
+}
+
+type boxWeight struct {
+	index  int
+	weight float32
+}
+
+// boxWeights lists, for every destination pixel, the source pixels it covers and by how much
+func boxWeights(srcSize, dstSize int) [][]boxWeight {
+	scale := float64(srcSize) / float64(dstSize)
+	weights := make([][]boxWeight, dstSize)
+	for i := range weights {
+		start, end := float64(i)*scale, float64(i+1)*scale
+		for j := int(start); j < srcSize && float64(j) < end; j++ {
+			coverage := math.Min(end, float64(j+1)) - math.Max(start, float64(j))
+			if coverage <= 0 {
+				continue
+			}
+			weights[i] = append(weights[i], boxWeight{index: j, weight: float32(coverage / scale)})
+		}
+	}
+	return weights
+}
+
+func clampByte(v float32) uint8 {
+	if v <= 0 {
+		return 0
+	}
+	if v >= 255 {
+		return 255
+	}
+	return uint8(v + 0.5)
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,85 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"image/png"
+
+	"golang.org/x/image/webp"
+)
+
+// Variant is one of the resized copies generated for every uploaded photo
+type Variant struct {
+	Name   string
+	Width  int
+	Height int
+	// Crop fills the whole box and cuts off the overflow, otherwise the photo is scaled to fit inside it
+	Crop bool
+	// MimeType of the output, empty keeps JPEG photos as JPEG and turns everything else into PNG
+	MimeType string
+}
+
+// Variants are generated in this order for every uploaded photo
+var Variants = []Variant{
+	{Name: "thumbnail", Width: 150, Height: 150, Crop: true},
+	{Name: "medium", Width: 640, Height: 640},
+	{Name: "large", Width: 1280, Height: 1280},
+	{Name: "webp", Width: 640, Height: 640, MimeType: "image/webp"},
+}
+
+// Decode decodes a stored photo, unless it is over maxPixels, see Inspect
+func Decode(data []byte, maxPixels int64) (image.Image, string, error) {
+	info, err := Inspect(data, maxPixels)
+	if err != nil {
+		return nil, "", err
+	}
+	if info.MimeType == "image/webp" {
+		img, err := webp.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, "", err
+		}
+		return fromWebP(img), info.MimeType, nil
+	}
+	// GIFs decode to their first frame
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+	return img, info.MimeType, nil
+}
+
+// Render resizes img for the variant and encodes it
+func Render(img image.Image, sourceMimeType string, variant Variant) ([]byte, Info, error) {
+	var resized *image.NRGBA
+	if variant.Crop {
+		resized = Fill(img, variant.Width, variant.Height)
+	} else {
+		resized = Fit(img, variant.Width, variant.Height)
+	}
+
+	mimeType := variant.MimeType
+	if mimeType == "" {
+		mimeType = "image/png"
+		if sourceMimeType == "image/jpeg" {
+			mimeType = "image/jpeg"
+		}
+	}
+
+	var buf bytes.Buffer
+	var err error
+	switch mimeType {
+	case "image/jpeg":
+		err = jpeg.Encode(&buf, resized, &jpeg.Options{Quality: 85})
+	case "image/webp":
+		err = EncodeWebP(&buf, resized, 80)
+	default:
+		encoder := png.Encoder{CompressionLevel: png.BestCompression}
+		err = encoder.Encode(&buf, resized)
+	}
+	if err != nil {
+		return nil, Info{}, err
+	}
+	bounds := resized.Bounds()
+	return buf.Bytes(), Info{MimeType: mimeType, Width: bounds.Dx(), Height: bounds.Dy()}, nil
+}
@@ -0,0 +1,125 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// halves is an 800x600 photo, red on the left half and blue on the right one
+func halves() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 800, 600))
+	for y := 0; y < 600; y++ {
+		for x := 0; x < 800; x++ {
+			if x < 400 {
+				img.Set(x, y, color.RGBA{R: 255, A: 255})
+			} else {
+				img.Set(x, y, color.RGBA{B: 255, A: 255})
+			}
+		}
+	}
+	return img
+}
+
+// near reports whether c is within a lossy encoding of want
+func near(c color.Color, want color.RGBA) bool {
+	r, g, b, _ := c.RGBA()
+	diff := func(got uint32, want uint8) bool {
+		d := int(got>>8) - int(want)
+		return d > -24 && d < 24
+	}
+	return diff(r, want.R) && diff(g, want.G) && diff(b, want.B)
+}
+
+// TestRenderRoundTrip decodes every variant back, in every format it is encoded to
+func TestRenderRoundTrip(t *testing.T) {
+	encoders := map[string]func(*bytes.Buffer, image.Image) error{
+		"image/jpeg": func(w *bytes.Buffer, img image.Image) error { return jpeg.Encode(w, img, &jpeg.Options{Quality: 95}) },
+		"image/png":  func(w *bytes.Buffer, img image.Image) error { return png.Encode(w, img) },
+		"image/gif":  func(w *bytes.Buffer, img image.Image) error { return gif.Encode(w, img, nil) },
+		"image/webp": func(w *bytes.Buffer, img image.Image) error { return EncodeWebP(w, img, 90) },
+	}
+	sizes := map[string][2]int{"thumbnail": {150, 150}, "medium": {640, 480}, "large": {800, 600}, "webp": {640, 480}}
+	for source, encode := range encoders {
+		var buf bytes.Buffer
+		if err := encode(&buf, halves()); err != nil {
+			t.Fatal(err)
+		}
+		img, mimeType, err := Decode(buf.Bytes(), 1000000)
+		if err != nil || mimeType != source {
+			t.Fatalf("%s source: got %s, %v", source, mimeType, err)
+		}
+		for _, spec := range Variants {
+			encoded, info, err := Render(img, mimeType, spec)
+			if err != nil {
+				t.Fatalf("%s %s: %v", source, spec.Name, err)
+			}
+			decoded, decodedType, err := Decode(encoded, 1000000)
+			if err != nil {
+				t.Fatalf("%s %s does not decode: %v", source, spec.Name, err)
+			}
+			bounds := decoded.Bounds()
+			want := sizes[spec.Name]
+			if decodedType != info.MimeType || bounds.Dx() != info.Width || bounds.Dy() != info.Height {
+				t.Errorf("%s %s: decoded a %dx%d %s, Render reported %+v", source, spec.Name, bounds.Dx(), bounds.Dy(), decodedType, info)
+			}
+			if info.Width != want[0] || info.Height != want[1] {
+				t.Errorf("%s %s: got %dx%d, want %dx%d", source, spec.Name, info.Width, info.Height, want[0], want[1])
+			}
+			wantType := spec.MimeType
+			if wantType == "" && source == "image/jpeg" {
+				wantType = "image/jpeg"
+			} else if wantType == "" {
+				wantType = "image/png"
+			}
+			if info.MimeType != wantType {
+				t.Errorf("%s %s: encoded as %s, want %s", source, spec.Name, info.MimeType, wantType)
+			}
+			middle := bounds.Min.Y + bounds.Dy()/2
+			if left := decoded.At(bounds.Min.X+5, middle); !near(left, color.RGBA{R: 255}) {
+				t.Errorf("%s %s: the left edge is %v, want red", source, spec.Name, left)
+			}
+			if right := decoded.At(bounds.Max.X-5, middle); !near(right, color.RGBA{B: 255}) {
+				t.Errorf("%s %s: the right edge is %v, want blue", source, spec.Name, right)
+			}
+		}
+	}
+}
+
+// TestEncodeWebPAlpha keeps the transparency of an image exactly and its colors closely
+func TestEncodeWebPAlpha(t *testing.T) {
+	// odd sizes leave partial macroblocks on the right and the bottom
+	img := image.NewNRGBA(image.Rect(0, 0, 101, 67))
+	for y := 0; y < 67; y++ {
+		for x := 0; x < 101; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(x * 2), G: uint8(y * 3), B: 200, A: uint8(x * y)})
+		}
+	}
+	var buf bytes.Buffer
+	if err := EncodeWebP(&buf, img, 90); err != nil {
+		t.Fatal(err)
+	}
+	decoded, mimeType, err := Decode(buf.Bytes(), 0)
+	if err != nil || mimeType != "image/webp" {
+		t.Fatalf("got %s, %v", mimeType, err)
+	}
+	if decoded.Bounds() != img.Bounds() {
+		t.Fatalf("decoded %v, want %v", decoded.Bounds(), img.Bounds())
+	}
+	for y := 0; y < 67; y++ {
+		for x := 0; x < 101; x++ {
+			want := img.NRGBAAt(x, y)
+			got := color.NRGBAModel.Convert(decoded.At(x, y)).(color.NRGBA)
+			if got.A != want.A {
+				t.Fatalf("alpha at %d,%d is %d, want %d", x, y, got.A, want.A)
+			}
+			if !near(color.RGBA{R: got.R, G: got.G, B: got.B}, color.RGBA{R: want.R, G: want.G, B: want.B}) {
+				t.Fatalf("color at %d,%d is %v, want %v", x, y, got, want)
+			}
+		}
+	}
+}
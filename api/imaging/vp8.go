@@ -0,0 +1,785 @@
+package imaging
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// This file is a lossy VP8 key frame encoder, the bitstream inside lossy WebP images.
+// See https://datatracker.ietf.org/doc/html/rfc6386
+//
+// It keeps to the simple half of the format: every macroblock is predicted as one 16x16 luma block
+// and two 8x8 chroma blocks, there are no segments and a single token partition. The token
+// probabilities are adapted to the image, which is where most of the size goes.
+
+const (
+	vp8ModeDC = iota
+	vp8ModeV
+	vp8ModeH
+	vp8ModeTM
+)
+
+// the planes of the token probabilities
+const (
+	vp8PlaneY1AfterY2 = iota
+	vp8PlaneY2
+	vp8PlaneUV
+)
+
+var (
+	// vp8Zigzag is the order coefficients are coded in
+	vp8Zigzag = [16]int{0, 1, 4, 8, 5, 2, 3, 6, 9, 12, 13, 10, 7, 11, 14, 15}
+	// vp8Bands maps a position in the zigzag order to its probability band, the 17th entry is never coded
+	vp8Bands = [17]int{0, 1, 2, 3, 6, 4, 5, 6, 6, 6, 6, 6, 6, 6, 6, 7, 0}
+	// vp8CategoryProbs are the probabilities of the extra bits of the large token categories 3 to 6
+	vp8CategoryProbs = [4][]uint8{
+		{173, 148, 140},
+		{176, 155, 140, 135},
+		{180, 157, 141, 134, 130},
+		{254, 254, 243, 230, 196, 177, 153, 140, 133, 130, 129},
+	}
+)
+
+// vp8Quant holds the DC and AC quantizer steps of the three kinds of blocks, as the decoder derives them
+type vp8Quant struct {
+	y1, y2, uv [2]int32
+}
+
+func newVP8Quant(index int) vp8Quant {
+	q := vp8Quant{
+		y1: [2]int32{int32(vp8DCQuant[index]), int32(vp8ACQuant[index])},
+		y2: [2]int32{int32(vp8DCQuant[index]) * 2, int32(vp8ACQuant[index]) * 155 / 100},
+		uv: [2]int32{int32(vp8DCQuant[minInt(index, 117)]), int32(vp8ACQuant[index])},
+	}
+	if q.y2[1] < 8 {
+		q.y2[1] = 8
+	}
+	return q
+}
+
+// vp8Macroblock is the coded form of a macroblock
+type vp8Macroblock struct {
+	yMode, uvMode int
+	// coeffs are the quantized coefficients in zigzag order: the Y2 block of the luma DCs,
+	// the 16 luma blocks, the 4 U blocks then the 4 V blocks
+	coeffs [25][16]int16
+	skip   bool
+}
+
+// vp8Encoder holds the planes of one frame, padded to whole macroblocks by repeating the last row and column
+type vp8Encoder struct {
+	width, height int
+	mbw, mbh      int
+	quantIndex    int
+	quant         vp8Quant
+	// y, u and v are the source, ry, ru and rv the reconstruction the decoder will predict from
+	y, u, v    []uint8
+	ry, ru, rv []uint8
+	mbs        []vp8Macroblock
+}
+
+// encodeVP8 encodes the planes made by toYUV420 as a key frame with the quantizer index (0 to 127)
+// and the loop filter level (0 to 63)
+func encodeVP8(width, height int, y, u, v []uint8, quantIndex, filterLevel int) ([]byte, error) {
+	if width < 1 || height < 1 || width > 1<<14-1 || height > 1<<14-1 {
+		return nil, errors.New("vp8: invalid image size")
+	}
+	e := &vp8Encoder{
+		width:      width,
+		height:     height,
+		mbw:        (width + 15) / 16,
+		mbh:        (height + 15) / 16,
+		quantIndex: quantIndex,
+		quant:      newVP8Quant(quantIndex),
+		y:          y,
+		u:          u,
+		v:          v,
+		ry:         make([]uint8, len(y)),
+		ru:         make([]uint8, len(u)),
+		rv:         make([]uint8, len(v)),
+	}
+	e.mbs = make([]vp8Macroblock, e.mbw*e.mbh)
+	for mby := 0; mby < e.mbh; mby++ {
+		for mbx := 0; mbx < e.mbw; mbx++ {
+			e.encodeMacroblock(mbx, mby, &e.mbs[mby*e.mbw+mbx])
+		}
+	}
+
+	probs := e.tokenProbs()
+	first := e.writeHeaders(&probs, filterLevel)
+	tokens := e.writeTokens(&probs)
+	if len(first) >= 1<<19 || len(tokens) >= 1<<24 {
+		return nil, errors.New("vp8: image too large")
+	}
+
+	frame := make([]byte, 10, 10+len(first)+len(tokens))
+	// a shown key frame of version 0, followed by the size of the first partition
+	tag := uint32(1<<4 | len(first)<<5)
+	frame[0], frame[1], frame[2] = byte(tag), byte(tag>>8), byte(tag>>16)
+	frame[3], frame[4], frame[5] = 0x9d, 0x01, 0x2a
+	binary.LittleEndian.PutUint16(frame[6:8], uint16(width))
+	binary.LittleEndian.PutUint16(frame[8:10], uint16(height))
+	frame = append(frame, first...)
+	return append(frame, tokens...), nil
+}
+
+// encodeMacroblock picks the prediction modes, quantizes the residuals and reconstructs the macroblock
+// exactly as the decoder will
+func (e *vp8Encoder) encodeMacroblock(mbx, mby int, mb *vp8Macroblock) {
+	stride := e.mbw * 16
+	var pred [256]uint8
+	mb.yMode = e.bestMode(e.y, e.ry, stride, mbx, mby, 16)
+	predictBlock(pred[:], e.ry, stride, mbx, mby, 16, mb.yMode)
+
+	// the DCs of the 16 luma blocks are coded together through the Walsh-Hadamard transform
+	var blocks [16][16]int32
+	var dcs [16]int32
+	for b := range blocks {
+		bx, by := b%4*4, b/4*4
+		blocks[b] = forwardDCT(e.y, stride, mbx*16+bx, mby*16+by, pred[:], 16, bx, by)
+		dcs[b] = blocks[b][0]
+	}
+	y2 := forwardWHT(dcs)
+	quantizeBlock(&y2, &mb.coeffs[0], e.quant.y2, 0)
+	dcs = inverseWHT(y2)
+	for b := range blocks {
+		bx, by := b%4*4, b/4*4
+		quantizeBlock(&blocks[b], &mb.coeffs[1+b], e.quant.y1, 1)
+		blocks[b][0] = dcs[b]
+		inverseDCT(&blocks[b], pred[:], 16, bx, by)
+	}
+	copyBlock(e.ry, stride, mbx*16, mby*16, pred[:], 16)
+
+	stride = e.mbw * 8
+	mb.uvMode = e.bestChromaMode(mbx, mby)
+	for plane, src := range [][]uint8{e.u, e.v} {
+		recon := [][]uint8{e.ru, e.rv}[plane]
+		predictBlock(pred[:64], recon, stride, mbx, mby, 8, mb.uvMode)
+		for b := 0; b < 4; b++ {
+			bx, by := b%2*4, b/2*4
+			block := forwardDCT(src, stride, mbx*8+bx, mby*8+by, pred[:64], 8, bx, by)
+			quantizeBlock(&block, &mb.coeffs[17+4*plane+b], e.quant.uv, 0)
+			inverseDCT(&block, pred[:64], 8, bx, by)
+		}
+		copyBlock(recon, stride, mbx*8, mby*8, pred[:64], 8)
+	}
+
+	mb.skip = true
+	for _, block := range mb.coeffs {
+		if block != [16]int16{} {
+			mb.skip = false
+			break
+		}
+	}
+}
+
+// bestMode picks the prediction of a size x size block closest to the source
+func (e *vp8Encoder) bestMode(src, recon []uint8, stride, mbx, mby, size int) int {
+	var pred [256]uint8
+	best, bestCost := vp8ModeDC, -1
+	for mode := vp8ModeDC; mode <= vp8ModeTM; mode++ {
+		predictBlock(pred[:size*size], recon, stride, mbx, mby, size, mode)
+		cost := blockSSE(src, stride, mbx*size, mby*size, pred[:size*size], size)
+		if bestCost < 0 || cost < bestCost {
+			best, bestCost = mode, cost
+		}
+	}
+	return best
+}
+
+// bestChromaMode picks the mode shared by both chroma blocks
+func (e *vp8Encoder) bestChromaMode(mbx, mby int) int {
+	var pred [64]uint8
+	stride := e.mbw * 8
+	best, bestCost := vp8ModeDC, -1
+	for mode := vp8ModeDC; mode <= vp8ModeTM; mode++ {
+		predictBlock(pred[:], e.ru, stride, mbx, mby, 8, mode)
+		cost := blockSSE(e.u, stride, mbx*8, mby*8, pred[:], 8)
+		predictBlock(pred[:], e.rv, stride, mbx, mby, 8, mode)
+		cost += blockSSE(e.v, stride, mbx*8, mby*8, pred[:], 8)
+		if bestCost < 0 || cost < bestCost {
+			best, bestCost = mode, cost
+		}
+	}
+	return best
+}
+
+// predictBlock fills pred with the prediction of the size x size block of macroblock mbx, mby. Outside
+// the frame the decoder assumes a row of 127 above and a column of 129 on the left.
+func predictBlock(pred, recon []uint8, stride, mbx, mby, size, mode int) {
+	x0, y0 := mbx*size, mby*size
+	top := func(i int) int32 {
+		if mby == 0 {
+			return 127
+		}
+		return int32(recon[(y0-1)*stride+x0+i])
+	}
+	left := func(j int) int32 {
+		if mbx == 0 {
+			return 129
+		}
+		return int32(recon[(y0+j)*stride+x0-1])
+	}
+
+	switch mode {
+	case vp8ModeDC:
+		// only the edges inside the frame are averaged
+		var sum, count int32
+		if mby > 0 {
+			for i := 0; i < size; i++ {
+				sum += top(i)
+			}
+			count += int32(size)
+		}
+		if mbx > 0 {
+			for j := 0; j < size; j++ {
+				sum += left(j)
+			}
+			count += int32(size)
+		}
+		dc := uint8(128)
+		if count > 0 {
+			dc = uint8((sum + count/2) / count)
+		}
+		for i := range pred[:size*size] {
+			pred[i] = dc
+		}
+	case vp8ModeV:
+		for j := 0; j < size; j++ {
+			for i := 0; i < size; i++ {
+				pred[j*size+i] = uint8(top(i))
+			}
+		}
+	case vp8ModeH:
+		for j := 0; j < size; j++ {
+			for i := 0; i < size; i++ {
+				pred[j*size+i] = uint8(left(j))
+			}
+		}
+	case vp8ModeTM:
+		corner := int32(127)
+		if mby > 0 {
+			corner = 129
+			if mbx > 0 {
+				corner = int32(recon[(y0-1)*stride+x0-1])
+			}
+		}
+		for j := 0; j < size; j++ {
+			for i := 0; i < size; i++ {
+				pred[j*size+i] = clampUint8(left(j) + top(i) - corner)
+			}
+		}
+	}
+}
+
+func blockSSE(src []uint8, stride, x0, y0 int, pred []uint8, size int) int {
+	sse := 0
+	for j := 0; j < size; j++ {
+		for i := 0; i < size; i++ {
+			d := int(src[(y0+j)*stride+x0+i]) - int(pred[j*size+i])
+			sse += d * d
+		}
+	}
+	return sse
+}
+
+func copyBlock(dst []uint8, stride, x0, y0 int, src []uint8, size int) {
+	for j := 0; j < size; j++ {
+		copy(dst[(y0+j)*stride+x0:(y0+j)*stride+x0+size], src[j*size:(j+1)*size])
+	}
+}
+
+// vp8IDCT is the matrix of the decoder's 4 point inverse DCT: cos(pi/8) and sin(pi/8) times the square root of 2
+// as 16 bit fixed point numbers
+var vp8IDCT = [4][4]float64{
+	{1, 85627.0 / 65536, 1, 35468.0 / 65536},
+	{1, 35468.0 / 65536, -1, -85627.0 / 65536},
+	{1, -35468.0 / 65536, -1, 85627.0 / 65536},
+	{1, -85627.0 / 65536, 1, -35468.0 / 65536},
+}
+
+// forwardDCT transforms the difference between the 4x4 source block at x0, y0 and the prediction at px, py.
+// The decoder computes the pixels as M C Mt / 8 and the columns of M are orthogonal with a norm of 2,
+// so the coefficients are Mt P M / 2.
+func forwardDCT(src []uint8, stride, x0, y0 int, pred []uint8, predStride, px, py int) [16]int32 {
+	var residual, tmp [4][4]float64
+	for j := 0; j < 4; j++ {
+		for i := 0; i < 4; i++ {
+			residual[j][i] = float64(int(src[(y0+j)*stride+x0+i]) - int(pred[(py+j)*predStride+px+i]))
+		}
+	}
+	for s := 0; s < 4; s++ {
+		for k := 0; k < 4; k++ {
+			for j := 0; j < 4; j++ {
+				tmp[s][k] += vp8IDCT[j][s] * residual[j][k]
+			}
+		}
+	}
+	var coeffs [16]int32
+	for s := 0; s < 4; s++ {
+		for i := 0; i < 4; i++ {
+			var c float64
+			for k := 0; k < 4; k++ {
+				c += tmp[s][k] * vp8IDCT[k][i]
+			}
+			coeffs[s*4+i] = int32(math.Round(c / 2))
+		}
+	}
+	return coeffs
+}
+
+// inverseDCT adds the inverse transform of coeffs to the 4x4 block of pred at px, py, with the integer
+// arithmetic of the decoder
+func inverseDCT(coeffs *[16]int32, pred []uint8, predStride, px, py int) {
+	const c1, c2 = 85627, 35468
+	var m [4][4]int32
+	for i := 0; i < 4; i++ {
+		a := coeffs[i] + coeffs[8+i]
+		b := coeffs[i] - coeffs[8+i]
+		c := (coeffs[4+i]*c2)>>16 - (coeffs[12+i]*c1)>>16
+		d := (coeffs[4+i]*c1)>>16 + (coeffs[12+i]*c2)>>16
+		m[i] = [4]int32{a + d, b + c, b - c, a - d}
+	}
+	for j := 0; j < 4; j++ {
+		dc := m[0][j] + 4
+		a := dc + m[2][j]
+		b := dc - m[2][j]
+		c := (m[1][j]*c2)>>16 - (m[3][j]*c1)>>16
+		d := (m[1][j]*c1)>>16 + (m[3][j]*c2)>>16
+		row := pred[(py+j)*predStride+px:]
+		for i, delta := range [4]int32{a + d, b + c, b - c, a - d} {
+			row[i] = clampUint8(int32(row[i]) + delta>>3)
+		}
+	}
+}
+
+// vp8WHT is the decoder's Walsh-Hadamard matrix, H Ht is 4 times the identity
+var vp8WHT = [4][4]int32{
+	{1, 1, 1, 1},
+	{1, 1, -1, -1},
+	{1, -1, -1, 1},
+	{1, -1, 1, -1},
+}
+
+// forwardWHT transforms the DCs of the 16 luma blocks. The decoder computes them as H Y2 Ht / 8,
+// so Y2 is Ht DC H / 2.
+func forwardWHT(dcs [16]int32) [16]int32 {
+	var tmp [4][4]int32
+	for r := 0; r < 4; r++ {
+		for k := 0; k < 4; k++ {
+			for i := 0; i < 4; i++ {
+				tmp[r][k] += vp8WHT[i][r] * dcs[i*4+k]
+			}
+		}
+	}
+	var coeffs [16]int32
+	for r := 0; r < 4; r++ {
+		for c := 0; c < 4; c++ {
+			var sum int32
+			for k := 0; k < 4; k++ {
+				sum += tmp[r][k] * vp8WHT[k][c]
+			}
+			if sum < 0 {
+				coeffs[r*4+c] = -((-sum + 1) / 2)
+			} else {
+				coeffs[r*4+c] = (sum + 1) / 2
+			}
+		}
+	}
+	return coeffs
+}
+
+// inverseWHT returns the DCs of the 16 luma blocks with the integer arithmetic of the decoder
+func inverseWHT(coeffs [16]int32) [16]int32 {
+	var m [16]int32
+	for i := 0; i < 4; i++ {
+		a0 := coeffs[i] + coeffs[12+i]
+		a1 := coeffs[4+i] + coeffs[8+i]
+		a2 := coeffs[4+i] - coeffs[8+i]
+		a3 := coeffs[i] - coeffs[12+i]
+		m[i] = a0 + a1
+		m[8+i] = a0 - a1
+		m[4+i] = a3 + a2
+		m[12+i] = a3 - a2
+	}
+	var dcs [16]int32
+	for i := 0; i < 4; i++ {
+		dc := m[i*4] + 3
+		a0 := dc + m[3+i*4]
+		a1 := m[1+i*4] + m[2+i*4]
+		a2 := m[1+i*4] - m[2+i*4]
+		a3 := dc - m[3+i*4]
+		dcs[i*4] = (a0 + a1) >> 3
+		dcs[i*4+1] = (a3 + a2) >> 3
+		dcs[i*4+2] = (a0 - a1) >> 3
+		dcs[i*4+3] = (a3 - a2) >> 3
+	}
+	return dcs
+}
+
+// quantizeBlock quantizes the coefficients from position first on into out, in zigzag order, and replaces
+// them with the values the decoder will see. The AC coefficients are rounded slightly towards zero,
+// small ones are not worth their bits.
+func quantizeBlock(coeffs *[16]int32, out *[16]int16, steps [2]int32, first int) {
+	for n := first; n < 16; n++ {
+		z := vp8Zigzag[n]
+		step := steps[1]
+		bias := step * 3 / 8
+		if z == 0 {
+			step = steps[0]
+			bias = step / 2
+		}
+		c := coeffs[z]
+		level := c
+		if level < 0 {
+			level = -level
+		}
+		level = (level + bias) / step
+		if level > 2048 {
+			level = 2048
+		}
+		if c < 0 {
+			level = -level
+		}
+		out[n] = int16(level)
+		coeffs[z] = level * step
+	}
+}
+
+// vp8TokenProbs is indexed by plane, band, context and the node of the token tree
+type vp8TokenProbs [4][8][3][11]uint8
+
+// tokenProbs adapts the default token probabilities to the frame wherever that saves more than it costs
+func (e *vp8Encoder) tokenProbs() vp8TokenProbs {
+	counts := &[4][8][3][11][2]int{}
+	e.codeTokens(&vp8TokenWriter{counts: counts})
+
+	probs := vp8TokenProbs(vp8DefaultTokenProbs)
+	for p := range probs {
+		for b := range probs[p] {
+			for c := range probs[p][b] {
+				for n := range probs[p][b][c] {
+					count := counts[p][b][c][n]
+					total := count[0] + count[1]
+					if total == 0 {
+						continue
+					}
+					prob := clampUint8(int32((count[0]*256 + total/2) / total))
+					if prob == 0 {
+						prob = 1
+					}
+					update := vp8TokenUpdateProbs[p][b][c][n]
+					saved := bitCost(probs[p][b][c][n], count) - bitCost(prob, count)
+					saved -= 8 + bitCost(update, [2]int{0, 1}) - bitCost(update, [2]int{1, 0})
+					if saved > 0 {
+						probs[p][b][c][n] = prob
+					}
+				}
+			}
+		}
+	}
+	return probs
+}
+
+// bitCost is the number of bits count[0] zeros and count[1] ones take with prob
+func bitCost(prob uint8, count [2]int) float64 {
+	p := float64(prob) / 256
+	return -float64(count[0])*math.Log2(p) - float64(count[1])*math.Log2(1-p)
+}
+
+// writeHeaders writes the first partition: the frame header then the prediction modes of every macroblock
+func (e *vp8Encoder) writeHeaders(probs *vp8TokenProbs, filterLevel int) []byte {
+	bw := newBoolWriter()
+	bw.putLiteral(0, 2)           // color space and clamping
+	bw.putLiteral(0, 1)           // no segments
+	bw.putLiteral(0, 1)           // normal loop filter
+	bw.putLiteral(filterLevel, 6) // filter level
+	bw.putLiteral(0, 3)           // sharpness
+	bw.putLiteral(0, 1)           // no filter deltas
+	bw.putLiteral(0, 2)           // one token partition
+	bw.putLiteral(e.quantIndex, 7)
+	bw.putLiteral(0, 5) // no quantizer deltas
+	bw.putLiteral(0, 1) // refresh_entropy_probs, meaningless for a single frame
+
+	for p := range probs {
+		for b := range probs[p] {
+			for c := range probs[p][b] {
+				for n, prob := range probs[p][b][c] {
+					updated := prob != vp8DefaultTokenProbs[p][b][c][n]
+					bw.putBit(vp8TokenUpdateProbs[p][b][c][n], updated)
+					if updated {
+						bw.putLiteral(int(prob), 8)
+					}
+				}
+			}
+		}
+	}
+
+	coded := 0
+	for _, mb := range e.mbs {
+		if !mb.skip {
+			coded++
+		}
+	}
+	skipProb := clampUint8(int32((coded*256 + len(e.mbs)/2) / len(e.mbs)))
+	if skipProb == 0 {
+		skipProb = 1
+	}
+	bw.putLiteral(1, 1)
+	bw.putLiteral(int(skipProb), 8)
+
+	for _, mb := range e.mbs {
+		bw.putBit(skipProb, mb.skip)
+		bw.putBit(145, true) // 16x16 luma prediction
+		switch mb.yMode {
+		case vp8ModeDC, vp8ModeV:
+			bw.putBit(156, false)
+			bw.putBit(163, mb.yMode == vp8ModeV)
+		default:
+			bw.putBit(156, true)
+			bw.putBit(128, mb.yMode == vp8ModeTM)
+		}
+		bw.putBit(142, mb.uvMode != vp8ModeDC)
+		if mb.uvMode != vp8ModeDC {
+			bw.putBit(114, mb.uvMode != vp8ModeV)
+			if mb.uvMode != vp8ModeV {
+				bw.putBit(183, mb.uvMode == vp8ModeTM)
+			}
+		}
+	}
+	return bw.flush()
+}
+
+// writeTokens writes the second partition, the coefficients of every macroblock
+func (e *vp8Encoder) writeTokens(probs *vp8TokenProbs) []byte {
+	bw := newBoolWriter()
+	e.codeTokens(&vp8TokenWriter{bw: bw, probs: probs})
+	return bw.flush()
+}
+
+// codeTokens codes the coefficients of the macroblocks, tracking which neighbouring blocks have
+// coefficients: that picks the probabilities of the first token of a block
+func (e *vp8Encoder) codeTokens(t *vp8TokenWriter) {
+	// per macroblock column the Y2 block then the bottom row of 4 luma, 2 U and 2 V blocks
+	above := make([][9]int, e.mbw)
+	for mby := 0; mby < e.mbh; mby++ {
+		// the same for the right column of the macroblock on the left
+		var left [9]int
+		for mbx := 0; mbx < e.mbw; mbx++ {
+			mb := &e.mbs[mby*e.mbw+mbx]
+			up := &above[mbx]
+			if mb.skip {
+				left, *up = [9]int{}, [9]int{}
+				continue
+			}
+
+			nz := t.block(&mb.coeffs[0], vp8PlaneY2, left[0]+up[0], 0)
+			left[0], up[0] = nz, nz
+			for y := 0; y < 4; y++ {
+				for x := 0; x < 4; x++ {
+					nz = t.block(&mb.coeffs[1+y*4+x], vp8PlaneY1AfterY2, left[1+y]+up[1+x], 1)
+					left[1+y], up[1+x] = nz, nz
+				}
+			}
+			for plane := 0; plane < 2; plane++ {
+				for y := 0; y < 2; y++ {
+					for x := 0; x < 2; x++ {
+						l, u := 5+plane*2+y, 5+plane*2+x
+						nz = t.block(&mb.coeffs[17+plane*4+y*2+x], vp8PlaneUV, left[l]+up[u], 0)
+						left[l], up[u] = nz, nz
+					}
+				}
+			}
+		}
+	}
+}
+
+// vp8TokenWriter writes tokens with probs, or only counts the branches taken in the token tree
+type vp8TokenWriter struct {
+	bw     *boolWriter
+	probs  *vp8TokenProbs
+	counts *[4][8][3][11][2]int
+}
+
+func (t *vp8TokenWriter) branch(plane, band, context, node int, bit bool) {
+	if t.counts != nil {
+		if bit {
+			t.counts[plane][band][context][node][1]++
+		} else {
+			t.counts[plane][band][context][node][0]++
+		}
+		return
+	}
+	t.bw.putBit(t.probs[plane][band][context][node], bit)
+}
+
+// extra writes the bits whose probability does not depend on the image
+func (t *vp8TokenWriter) extra(prob uint8, bit bool) {
+	if t.bw != nil {
+		t.bw.putBit(prob, bit)
+	}
+}
+
+// block codes the coefficients of a block from position first on and returns 1 when one is not zero
+func (t *vp8TokenWriter) block(coeffs *[16]int16, plane, context, first int) int {
+	last := -1
+	for n := first; n < 16; n++ {
+		if coeffs[n] != 0 {
+			last = n
+		}
+	}
+	if last < 0 {
+		t.branch(plane, vp8Bands[first], context, 0, false) // end of block
+		return 0
+	}
+	t.branch(plane, vp8Bands[first], context, 0, true)
+
+	for n := first; n < 16; n++ {
+		band := vp8Bands[n]
+		level := int(coeffs[n])
+		if level < 0 {
+			level = -level
+		}
+		if level == 0 {
+			// a zero is never followed by the end of the block
+			t.branch(plane, band, context, 1, false)
+			context = 0
+			continue
+		}
+		t.branch(plane, band, context, 1, true)
+		if level == 1 {
+			t.branch(plane, band, context, 2, false)
+		} else {
+			t.branch(plane, band, context, 2, true)
+			t.large(plane, band, context, level)
+		}
+		t.extra(128, coeffs[n] < 0)
+		context = minInt(level, 2)
+
+		if n == 15 {
+			break
+		}
+		t.branch(plane, vp8Bands[n+1], context, 0, n != last)
+		if n == last {
+			break
+		}
+	}
+	return 1
+}
+
+// large codes a level over 1: 2, 3 and 4 have their own tokens, larger ones a category and extra bits
+func (t *vp8TokenWriter) large(plane, band, context, level int) {
+	switch {
+	case level <= 4:
+		t.branch(plane, band, context, 3, false)
+		t.branch(plane, band, context, 4, level != 2)
+		if level != 2 {
+			t.branch(plane, band, context, 5, level == 4)
+		}
+	case level <= 10:
+		t.branch(plane, band, context, 3, true)
+		t.branch(plane, band, context, 6, false)
+		if level <= 6 {
+			t.branch(plane, band, context, 7, false)
+			t.extra(159, level == 6)
+		} else {
+			t.branch(plane, band, context, 7, true)
+			t.extra(165, (level-7)&2 != 0)
+			t.extra(145, (level-7)&1 != 0)
+		}
+	default:
+		t.branch(plane, band, context, 3, true)
+		t.branch(plane, band, context, 6, true)
+		category := 3
+		for category > 0 && level < 3+8<<category {
+			category--
+		}
+		t.branch(plane, band, context, 8, category >= 2)
+		t.branch(plane, band, context, 9+category/2, category%2 == 1)
+		probs := vp8CategoryProbs[category]
+		extra := level - (3 + 8<<category)
+		for i, prob := range probs {
+			t.extra(prob, extra>>(len(probs)-1-i)&1 != 0)
+		}
+	}
+}
+
+// boolWriter is the boolean entropy encoder of section 7
+type boolWriter struct {
+	buf      []byte
+	rng      uint32
+	bottom   uint32
+	bitCount int
+}
+
+func newBoolWriter() *boolWriter {
+	return &boolWriter{rng: 255, bitCount: 24}
+}
+
+func (bw *boolWriter) putBit(prob uint8, bit bool) {
+	split := 1 + (bw.rng-1)*uint32(prob)>>8
+	if bit {
+		bw.bottom += split
+		bw.rng -= split
+	} else {
+		bw.rng = split
+	}
+	for bw.rng < 128 {
+		bw.rng <<= 1
+		if bw.bottom&(1<<31) != 0 {
+			bw.carry()
+		}
+		bw.bottom <<= 1
+		bw.bitCount--
+		if bw.bitCount == 0 {
+			bw.buf = append(bw.buf, byte(bw.bottom>>24))
+			bw.bottom &= 1<<24 - 1
+			bw.bitCount = 8
+		}
+	}
+}
+
+// carry propagates an overflow of bottom into the bytes already written
+func (bw *boolWriter) carry() {
+	i := len(bw.buf) - 1
+	for i >= 0 && bw.buf[i] == 0xff {
+		bw.buf[i] = 0
+		i--
+	}
+	if i >= 0 {
+		bw.buf[i]++
+	}
+}
+
+// putLiteral writes the n bits of value, most significant first
+func (bw *boolWriter) putLiteral(value, n int) {
+	for i := n - 1; i >= 0; i-- {
+		bw.putBit(128, value>>i&1 != 0)
+	}
+}
+
+// flush pushes out the bits still held in bottom
+func (bw *boolWriter) flush() []byte {
+	for i := 0; i < 32; i++ {
+		bw.putBit(128, false)
+	}
+	return bw.buf
+}
+
+func clampUint8(v int32) uint8 {
+	if v < 0 {
+		return 0
+	}
+	if v > 255 {
+		return 255
+	}
+	return uint8(v)
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
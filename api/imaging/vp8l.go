@@ -0,0 +1,354 @@
+package imaging
+
+import "sort"
+
+// The alpha plane of a lossy WebP image is compressed with the lossless VP8L format, with the values in the
+// green channel. This is a small VP8L writer for it: the gradient filter of the alpha chunk followed by
+// prefix (Huffman) coding, without backward references.
+// See https://developers.google.com/speed/webp/docs/riff_container#alpha
+
+// encodeAlpha returns the payload of the ALPH chunk
+func encodeAlpha(alpha []uint8, width, height int) []byte {
+	filtered := make([]uint32, len(alpha))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			i := y*width + x
+			var prediction uint8
+			switch {
+			case x == 0 && y == 0:
+			case y == 0:
+				prediction = alpha[i-1]
+			case x == 0:
+				prediction = alpha[i-width]
+			default:
+				prediction = clampUint8(int32(alpha[i-1]) + int32(alpha[i-width]) - int32(alpha[i-width-1]))
+			}
+			filtered[i] = uint32(alpha[i]-prediction) << 8
+		}
+	}
+
+	bw := &bitWriter{}
+	bw.writeBits(0, 1) // no transform
+	writeImageData(bw, filtered, len(filtered), true)
+	// no preprocessing, the gradient filter and lossless compression
+	return append([]byte{3<<2 | 1}, bw.bytes()...)
+}
+
+const (
+	greenAlphabet    = 256 + 24 // literals and length prefixes, there is no color cache
+	colorAlphabet    = 256
+	distanceAlphabet = 40
+	maxCodeLength    = 15
+)
+
+// codeLengthOrder is the order code length code lengths are written in
+var codeLengthOrder = [19]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// writeImageData entropy codes pixels with one group of prefix codes and no backward references
+func writeImageData(bw *bitWriter, pixels []uint32, count int, main bool) {
+	bw.writeBits(0, 1) // no color cache
+	if main {
+		bw.writeBits(0, 1) // no meta prefix codes
+	}
+
+	histograms := [5][]int{
+		make([]int, greenAlphabet),
+		make([]int, colorAlphabet),
+		make([]int, colorAlphabet),
+		make([]int, colorAlphabet),
+		make([]int, distanceAlphabet),
+	}
+	for _, p := range pixels[:count] {
+		histograms[0][(p>>8)&0xff]++
+		histograms[1][(p>>16)&0xff]++
+		histograms[2][p&0xff]++
+		histograms[3][p>>24]++
+	}
+	var codes [5]prefixCode
+	for i, histogram := range histograms {
+		codes[i] = writePrefixCode(bw, histogram)
+	}
+
+	for _, p := range pixels[:count] {
+		codes[0].write(bw, int((p>>8)&0xff))
+		codes[1].write(bw, int((p>>16)&0xff))
+		codes[2].write(bw, int(p&0xff))
+		codes[3].write(bw, int(p>>24))
+	}
+}
+
+// prefixCode holds the bit reversed canonical codes, ready for the LSB first bit writer
+type prefixCode struct {
+	lengths []uint8
+	codes   []uint32
+	// a code with a single symbol takes no bits at all
+	single bool
+}
+
+func (pc prefixCode) write(bw *bitWriter, symbol int) {
+	if pc.single {
+		return
+	}
+	bw.writeBits(pc.codes[symbol], uint(pc.lengths[symbol]))
+}
+
+// writePrefixCode picks the code for a histogram and writes its description to the stream
+func writePrefixCode(bw *bitWriter, histogram []int) prefixCode {
+	var used []int
+	for symbol, count := range histogram {
+		if count > 0 {
+			used = append(used, symbol)
+		}
+	}
+	if len(used) == 0 {
+		used = []int{0}
+	}
+
+	// the simple code handles one or two symbols below 256
+	if len(used) <= 2 && used[len(used)-1] < 256 {
+		bw.writeBits(1, 1)
+		bw.writeBits(uint32(len(used)-1), 1)
+		if used[0] < 2 {
+			bw.writeBits(0, 1)
+			bw.writeBits(uint32(used[0]), 1)
+		} else {
+			bw.writeBits(1, 1)
+			bw.writeBits(uint32(used[0]), 8)
+		}
+		if len(used) == 2 {
+			bw.writeBits(uint32(used[1]), 8)
+		}
+		lengths := make([]uint8, len(histogram))
+		for _, symbol := range used {
+			lengths[symbol] = 1
+		}
+		return newPrefixCode(lengths)
+	}
+
+	lengths := codeLengths(histogram, maxCodeLength)
+	bw.writeBits(0, 1) // normal code
+
+	tokens := codeLengthTokens(lengths)
+	tokenHistogram := make([]int, len(codeLengthOrder))
+	for _, t := range tokens {
+		tokenHistogram[t.symbol]++
+	}
+	tokenLengths := codeLengths(tokenHistogram, 7)
+	tokenCode := newPrefixCode(tokenLengths)
+
+	count := 4
+	for i := len(codeLengthOrder) - 1; i >= 4; i-- {
+		if tokenLengths[codeLengthOrder[i]] != 0 {
+			count = i + 1
+			break
+		}
+	}
+	bw.writeBits(uint32(count-4), 4)
+	for _, symbol := range codeLengthOrder[:count] {
+		bw.writeBits(uint32(tokenLengths[symbol]), 3)
+	}
+
+	bw.writeBits(0, 1) // lengths are given for the whole alphabet
+	for _, t := range tokens {
+		tokenCode.write(bw, t.symbol)
+		switch t.symbol {
+		case 16:
+			bw.writeBits(uint32(t.extra), 2)
+		case 17:
+			bw.writeBits(uint32(t.extra), 3)
+		case 18:
+			bw.writeBits(uint32(t.extra), 7)
+		}
+	}
+	return newPrefixCode(lengths)
+}
+
+type codeLengthToken struct {
+	symbol int
+	extra  int
+}
+
+// codeLengthTokens run length encodes code lengths: 16 repeats the previous length, 17 and 18 are runs of zeros
+func codeLengthTokens(lengths []uint8) []codeLengthToken {
+	var tokens []codeLengthToken
+	for i := 0; i < len(lengths); {
+		length := lengths[i]
+		run := 1
+		for i+run < len(lengths) && lengths[i+run] == length {
+			run++
+		}
+		i += run
+
+		if length == 0 {
+			for run > 0 {
+				switch {
+				case run >= 11:
+					n := minInt(run, 138)
+					tokens = append(tokens, codeLengthToken{symbol: 18, extra: n - 11})
+					run -= n
+				case run >= 3:
+					tokens = append(tokens, codeLengthToken{symbol: 17, extra: run - 3})
+					run = 0
+				default:
+					tokens = append(tokens, codeLengthToken{symbol: 0})
+					run--
+				}
+			}
+			continue
+		}
+
+		tokens = append(tokens, codeLengthToken{symbol: int(length)})
+		run--
+		for run > 0 {
+			if run >= 3 {
+				n := minInt(run, 6)
+				tokens = append(tokens, codeLengthToken{symbol: 16, extra: n - 3})
+				run -= n
+			} else {
+				tokens = append(tokens, codeLengthToken{symbol: int(length)})
+				run--
+			}
+		}
+	}
+	return tokens
+}
+
+// codeLengths builds Huffman code lengths no longer than maxLength. When the tree is too deep
+// the rare symbols are made more frequent until it fits.
+func codeLengths(histogram []int, maxLength int) []uint8 {
+	counts := append([]int(nil), histogram...)
+	for minCount := 1; ; minCount *= 2 {
+		lengths, depth := huffmanLengths(counts)
+		if depth <= maxLength {
+			return lengths
+		}
+		for i, count := range counts {
+			if count > 0 && count < minCount {
+				counts[i] = minCount
+			}
+		}
+	}
+}
+
+type huffmanNode struct {
+	count       int
+	symbol      int
+	left, right int
+}
+
+// huffmanLengths builds a Huffman tree with the two queue method and returns the depth of every symbol
+func huffmanLengths(counts []int) ([]uint8, int) {
+	lengths := make([]uint8, len(counts))
+	var nodes []huffmanNode
+	for symbol, count := range counts {
+		if count > 0 {
+			nodes = append(nodes, huffmanNode{count: count, symbol: symbol, left: -1, right: -1})
+		}
+	}
+	if len(nodes) == 0 {
+		return lengths, 0
+	}
+	if len(nodes) == 1 {
+		lengths[nodes[0].symbol] = 1
+		return lengths, 1
+	}
+	sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].count < nodes[j].count })
+
+	leaves := len(nodes)
+	nextLeaf, nextInner := 0, leaves
+	smallest := func() int {
+		if nextLeaf < leaves && (nextInner >= len(nodes) || nodes[nextLeaf].count <= nodes[nextInner].count) {
+			nextLeaf++
+			return nextLeaf - 1
+		}
+		nextInner++
+		return nextInner - 1
+	}
+	for len(nodes) < 2*leaves-1 {
+		a, b := smallest(), smallest()
+		nodes = append(nodes, huffmanNode{count: nodes[a].count + nodes[b].count, symbol: -1, left: a, right: b})
+	}
+
+	depth := 0
+	var walk func(node, level int)
+	walk = func(node, level int) {
+		if nodes[node].symbol >= 0 {
+			lengths[nodes[node].symbol] = uint8(minInt(level, 255))
+			if level > depth {
+				depth = level
+			}
+			return
+		}
+		walk(nodes[node].left, level+1)
+		walk(nodes[node].right, level+1)
+	}
+	walk(len(nodes)-1, 0)
+	return lengths, depth
+}
+
+// newPrefixCode assigns canonical codes to the lengths
+func newPrefixCode(lengths []uint8) prefixCode {
+	pc := prefixCode{lengths: lengths, codes: make([]uint32, len(lengths))}
+
+	var lengthCount [maxCodeLength + 2]int
+	used := 0
+	for _, length := range lengths {
+		if length > 0 {
+			lengthCount[length]++
+			used++
+		}
+	}
+	if used <= 1 {
+		pc.single = true
+		return pc
+	}
+
+	var nextCode [maxCodeLength + 2]uint32
+	code := uint32(0)
+	for length := 1; length < len(nextCode); length++ {
+		code = (code + uint32(lengthCount[length-1])) << 1
+		nextCode[length] = code
+	}
+	for symbol, length := range lengths {
+		if length == 0 {
+			continue
+		}
+		pc.codes[symbol] = reverseBits(nextCode[length], uint(length))
+		nextCode[length]++
+	}
+	return pc
+}
+
+func reverseBits(code uint32, length uint) uint32 {
+	var reversed uint32
+	for i := uint(0); i < length; i++ {
+		reversed = reversed<<1 | code&1
+		code >>= 1
+	}
+	return reversed
+}
+
+// bitWriter packs bits least significant bit first, as VP8L expects
+type bitWriter struct {
+	buf   []byte
+	acc   uint64
+	nbits uint
+}
+
+func (bw *bitWriter) writeBits(value uint32, n uint) {
+	bw.acc |= uint64(value) << bw.nbits
+	bw.nbits += n
+	for bw.nbits >= 8 {
+		bw.buf = append(bw.buf, byte(bw.acc))
+		bw.acc >>= 8
+		bw.nbits -= 8
+	}
+}
+
+func (bw *bitWriter) bytes() []byte {
+	if bw.nbits > 0 {
+		bw.buf = append(bw.buf, byte(bw.acc))
+		bw.acc, bw.nbits = 0, 0
+	}
+	return bw.buf
+}
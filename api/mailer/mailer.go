@@ -0,0 +1,31 @@
+package mailer
+
+import (
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/config"
+)
+
+// Message is a rendered email ready to be handed to a Sender
+type Message struct {
+	ToName  string
+	ToEmail string
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// Sender delivers a Message. Implementations must be safe for concurrent use.
+type Sender interface {
+	Send(msg Message) error
+}
+
+// NewSender picks the Sender named by the mail driver (sendgrid, smtp or memory)
+func NewSender(cfg config.Mail) Sender {
+	switch cfg.Driver {
+	case "sendgrid":
+		return NewSendGridSender(cfg.SendGridAPIKey, cfg.FromName, cfg.FromAddress)
+	case "smtp":
+		return NewSMTPSender(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.FromName, cfg.FromAddress)
+	default:
+		return NewMemorySender()
+	}
+}
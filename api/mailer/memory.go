@@ -0,0 +1,39 @@
+package mailer
+
+import "sync"
+
+// MemorySender keeps every message instead of delivering it. It is meant for tests and local development.
+type MemorySender struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func NewMemorySender() *MemorySender {
+	return &MemorySender{}
+}
+
+func (s *MemorySender) Send(msg Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, msg)
+	return nil
+}
+
+// Messages returns a copy of everything sent so far
+func (s *MemorySender) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	messages := make([]Message, len(s.messages))
+	copy(messages, s.messages)
+	return messages
+}
+
+// Last returns the most recent message, if any
+func (s *MemorySender) Last() (Message, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.messages) == 0 {
+		return Message{}, false
+	}
+	return s.messages[len(s.messages)-1], true
+}
@@ -0,0 +1,37 @@
+package mailer
+
+import (
+	"fmt"
+
+	"github.com/sendgrid/sendgrid-go"
+	"github.com/sendgrid/sendgrid-go/helpers/mail"
+)
+
+// SendGridSender delivers mail through the SendGrid v3 API
+type SendGridSender struct {
+	client   *sendgrid.Client
+	fromName string
+	from     string
+}
+
+func NewSendGridSender(apiKey, fromName, from string) *SendGridSender {
+	return &SendGridSender{
+		client:   sendgrid.NewSendClient(apiKey),
+		fromName: fromName,
+		from:     from,
+	}
+}
+
+func (s *SendGridSender) Send(msg Message) error {
+	from := mail.NewEmail(s.fromName, s.from)
+	to := mail.NewEmail(msg.ToName, msg.ToEmail)
+	message := mail.NewSingleEmail(from, msg.Subject, to, msg.Text, msg.HTML)
+	response, err := s.client.Send(message)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("sendgrid responded with status %d: %s", response.StatusCode, response.Body)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package mailer
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+)
+
+// SMTPSender delivers mail through a plain SMTP relay, using STARTTLS when the server offers it
+type SMTPSender struct {
+	addr     string
+	auth     smtp.Auth
+	fromName string
+	from     string
+}
+
+func NewSMTPSender(host, port, username, password, fromName, from string) *SMTPSender {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return &SMTPSender{
+		addr:     fmt.Sprintf("%s:%s", host, port),
+		auth:     auth,
+		fromName: fromName,
+		from:     from,
+	}
+}
+
+func (s *SMTPSender) Send(msg Message) error {
+	body, err := s.build(msg)
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(s.addr, s.auth, s.from, []string{msg.ToEmail}, body)
+}
+
+// build renders msg as a multipart/alternative MIME message
+func (s *SMTPSender) build(msg Message) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	from := mail.Address{Name: s.fromName, Address: s.from}
+	to := mail.Address{Name: msg.ToName, Address: msg.ToEmail}
+	fmt.Fprintf(&buf, "From: %s\r\n", from.String())
+	fmt.Fprintf(&buf, "To: %s\r\n", to.String())
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", msg.Subject))
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", writer.Boundary())
+
+	parts := []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=UTF-8", msg.Text},
+		{"text/html; charset=UTF-8", msg.HTML},
+	}
+	for _, p := range parts {
+		if p.content == "" {
+			continue
+		}
+		part, err := writer.CreatePart(textproto.MIMEHeader{"Content-Type": {p.contentType}})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := part.Write([]byte(p.content)); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
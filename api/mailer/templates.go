@@ -0,0 +1,115 @@
+package mailer
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/matcornic/hermes/v2"
+)
+
+// productLink is the address of the API the emails link to, see SetProductLink
+var productLink string
+
+// SetProductLink sets the address the emails link to, APP_URL
+func SetProductLink(url string) {
+	productLink = url
+}
+
+func product() hermes.Hermes {
+	return hermes.Hermes{
+		Product: hermes.Product{
+			Name:      "MyGram",
+			Link:      productLink,
+			Copyright: "Copyright © MyGram. All rights reserved.",
+		},
+	}
+}
+
+// humanize prints durations the way people write them in emails ("1 hour", "30 minutes")
+func humanize(d time.Duration) string {
+	if d >= time.Hour && d%time.Hour == 0 {
+		if d == time.Hour {
+			return "1 hour"
+		}
+		return fmt.Sprintf("%d hours", d/time.Hour)
+	}
+	minutes := int(d.Round(time.Minute) / time.Minute)
+	if minutes == 1 {
+		return "1 minute"
+	}
+	return fmt.Sprintf("%d minutes", minutes)
+}
+
+// render turns a hermes email into a Message addressed to the given user
+func render(toName, toEmail, subject string, email hermes.Email) (Message, error) {
+	h := product()
+	html, err := h.GenerateHTML(email)
+	if err != nil {
+		return Message{}, err
+	}
+	text, err := h.GeneratePlainText(email)
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{
+		ToName:  toName,
+		ToEmail: toEmail,
+		Subject: subject,
+		HTML:    html,
+		Text:    text,
+	}, nil
+}
+
+// PasswordResetEmail renders the "forgot password" email containing the reset link
+func PasswordResetEmail(username, email, link string, ttl time.Duration) (Message, error) {
+	return render(username, email, "Reset your MyGram password", hermes.Email{
+		Body: hermes.Body{
+			Name: username,
+			Intros: []string{
+				"You have received this email because a password reset request for your MyGram account was received.",
+			},
+			Actions: []hermes.Action{
+				{
+					Instructions: "Click the button below to reset your password:",
+					Button: hermes.Button{
+						Color: "#DC4D2F",
+						Text:  "Reset your password",
+						Link:  link,
+					},
+				},
+			},
+			Outros: []string{
+				fmt.Sprintf("This link expires in %s and can only be used once.", humanize(ttl)),
+				"If you did not request a password reset, no further action is required on your part.",
+			},
+			Signature: "Thanks",
+		},
+	})
+}
+
+// VerificationEmail renders the "confirm your address" email sent after registration
+func VerificationEmail(username, email, link string, ttl time.Duration) (Message, error) {
+	return render(username, email, "Confirm your MyGram email address", hermes.Email{
+		Body: hermes.Body{
+			Name: username,
+			Intros: []string{
+				"Welcome to MyGram! Please confirm that this is your email address.",
+			},
+			Actions: []hermes.Action{
+				{
+					Instructions: "Click the button below to verify your email address:",
+					Button: hermes.Button{
+						Color: "#22BC66",
+						Text:  "Verify your email",
+						Link:  link,
+					},
+				},
+			},
+			Outros: []string{
+				fmt.Sprintf("This link expires in %s. You can request a new one from the app.", humanize(ttl)),
+				"If you did not create a MyGram account, you can ignore this email.",
+			},
+			Signature: "Thanks",
+		},
+	})
+}
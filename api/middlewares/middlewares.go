@@ -4,13 +4,39 @@ import (
 	"net/http"
 
 	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/auth"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/policy"
 	"github.com/gin-gonic/gin"
 )
 
-func TokenAuthMiddleware() gin.HandlerFunc {
-	errList := make(map[string]string)
+// actorKey is where TokenAuthMiddleware stores the authenticated policy.Actor
+const actorKey = "actor"
+
+// EmailVerifiedFunc reports whether the user has confirmed their email address
+type EmailVerifiedFunc func(uid uint32) (bool, error)
+
+// AuthOption adds a check that runs once the token itself is known to be valid
+type AuthOption func(*authPolicy)
+
+type authPolicy struct {
+	emailVerified EmailVerifiedFunc
+}
+
+// RequireVerifiedEmail rejects users that have not confirmed their email address yet
+func RequireVerifiedEmail(verified EmailVerifiedFunc) AuthOption {
+	return func(p *authPolicy) {
+		p.emailVerified = verified
+	}
+}
+
+// TokenAuthMiddleware rejects the requests without a valid access token of tokens
+func TokenAuthMiddleware(tokens *auth.Tokens, options ...AuthOption) gin.HandlerFunc {
+	checks := authPolicy{}
+	for _, option := range options {
+		option(&checks)
+	}
 	return func(c *gin.Context) {
-		err := auth.TokenValid(c.Request)
+		errList := make(map[string]string)
+		claims, err := tokens.ExtractTokenClaims(c.Request)
 		if err != nil {
 			errList["unauthorized"] = "Unauthorized"
 			c.JSON(http.StatusUnauthorized, gin.H{
@@ -20,6 +46,66 @@ func TokenAuthMiddleware() gin.HandlerFunc {
 			c.Abort()
 			return
 		}
+		if checks.emailVerified != nil {
+			verified, err := checks.emailVerified(claims.UserID)
+			if err != nil || !verified {
+				errList["Unverified_email"] = "Please verify your email address first"
+				c.JSON(http.StatusForbidden, gin.H{
+					"status": http.StatusForbidden,
+					"error":  errList,
+				})
+				c.Abort()
+				return
+			}
+		}
+		setActor(c, claims)
 		c.Next()
 	}
 }
+
+// RequireRole only lets through users with one of the given roles. It must run after TokenAuthMiddleware.
+func RequireRole(roles ...policy.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actor, ok := CurrentActor(c)
+		if !ok || !policy.HasRole(actor, roles...) {
+			forbidden(c)
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequirePermission only lets through users whose role grants the permission. It must run after TokenAuthMiddleware.
+func RequirePermission(permission policy.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actor, ok := CurrentActor(c)
+		if !ok || !policy.Can(actor, permission) {
+			forbidden(c)
+			return
+		}
+		c.Next()
+	}
+}
+
+// CurrentActor returns the user TokenAuthMiddleware authenticated for this request
+func CurrentActor(c *gin.Context) (policy.Actor, bool) {
+	value, ok := c.Get(actorKey)
+	if !ok {
+		return policy.Actor{}, false
+	}
+	actor, ok := value.(policy.Actor)
+	return actor, ok
+}
+
+func setActor(c *gin.Context, claims *auth.Claims) {
+	c.Set(actorKey, policy.Actor{ID: claims.UserID, Role: claims.Role})
+}
+
+func forbidden(c *gin.Context) {
+	errList := map[string]string{"forbidden": "Forbidden"}
+	c.JSON(http.StatusForbidden, gin.H{
+		"status": http.StatusForbidden,
+		"error":  errList,
+	})
+	c.Abort()
+}
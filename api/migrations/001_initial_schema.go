@@ -0,0 +1,284 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// initialSchema creates the tables as AutoMigrate left them before the schema was versioned. The structs are
+// copies of the models at that point so later model changes do not rewrite history. A database created by the
+// old AutoMigrate startup is adopted: AutoMigrate only adds what is missing and existing foreign keys are kept.
+var initialSchema = Migration{
+	Version: 1,
+	Name:    "initial_schema",
+	Up: func(tx *gorm.DB) error {
+		if err := tx.Debug().AutoMigrate(initialTables...).Error; err != nil {
+			return err
+		}
+		for _, key := range initialForeignKeys {
+			if err := tx.Debug().Model(key.model).AddForeignKey(key.field, key.dest, "cascade", "cascade").Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Down: func(tx *gorm.DB) error {
+		dropped := make([]interface{}, len(initialTables))
+		for i, table := range initialTables {
+			dropped[len(initialTables)-1-i] = table
+		}
+		return tx.Debug().DropTableIfExists(dropped...).Error
+	},
+}
+
+// initialTables are in the order they are created, dependencies first. They are dropped in reverse.
+var initialTables = []interface{}{
+	&initialUser{}, &initialRefreshToken{}, &initialResetPassword{}, &initialEmailVerification{}, &initialPhoto{},
+	&initialPhotoVariant{}, &initialSocialMedia{}, &initialComment{}, &initialLike{}, &initialCommentLike{}, &initialFollow{},
+	&initialTimelineEntry{}, &initialTag{}, &initialPhotoTag{}, &initialMention{}, &initialNotification{},
+	&initialNotificationPreference{},
+}
+
+// initialForeignKeys delete what points at a row along with it
+var initialForeignKeys = []struct {
+	model interface{}
+	field string
+	dest  string
+}{
+	{&initialPhoto{}, "user_id", "users(id)"},
+	{&initialComment{}, "user_id", "users(id)"},
+	{&initialSocialMedia{}, "user_id", "users(id)"},
+	{&initialComment{}, "photo_id", "photos(id)"},
+	{&initialComment{}, "parent_id", "comments(id)"},
+	{&initialPhotoVariant{}, "photo_id", "photos(id)"},
+	{&initialLike{}, "user_id", "users(id)"},
+	{&initialLike{}, "photo_id", "photos(id)"},
+	{&initialCommentLike{}, "user_id", "users(id)"},
+	{&initialCommentLike{}, "comment_id", "comments(id)"},
+	{&initialFollow{}, "follower_id", "users(id)"},
+	{&initialFollow{}, "followee_id", "users(id)"},
+	{&initialTimelineEntry{}, "user_id", "users(id)"},
+	{&initialTimelineEntry{}, "photo_id", "photos(id)"},
+	{&initialTimelineEntry{}, "author_id", "users(id)"},
+	{&initialPhotoTag{}, "photo_id", "photos(id)"},
+	{&initialPhotoTag{}, "tag_id", "tags(id)"},
+	{&initialMention{}, "user_id", "users(id)"},
+	{&initialMention{}, "author_id", "users(id)"},
+	{&initialMention{}, "photo_id", "photos(id)"},
+	{&initialMention{}, "comment_id", "comments(id)"},
+	{&initialNotification{}, "user_id", "users(id)"},
+	{&initialNotification{}, "actor_id", "users(id)"},
+	{&initialNotification{}, "photo_id", "photos(id)"},
+	{&initialNotification{}, "comment_id", "comments(id)"},
+	{&initialNotificationPreference{}, "user_id", "users(id)"},
+}
+
+type initialUser struct {
+	ID              uint32 `gorm:"primary_key;auto_increment"`
+	Username        string `gorm:"size:255;not null;unique"`
+	Email           string `gorm:"size:100;not null;unique"`
+	Password        string `gorm:"size:100;not null;"`
+	Age             uint32 `gorm:"not null;"`
+	Role            string `gorm:"size:20;not null;default:'user'"`
+	EmailVerifiedAt *time.Time
+	CreatedAt       time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt       time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+func (initialUser) TableName() string { return "users" }
+
+type initialRefreshToken struct {
+	ID        uint64    `gorm:"primary_key;auto_increment"`
+	TokenHash string    `gorm:"size:64;not null;unique"`
+	FamilyID  string    `gorm:"size:64;not null;index"`
+	UserID    uint32    `gorm:"not null;index"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+func (initialRefreshToken) TableName() string { return "refresh_tokens" }
+
+type initialResetPassword struct {
+	ID        uint64    `gorm:"primary_key;auto_increment"`
+	UserID    uint32    `gorm:"not null;index"`
+	Email     string    `gorm:"size:100;not null;"`
+	TokenHash string    `gorm:"size:64;not null;unique"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+func (initialResetPassword) TableName() string { return "reset_passwords" }
+
+type initialEmailVerification struct {
+	ID        uint64    `gorm:"primary_key;auto_increment"`
+	UserID    uint32    `gorm:"not null;index"`
+	Email     string    `gorm:"size:100;not null;"`
+	TokenHash string    `gorm:"size:64;not null;unique"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+func (initialEmailVerification) TableName() string { return "email_verifications" }
+
+type initialPhoto struct {
+	ID          uint64    `gorm:"primary_key;auto_increment"`
+	Title       string    `gorm:"size:255;not null;unique"`
+	Caption     string    `gorm:"size:255;not null;"`
+	PhotoURL    string    `gorm:"size:255;not null;"`
+	UserID      uint32    `gorm:"not null"`
+	CreatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	StorageKey  string    `gorm:"size:255"`
+	MimeType    string    `gorm:"size:50"`
+	Width       int
+	Height      int
+	ByteSize    int64
+	TakenAt     *time.Time
+	CameraModel string `gorm:"size:100"`
+	Orientation int
+}
+
+func (initialPhoto) TableName() string { return "photos" }
+
+type initialPhotoVariant struct {
+	ID         uint64 `gorm:"primary_key;auto_increment"`
+	PhotoID    uint64 `gorm:"not null;unique_index:idx_photo_variants_photo_name"`
+	Name       string `gorm:"size:20;not null;unique_index:idx_photo_variants_photo_name"`
+	StorageKey string `gorm:"size:255;not null"`
+	URL        string `gorm:"size:255;not null"`
+	MimeType   string `gorm:"size:50"`
+	Width      int
+	Height     int
+	ByteSize   int64
+	CreatedAt  time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+func (initialPhotoVariant) TableName() string { return "photo_variants" }
+
+type initialSocialMedia struct {
+	ID             uint64    `gorm:"primary_key;auto_increment"`
+	Name           string    `gorm:"size:255;not null;unique"`
+	SocialMediaURL string    `gorm:"text;not null;"`
+	UserID         uint32    `gorm:"not null"`
+	CreatedAt      time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt      time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+func (initialSocialMedia) TableName() string { return "social_media" }
+
+type initialComment struct {
+	ID        uint64    `gorm:"primary_key;auto_increment"`
+	Message   string    `gorm:"size:255;not null"`
+	UserID    uint32    `gorm:"not null"`
+	PhotoID   uint64    `gorm:"not null;index"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	ParentID  *uint64   `gorm:"index"`
+	Depth     int       `gorm:"not null;default:0"`
+	Path      string    `gorm:"size:255;index"`
+	Deleted   bool      `gorm:"not null;default:false"`
+}
+
+func (initialComment) TableName() string { return "comments" }
+
+type initialLike struct {
+	ID        uint64    `gorm:"primary_key;auto_increment"`
+	UserID    uint32    `gorm:"not null;unique_index:idx_likes_user_photo"`
+	PhotoID   uint64    `gorm:"not null;unique_index:idx_likes_user_photo;index"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+func (initialLike) TableName() string { return "likes" }
+
+type initialCommentLike struct {
+	ID        uint64    `gorm:"primary_key;auto_increment"`
+	UserID    uint32    `gorm:"not null;unique_index:idx_comment_likes_user_comment"`
+	CommentID uint64    `gorm:"not null;unique_index:idx_comment_likes_user_comment;index"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+func (initialCommentLike) TableName() string { return "comment_likes" }
+
+type initialFollow struct {
+	ID         uint64    `gorm:"primary_key;auto_increment"`
+	FollowerID uint32    `gorm:"not null;unique_index:idx_follows_follower_followee"`
+	FolloweeID uint32    `gorm:"not null;unique_index:idx_follows_follower_followee;index"`
+	CreatedAt  time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt  time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+func (initialFollow) TableName() string { return "follows" }
+
+type initialTimelineEntry struct {
+	ID        uint64    `gorm:"primary_key;auto_increment"`
+	UserID    uint32    `gorm:"not null;unique_index:idx_timeline_entries_user_photo;index:idx_timeline_entries_user_created"`
+	PhotoID   uint64    `gorm:"not null;unique_index:idx_timeline_entries_user_photo;index"`
+	AuthorID  uint32    `gorm:"not null;index"`
+	CreatedAt time.Time `gorm:"not null;index:idx_timeline_entries_user_created"`
+}
+
+func (initialTimelineEntry) TableName() string { return "timeline_entries" }
+
+type initialTag struct {
+	ID         uint64    `gorm:"primary_key;auto_increment"`
+	Name       string    `gorm:"size:50;not null;unique"`
+	PhotoCount int64     `gorm:"not null;default:0;index"`
+	CreatedAt  time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt  time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+func (initialTag) TableName() string { return "tags" }
+
+type initialPhotoTag struct {
+	ID        uint64    `gorm:"primary_key;auto_increment"`
+	PhotoID   uint64    `gorm:"not null;unique_index:idx_photo_tags_photo_tag"`
+	TagID     uint64    `gorm:"not null;unique_index:idx_photo_tags_photo_tag;index"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP;index"`
+}
+
+func (initialPhotoTag) TableName() string { return "photo_tags" }
+
+type initialMention struct {
+	ID        uint64    `gorm:"primary_key;auto_increment"`
+	UserID    uint32    `gorm:"not null;index"`
+	AuthorID  uint32    `gorm:"not null;index"`
+	PhotoID   uint64    `gorm:"not null;index"`
+	CommentID *uint64   `gorm:"index"`
+	Start     int       `gorm:"column:start_offset;not null"`
+	End       int       `gorm:"column:end_offset;not null"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+func (initialMention) TableName() string { return "mentions" }
+
+type initialNotification struct {
+	ID        uint64  `gorm:"primary_key;auto_increment"`
+	UserID    uint32  `gorm:"not null;index"`
+	ActorID   uint32  `gorm:"not null;index"`
+	Kind      string  `gorm:"size:20;not null"`
+	PhotoID   *uint64 `gorm:"index"`
+	CommentID *uint64 `gorm:"index"`
+	ReadAt    *time.Time
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+func (initialNotification) TableName() string { return "notifications" }
+
+type initialNotificationPreference struct {
+	UserID    uint32    `gorm:"primary_key;auto_increment:false"`
+	Comment   bool      `gorm:"column:on_comment;not null"`
+	Reply     bool      `gorm:"column:on_reply;not null"`
+	Mention   bool      `gorm:"column:on_mention;not null"`
+	Follow    bool      `gorm:"column:on_follow;not null"`
+	Like      bool      `gorm:"column:on_like;not null"`
+	UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+func (initialNotificationPreference) TableName() string { return "notification_preferences" }
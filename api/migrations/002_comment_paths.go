@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+)
+
+// commentPaths gives the comments made before threads existed their path, the server used to do it on every start.
+// They are all top level, so the path is the zero padded id the threads used then. Going down leaves the paths,
+// they are right for a top level comment either way.
+var commentPaths = Migration{
+	Version: 2,
+	Name:    "comment_paths",
+	Up: func(tx *gorm.DB) error {
+		for {
+			var ids []uint64
+			err := tx.Debug().Table("comments").Where("path = ? OR path IS NULL", "").Limit(100).Pluck("id", &ids).Error
+			if err != nil {
+				return err
+			}
+			if len(ids) == 0 {
+				return nil
+			}
+			for _, id := range ids {
+				err = tx.Debug().Table("comments").Where("id = ?", id).UpdateColumn("path", fmt.Sprintf("%020d/", id)).Error
+				if err != nil {
+					return err
+				}
+			}
+		}
+	},
+	Down: func(tx *gorm.DB) error {
+		return nil
+	},
+}
@@ -0,0 +1,47 @@
+package migrations
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite" //sqlite database driver of the tests
+)
+
+func TestCommentPaths(t *testing.T) {
+	db, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	// every connection to :memory: opens a database of its own
+	db.DB().SetMaxOpenConns(1)
+	if err = db.AutoMigrate(&initialComment{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	// more comments than a batch, with an empty path as left by AutoMigrate, and one that already has its path
+	for i := 0; i < 150; i++ {
+		if err = db.Create(&initialComment{Message: "old", UserID: 1, PhotoID: 1}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.Exec("UPDATE comments SET path = NULL WHERE id = 3")
+	db.Create(&initialComment{Message: "reply", UserID: 1, PhotoID: 1, Path: "00000000000000000001/00000000000000000999/", Depth: 1})
+
+	if err = db.Transaction(commentPaths.Up); err != nil {
+		t.Fatal(err)
+	}
+	comments := []initialComment{}
+	db.Order("id").Find(&comments)
+	for _, comment := range comments[:150] {
+		if want := fmt.Sprintf("%020d/", comment.ID); comment.Path != want {
+			t.Errorf("comment %d: got path %q, want %q", comment.ID, comment.Path, want)
+		}
+	}
+	if got := comments[2].Path; got != "00000000000000000003/" {
+		t.Errorf("a comment without a path got %q", got)
+	}
+	if got := comments[150].Path; got != "00000000000000000001/00000000000000000999/" {
+		t.Errorf("a comment with its path got %q", got)
+	}
+}
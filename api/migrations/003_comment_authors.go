@@ -0,0 +1,28 @@
+package migrations
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+// commentAuthors lets a comment lose its author: when an account is deleted, its comments that have replies stay
+// as tombstones with no user_id, the foreign key would otherwise delete them and their replies with the user.
+// Going down deletes those tombstones, the replies under them go along through the parent_id foreign key.
+var commentAuthors = Migration{
+	Version: 3,
+	Name:    "comment_authors",
+	Up: func(tx *gorm.DB) error {
+		if tx.Dialect().GetName() == "postgres" {
+			return tx.Debug().Exec("ALTER TABLE comments ALTER COLUMN user_id DROP NOT NULL").Error
+		}
+		return tx.Debug().Table("comments").ModifyColumn("user_id", "int unsigned NULL").Error
+	},
+	Down: func(tx *gorm.DB) error {
+		if err := tx.Debug().Exec("DELETE FROM comments WHERE user_id IS NULL").Error; err != nil {
+			return err
+		}
+		if tx.Dialect().GetName() == "postgres" {
+			return tx.Debug().Exec("ALTER TABLE comments ALTER COLUMN user_id SET NOT NULL").Error
+		}
+		return tx.Debug().Table("comments").ModifyColumn("user_id", "int unsigned NOT NULL").Error
+	},
+}
@@ -0,0 +1,25 @@
+package migrations
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+// photoVariantErrors records why the variants of a photo cannot be generated, a file that does not decode
+// never will and the backfill stops picking it up. Going down drops the column.
+var photoVariantErrors = Migration{
+	Version: 4,
+	Name:    "photo_variant_errors",
+	Up: func(tx *gorm.DB) error {
+		return tx.Debug().AutoMigrate(&variantErrorPhoto{}).Error
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Debug().Model(&variantErrorPhoto{}).DropColumn("variants_error").Error
+	},
+}
+
+// variantErrorPhoto holds the column the migration adds, AutoMigrate leaves the others of photos alone
+type variantErrorPhoto struct {
+	VariantsError string `gorm:"size:255;not null;default:''"`
+}
+
+func (variantErrorPhoto) TableName() string { return "photos" }
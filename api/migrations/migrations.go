@@ -0,0 +1,180 @@
+package migrations
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Migration is one versioned change of the schema. Up and Down get a transaction and go through gorm,
+// which writes the SQL of the dialect, so the same migration runs on postgres and mysql.
+// mysql commits every CREATE, ALTER and DROP on its own, there a failed migration can be left half applied.
+type Migration struct {
+	Version uint
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// SchemaMigration records a migration applied to the database
+type SchemaMigration struct {
+	Version   uint      `gorm:"primary_key;auto_increment:false" json:"version"`
+	Name      string    `gorm:"size:255;not null" json:"name"`
+	AppliedAt time.Time `gorm:"not null" json:"applied_at"`
+}
+
+// State is a migration with when it was applied, AppliedAt is nil while it is pending.
+// Unknown is set for a version recorded in the database that this build does not have.
+type State struct {
+	Migration
+	AppliedAt *time.Time
+	Unknown   bool
+}
+
+// all lists every migration, in the order they are applied. Never edit one that has shipped, add a new one.
+var all = []Migration{
+	initialSchema,
+	commentPaths,
+	commentAuthors,
+	photoVariantErrors,
+}
+
+func init() {
+	sort.Slice(all, func(i, j int) bool { return all[i].Version < all[j].Version })
+}
+
+// Status lists every migration, applied or not, and the applied versions this build does not know about
+func Status(db *gorm.DB) ([]State, error) {
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return nil, err
+	}
+	states := make([]State, 0, len(all)+len(applied))
+	known := make(map[uint]bool, len(all))
+	for _, migration := range all {
+		known[migration.Version] = true
+		state := State{Migration: migration}
+		if record, ok := applied[migration.Version]; ok {
+			state.AppliedAt = &record.AppliedAt
+		}
+		states = append(states, state)
+	}
+	for version, record := range applied {
+		if !known[version] {
+			appliedAt := record.AppliedAt
+			states = append(states, State{Migration: Migration{Version: version, Name: record.Name}, AppliedAt: &appliedAt, Unknown: true})
+		}
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Version < states[j].Version })
+	return states, nil
+}
+
+// Pending lists the migrations not applied yet, oldest first. It only reads, the server calls it on startup.
+func Pending(db *gorm.DB) ([]Migration, error) {
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return nil, err
+	}
+	pending := []Migration{}
+	for _, migration := range all {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// Up applies the pending migrations oldest first, steps caps how many and 0 applies them all.
+// It returns the migrations applied, up to the one that failed.
+func Up(db *gorm.DB, steps int) ([]Migration, error) {
+	if err := db.Debug().AutoMigrate(&SchemaMigration{}).Error; err != nil {
+		return nil, err
+	}
+	pending, err := Pending(db)
+	if err != nil {
+		return nil, err
+	}
+	if steps > 0 && steps < len(pending) {
+		pending = pending[:steps]
+	}
+	done := []Migration{}
+	for _, migration := range pending {
+		err = run(db, migration.Up, func(tx *gorm.DB) error {
+			return tx.Debug().Create(&SchemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %d %s: %v", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// Down rolls back the applied migrations newest first, steps caps how many and 0 rolls back every one of them.
+// It refuses when an applied migration is not part of this build, its Down is not known.
+func Down(db *gorm.DB, steps int) ([]Migration, error) {
+	states, err := Status(db)
+	if err != nil {
+		return nil, err
+	}
+	applied := []State{}
+	for i := len(states) - 1; i >= 0; i-- {
+		if states[i].AppliedAt != nil {
+			applied = append(applied, states[i])
+		}
+	}
+	if steps > 0 && steps < len(applied) {
+		applied = applied[:steps]
+	}
+	done := []Migration{}
+	for _, state := range applied {
+		if state.Unknown {
+			return done, fmt.Errorf("migration %d %s is applied but unknown to this build, roll it back with the build that has it", state.Version, state.Name)
+		}
+		migration := state.Migration
+		err = run(db, migration.Down, func(tx *gorm.DB) error {
+			return tx.Debug().Where("version = ?", migration.Version).Delete(&SchemaMigration{}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %d %s: %v", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// run applies one direction of a migration and its bookkeeping in a single transaction.
+// Two migrate commands racing each other collide on the version primary key and the second one rolls back.
+func run(db *gorm.DB, change, record func(tx *gorm.DB) error) error {
+	tx := db.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+	if err := change(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := record(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// appliedVersions reads schema_migrations, a database that never ran a migration has none applied
+func appliedVersions(db *gorm.DB) (map[uint]SchemaMigration, error) {
+	applied := make(map[uint]SchemaMigration)
+	if !db.HasTable(&SchemaMigration{}) {
+		return applied, nil
+	}
+	records := []SchemaMigration{}
+	if err := db.Debug().Model(&SchemaMigration{}).Find(&records).Error; err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
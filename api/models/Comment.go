@@ -2,10 +2,12 @@ package models
 
 import (
 	"errors"
+	"fmt"
 	"html"
 	"strings"
 	"time"
 
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/utils/pagination"
 	"github.com/jinzhu/gorm"
 )
 
@@ -13,10 +15,31 @@ type Comment struct {
 	ID        uint64    `gorm:"primary_key;auto_increment" json:"id"`
 	Message   string    `gorm:"size:255;not null" json:"message"`
 	User      User      `json:"user"`
-	UserID    uint32    `gorm:"not null" json:"user_id"`
-	PhotoID   uint64    `gorm:"not null" json:"photo_id"`
+	UserID    uint32    `json:"user_id"`
+	PhotoID   uint64    `gorm:"not null;index" json:"photo_id"`
 	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
 	UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+
+	// replies point at the comment they answer, top level comments have no parent.
+	// Path lists the ids from the top level comment down to this one, see commentPath
+	ParentID *uint64 `gorm:"index" json:"parent_id"`
+	Depth    int     `gorm:"not null;default:0" json:"depth"`
+	Path     string  `gorm:"size:255;index" json:"-"`
+	// a deleted comment that still has replies is kept as a tombstone so the thread stays whole.
+	// The user_id of a tombstone is NULL, read as 0, once its author deleted their account
+	Deleted bool `gorm:"not null;default:false" json:"deleted"`
+
+	// filled in per viewer by CommentLike.FillCommentLikes
+	LikeCount int64 `gorm:"-" json:"like_count"`
+	LikedByMe bool  `gorm:"-" json:"liked_by_me"`
+
+	ReplyCount int64 `gorm:"-" json:"reply_count"`
+	// the users mentioned in the message, kept in sync by SaveComment and UpdateAComment
+	Mentions []Mention `gorm:"-" json:"mentions"`
+	// only set when a thread is loaded as a tree
+	Replies []Comment `gorm:"-" json:"replies"`
+	// set when a thread leaves out replies under the comment, FindMoreReplies lists them from there
+	MoreReplies string `gorm:"-" json:"more_replies,omitempty"`
 }
 
 type CreateComment struct {
@@ -58,6 +81,16 @@ func (p *Comment) SaveComment(db *gorm.DB) (*Comment, error) {
 	if err != nil {
 		return &Comment{}, err
 	}
+	// the path ends with the id of the comment, which is only known now
+	p.Path += commentPath(p.ID)
+	err = db.Debug().Model(&Comment{}).Where("id = ?", p.ID).UpdateColumn("path", p.Path).Error
+	if err != nil {
+		return &Comment{}, err
+	}
+	mention := Mention{}
+	if p.Mentions, err = mention.SyncCommentMentions(db, *p); err != nil {
+		return &Comment{}, err
+	}
 	if p.ID != 0 {
 		err = db.Debug().Model(&User{}).Where("id = ?", p.UserID).Take(&p.User).Error
 		if err != nil {
@@ -68,22 +101,80 @@ func (p *Comment) SaveComment(db *gorm.DB) (*Comment, error) {
 	return p, nil
 }
 
-func (p *Comment) FindAllComments(db *gorm.DB) (*[]Comment, error) {
+// CommentSortKeys are the orders GET /comments can be listed in
+var CommentSortKeys = []pagination.SortKey{
+	{Name: "created_at", Column: "created_at", Kind: pagination.Time},
+	{Name: "id", Column: "id", Kind: pagination.Number},
+}
+
+func (p *Comment) FindAllComments(db *gorm.DB, page pagination.Page, filter pagination.Filter) (*[]Comment, pagination.Links, error) {
+	return findComments(db, db.Debug().Model(&Comment{}), page, filter)
+}
+
+// FindPhotoComments lists the comments of one photo, paged like FindAllComments
+func (p *Comment) FindPhotoComments(db *gorm.DB, pid uint64, page pagination.Page, filter pagination.Filter) (*[]Comment, pagination.Links, error) {
+	return findComments(db, db.Debug().Model(&Comment{}).Where("comments.photo_id = ?", pid), page, filter)
+}
+
+// findComments pages through the comments selected by query, db loads their associations
+func findComments(db *gorm.DB, query *gorm.DB, page pagination.Page, filter pagination.Filter) (*[]Comment, pagination.Links, error) {
 	var err error
 	comments := []Comment{}
-	err = db.Debug().Model(&Comment{}).Limit(100).Order("created_at desc").Find(&comments).Error
+	err = page.Apply(filter.Apply(query, "comments"), "comments").Find(&comments).Error
 	if err != nil {
-		return &[]Comment{}, err
+		return &[]Comment{}, pagination.Links{}, err
 	}
-	if len(comments) > 0 {
-		for i, _ := range comments {
-			err := db.Debug().Model(&User{}).Where("id = ?", comments[i].UserID).Take(&comments[i].User).Error
-			if err != nil {
-				return &[]Comment{}, err
-			}
+	comments, links := pagination.Window(page, comments, func(row Comment) (interface{}, uint64) {
+		switch page.Key.Name {
+		case "id":
+			return row.ID, row.ID
 		}
+		return row.CreatedAt, row.ID
+	})
+	if err = loadComments(db, comments); err != nil {
+		return &[]Comment{}, pagination.Links{}, err
 	}
-	return &comments, nil
+	return &comments, links, nil
+}
+
+// FindFirstComments sets Comments of every photo to its n oldest top level comments, with a single query for all the photos.
+// Each photo gets a LIMIT subquery of its own, window functions are not available before MySQL 8.
+func (p *Comment) FindFirstComments(db *gorm.DB, photos []Photo, n int) error {
+	parts := []string{}
+	values := []interface{}{}
+	seen := make(map[uint64]bool)
+	for _, photo := range photos {
+		if seen[photo.ID] {
+			continue
+		}
+		seen[photo.ID] = true
+		parts = append(parts, fmt.Sprintf(`SELECT * FROM (
+			SELECT * FROM comments WHERE photo_id = ? AND parent_id IS NULL ORDER BY created_at ASC, id ASC LIMIT ?
+		) first_%d`, len(parts)))
+		values = append(values, photo.ID, n)
+	}
+	comments := []Comment{}
+	if len(parts) > 0 && n > 0 {
+		err := db.Debug().Raw(`SELECT * FROM (`+strings.Join(parts, " UNION ALL ")+`) first_comments
+			ORDER BY photo_id, created_at ASC, id ASC`, values...).Scan(&comments).Error
+		if err != nil {
+			return err
+		}
+	}
+	if err := loadComments(db, comments); err != nil {
+		return err
+	}
+	byPhoto := make(map[uint64][]Comment)
+	for _, comment := range comments {
+		byPhoto[comment.PhotoID] = append(byPhoto[comment.PhotoID], comment)
+	}
+	for i := range photos {
+		photos[i].Comments = byPhoto[photos[i].ID]
+		if photos[i].Comments == nil {
+			photos[i].Comments = []Comment{}
+		}
+	}
+	return nil
 }
 
 func (p *Comment) FindCommentByID(db *gorm.DB, pid uint64) (*Comment, error) {
@@ -92,12 +183,11 @@ func (p *Comment) FindCommentByID(db *gorm.DB, pid uint64) (*Comment, error) {
 	if err != nil {
 		return &Comment{}, err
 	}
-	if p.ID != 0 {
-		err = db.Debug().Model(&User{}).Where("id = ?", p.UserID).Take(&p.User).Error
-		if err != nil {
-			return &Comment{}, err
-		}
+	comments := []Comment{*p}
+	if err = loadComments(db, comments); err != nil {
+		return &Comment{}, err
 	}
+	*p = comments[0]
 	return p, nil
 }
 
@@ -109,6 +199,10 @@ func (p *Comment) UpdateAComment(db *gorm.DB) (*Comment, error) {
 	if err != nil {
 		return &Comment{}, err
 	}
+	mention := Mention{}
+	if p.Mentions, err = mention.SyncCommentMentions(db, *p); err != nil {
+		return &Comment{}, err
+	}
 	if p.ID != 0 {
 		err = db.Debug().Model(&User{}).Where("id = ?", p.UserID).Take(&p.User).Error
 		if err != nil {
@@ -118,13 +212,83 @@ func (p *Comment) UpdateAComment(db *gorm.DB) (*Comment, error) {
 	return p, nil
 }
 
+// DeleteAComment deletes the comment, or turns it into a tombstone when it has replies.
+// Tombstones left without replies are deleted as well, up the thread, all in one transaction.
 func (p *Comment) DeleteAComment(db *gorm.DB) (int64, error) {
 
-	db = db.Debug().Model(&Comment{}).Where("id = ?", p.ID).Take(&Comment{}).Delete(&Comment{})
-	if db.Error != nil {
-		return 0, db.Error
+	tx := db.Begin()
+	if tx.Error != nil {
+		return 0, tx.Error
+	}
+	deleted, err := p.deleteComment(tx)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if err = tx.Commit().Error; err != nil {
+		return 0, err
+	}
+	return deleted, nil
+}
+
+func (p *Comment) deleteComment(db *gorm.DB) (int64, error) {
+
+	like := CommentLike{}
+	if _, err := like.DeleteCommentLikes(db, p.ID); err != nil {
+		return 0, err
+	}
+	// a tombstone has no message left to mention anyone
+	mention := Mention{}
+	if _, err := mention.DeleteCommentMentions(db, []uint64{p.ID}); err != nil {
+		return 0, err
+	}
+	notification := Notification{}
+	if _, err := notification.DeleteCommentNotifications(db, []uint64{p.ID}); err != nil {
+		return 0, err
+	}
+	comment := Comment{}
+	err := db.Debug().Model(&Comment{}).Where("id = ?", p.ID).Take(&comment).Error
+	if err != nil {
+		return 0, err
+	}
+	var replies int64
+	if err = db.Debug().Model(&Comment{}).Where("parent_id = ?", comment.ID).Count(&replies).Error; err != nil {
+		return 0, err
+	}
+	if replies > 0 {
+		db = db.Debug().Model(&Comment{}).Where("id = ?", comment.ID).UpdateColumns(map[string]interface{}{"message": "", "deleted": true, "updated_at": time.Now()})
+		if db.Error != nil {
+			return 0, db.Error
+		}
+		return db.RowsAffected, nil
+	}
+
+	var deleted int64
+	for {
+		result := db.Debug().Where("id = ?", comment.ID).Delete(&Comment{})
+		if result.Error != nil {
+			return deleted, result.Error
+		}
+		deleted += result.RowsAffected
+		if comment.ParentID == nil {
+			return deleted, nil
+		}
+		parent := Comment{}
+		err = db.Debug().Model(&Comment{}).Where("id = ? AND deleted = ?", *comment.ParentID, true).Take(&parent).Error
+		if gorm.IsRecordNotFoundError(err) {
+			return deleted, nil
+		}
+		if err != nil {
+			return deleted, err
+		}
+		if err = db.Debug().Model(&Comment{}).Where("parent_id = ?", parent.ID).Count(&replies).Error; err != nil {
+			return deleted, err
+		}
+		if replies > 0 {
+			return deleted, nil
+		}
+		comment = parent
 	}
-	return db.RowsAffected, nil
 }
 
 func (p *Comment) FindUserComments(db *gorm.DB, uid uint32) (*[]Comment, error) {
@@ -135,23 +299,51 @@ func (p *Comment) FindUserComments(db *gorm.DB, uid uint32) (*[]Comment, error)
 	if err != nil {
 		return &[]Comment{}, err
 	}
-	if len(comments) > 0 {
-		for i, _ := range comments {
-			err := db.Debug().Model(&User{}).Where("id = ?", comments[i].UserID).Take(&comments[i].User).Error
-			if err != nil {
-				return &[]Comment{}, err
-			}
-		}
+	if err = loadComments(db, comments); err != nil {
+		return &[]Comment{}, err
 	}
 	return &comments, nil
 }
 
-// When a user is deleted, we also delete the comment that the user had
+// When a user is deleted, we also delete the comments that the user had. A comment with replies is kept as
+// a tombstone without its author so the thread stays whole, tombstones left without replies are deleted as well.
 func (c *Comment) DeleteUserComments(db *gorm.DB, uid uint32) (int64, error) {
-	comments := []Comment{}
-	db = db.Debug().Model(&Comment{}).Where("user_id = ?", uid).Find(&comments).Delete(&comments)
-	if db.Error != nil {
-		return 0, db.Error
+	own := db.Model(&Comment{}).Select("id").Where("user_id = ?", uid).QueryExpr()
+	for _, model := range []interface{}{&CommentLike{}, &Mention{}, &Notification{}} {
+		if err := db.Debug().Where("comment_id IN (?)", own).Delete(model).Error; err != nil {
+			return 0, err
+		}
 	}
-	return db.RowsAffected, nil
+	var photos []uint64
+	if err := db.Debug().Model(&Comment{}).Where("user_id = ?", uid).Pluck("DISTINCT photo_id", &photos).Error; err != nil {
+		return 0, err
+	}
+	if len(photos) == 0 {
+		return 0, nil
+	}
+
+	// deleting the leaves can leave their parent without replies, so this goes on up the threads
+	var deleted int64
+	for {
+		var ids []uint64
+		err := db.Debug().Model(&Comment{}).Where("photo_id IN (?) AND (user_id = ? OR deleted = ?)", photos, uid, true).
+			Where("NOT EXISTS (SELECT 1 FROM comments replies WHERE replies.parent_id = comments.id)").
+			Limit(100).Pluck("id", &ids).Error
+		if err != nil {
+			return deleted, err
+		}
+		if len(ids) == 0 {
+			break
+		}
+		result := db.Debug().Where("id IN (?)", ids).Delete(&Comment{})
+		if result.Error != nil {
+			return deleted, result.Error
+		}
+		deleted += result.RowsAffected
+	}
+
+	// the comments left have replies of others, the foreign key would delete them with the user
+	err := db.Debug().Model(&Comment{}).Where("user_id = ?", uid).
+		UpdateColumns(map[string]interface{}{"message": "", "deleted": true, "user_id": gorm.Expr("NULL"), "updated_at": time.Now()}).Error
+	return deleted, err
 }
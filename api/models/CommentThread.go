@@ -0,0 +1,185 @@
+package models
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/utils/pagination"
+	"github.com/jinzhu/gorm"
+)
+
+// MaxCommentDepth is how deep replies can nest, top level comments are at depth 0
+var MaxCommentDepth = 5
+
+// MaxThreadReplies is how many replies come with each comment of a thread, the others are
+// listed from the more_replies cursor of the comment
+var MaxThreadReplies = 50
+
+var (
+	errMaxDepth       = errors.New("max depth")
+	errDeletedComment = errors.New("deleted comment")
+)
+
+// commentPath is the segment a comment adds to the path of its thread. The ids are zero padded so that
+// sorting by path lists a thread depth first, every comment followed by its replies in the order they came.
+func commentPath(id uint64) string {
+	return fmt.Sprintf("%020d/", id)
+}
+
+// underPaths selects the comments with one of the paths, and everything below them
+func underPaths(db *gorm.DB, paths []string) *gorm.DB {
+	conditions := []string{}
+	args := []interface{}{}
+	for _, path := range paths {
+		// an empty path would select every comment
+		if path == "" {
+			continue
+		}
+		conditions = append(conditions, "comments.path LIKE ?")
+		args = append(args, path+"%")
+	}
+	if len(conditions) == 0 {
+		return db.Where("1 = 0")
+	}
+	return db.Where(strings.Join(conditions, " OR "), args...)
+}
+
+// ReplyTo makes the comment a reply to parent, on the same photo
+func (p *Comment) ReplyTo(parent Comment) error {
+	if parent.Deleted {
+		return errDeletedComment
+	}
+	if parent.Depth >= MaxCommentDepth {
+		return errMaxDepth
+	}
+	p.ParentID = &parent.ID
+	p.PhotoID = parent.PhotoID
+	p.Depth = parent.Depth + 1
+	// SaveComment appends the id of the reply
+	p.Path = parent.Path
+	return nil
+}
+
+// FindPhotoThreads pages through the top level comments of a photo, each followed by the replies under it
+func (p *Comment) FindPhotoThreads(db *gorm.DB, pid uint64, page pagination.Page, filter pagination.Filter) (*[]Comment, pagination.Links, error) {
+	return findThreads(db, db.Debug().Model(&Comment{}).Where("comments.photo_id = ? AND comments.parent_id IS NULL", pid), page, filter)
+}
+
+// FindReplyThreads pages through the replies to a comment, each followed by the replies under it
+func (p *Comment) FindReplyThreads(db *gorm.DB, cid uint64, page pagination.Page, filter pagination.Filter) (*[]Comment, pagination.Links, error) {
+	return findThreads(db, db.Debug().Model(&Comment{}).Where("comments.parent_id = ?", cid), page, filter)
+}
+
+// findThreads pages through the comments selected by query, they all sit at the same depth. Each comment is
+// followed by at most MaxThreadReplies of the replies under it depth first, so the result is a flattened thread;
+// NestThreads turns it into a tree. When replies are left out, MoreReplies of the comment is where they continue.
+// The replies of a whole page are loaded with one query, a LIMIT subquery per comment.
+func findThreads(db *gorm.DB, query *gorm.DB, page pagination.Page, filter pagination.Filter) (*[]Comment, pagination.Links, error) {
+	comments, links, err := findComments(db, query, page, filter)
+	if err != nil || len(*comments) == 0 {
+		return comments, links, err
+	}
+	parts := make([]string, len(*comments))
+	values := []interface{}{}
+	for i, comment := range *comments {
+		// the _ wildcard asks for at least one more character, so the comment itself is left out.
+		// One reply more than the cap tells whether there are more.
+		parts[i] = fmt.Sprintf(`SELECT * FROM (
+			SELECT * FROM comments WHERE path LIKE ? ORDER BY path LIMIT ?
+		) replies_%d`, i)
+		values = append(values, comment.Path+"_%", MaxThreadReplies+1)
+	}
+	replies := []Comment{}
+	err = db.Debug().Raw(`SELECT * FROM (`+strings.Join(parts, " UNION ALL ")+`) thread_replies ORDER BY path`, values...).Scan(&replies).Error
+	if err != nil {
+		return &[]Comment{}, pagination.Links{}, err
+	}
+
+	// paths have a fixed width per depth, the start of the path of a reply is the path of its paged ancestor
+	width := len((*comments)[0].Path)
+	below := make(map[string][]Comment)
+	for _, reply := range replies {
+		if len(reply.Path) > width {
+			below[reply.Path[:width]] = append(below[reply.Path[:width]], reply)
+		}
+	}
+	kept := make([]Comment, 0, len(replies))
+	for i, comment := range *comments {
+		if under := below[comment.Path]; len(under) > MaxThreadReplies {
+			below[comment.Path] = under[:MaxThreadReplies]
+			(*comments)[i].MoreReplies = repliesCursor(under[MaxThreadReplies-1].Path)
+		}
+		kept = append(kept, below[comment.Path]...)
+	}
+	if err = loadComments(db, kept); err != nil {
+		return &[]Comment{}, pagination.Links{}, err
+	}
+	thread := make([]Comment, 0, len(*comments)+len(kept))
+	for _, comment := range *comments {
+		thread = append(thread, comment)
+		thread = append(thread, kept[:len(below[comment.Path])]...)
+		kept = kept[len(below[comment.Path]):]
+	}
+	return &thread, links, nil
+}
+
+// FindMoreReplies lists the replies under a comment depth first, from a more_replies cursor on. It returns at most
+// MaxThreadReplies of them, with the cursor of the next ones or an empty one when there are no more.
+func (p *Comment) FindMoreReplies(db *gorm.DB, comment Comment, cursor string) (*[]Comment, string, error) {
+	after, err := base64.RawURLEncoding.DecodeString(cursor)
+	// a cursor only makes sense under the comment it was made for
+	if err != nil || !strings.HasPrefix(string(after), comment.Path) || len(after) <= len(comment.Path) {
+		return &[]Comment{}, "", pagination.ErrInvalidCursor
+	}
+	replies := []Comment{}
+	err = underPaths(db.Debug().Model(&Comment{}), []string{comment.Path + "_"}).Where("comments.path > ?", string(after)).
+		Order("comments.path").Limit(MaxThreadReplies + 1).Find(&replies).Error
+	if err != nil {
+		return &[]Comment{}, "", err
+	}
+	next := ""
+	if len(replies) > MaxThreadReplies {
+		replies = replies[:MaxThreadReplies]
+		next = repliesCursor(replies[len(replies)-1].Path)
+	}
+	if err = loadComments(db, replies); err != nil {
+		return &[]Comment{}, "", err
+	}
+	return &replies, next, nil
+}
+
+// repliesCursor points just past the reply with the path, it is handed to clients as an opaque string
+func repliesCursor(path string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(path))
+}
+
+// NestThreads turns a flattened thread into a tree: replies move into Replies of the comment they answer.
+// Comments whose parent is not in the list are the roots of the tree.
+func NestThreads(comments []Comment) []Comment {
+	present := make(map[uint64]bool, len(comments))
+	for _, comment := range comments {
+		present[comment.ID] = true
+	}
+	children := make(map[uint64][]Comment)
+	roots := []Comment{}
+	for _, comment := range comments {
+		if comment.ParentID != nil && present[*comment.ParentID] {
+			children[*comment.ParentID] = append(children[*comment.ParentID], comment)
+		} else {
+			roots = append(roots, comment)
+		}
+	}
+	var nest func(level []Comment) []Comment
+	nest = func(level []Comment) []Comment {
+		for i := range level {
+			level[i].Replies = nest(children[level[i].ID])
+			if level[i].Replies == nil {
+				level[i].Replies = []Comment{}
+			}
+		}
+		return level
+	}
+	return nest(roots)
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// EmailVerification is a single-use token mailed to a new account. Only the digest of the token is stored.
+type EmailVerification struct {
+	ID        uint64     `gorm:"primary_key;auto_increment" json:"id"`
+	UserID    uint32     `gorm:"not null;index" json:"user_id"`
+	Email     string     `gorm:"size:100;not null;" json:"email"`
+	TokenHash string     `gorm:"size:64;not null;unique" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+func (v *EmailVerification) SaveEmailVerification(db *gorm.DB) (*EmailVerification, error) {
+	var err error
+	err = db.Debug().Model(&EmailVerification{}).Create(&v).Error
+	if err != nil {
+		return &EmailVerification{}, err
+	}
+	return v, nil
+}
+
+func (v *EmailVerification) FindEmailVerificationByToken(db *gorm.DB, tokenHash string) (*EmailVerification, error) {
+	var err error
+	err = db.Debug().Model(&EmailVerification{}).Where("token_hash = ?", tokenHash).Take(&v).Error
+	if err != nil {
+		return &EmailVerification{}, err
+	}
+	return v, nil
+}
+
+func (v *EmailVerification) IsExpired() bool {
+	return time.Now().After(v.ExpiresAt)
+}
+
+// MarkUsed consumes the token, it reports false when the token was already used
+func (v *EmailVerification) MarkUsed(db *gorm.DB) (bool, error) {
+	now := time.Now()
+	db = db.Debug().Model(&EmailVerification{}).Where("id = ? AND used_at IS NULL", v.ID).UpdateColumn("used_at", now)
+	if db.Error != nil {
+		return false, db.Error
+	}
+	v.UsedAt = &now
+	return db.RowsAffected == 1, nil
+}
+
+// InvalidateUserVerifications burns the outstanding tokens of a user, so only the latest email works
+func (v *EmailVerification) InvalidateUserVerifications(db *gorm.DB, uid uint32) (int64, error) {
+	db = db.Debug().Model(&EmailVerification{}).Where("user_id = ? AND used_at IS NULL", uid).UpdateColumn("used_at", time.Now())
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return db.RowsAffected, nil
+}
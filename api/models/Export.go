@@ -0,0 +1,93 @@
+package models
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+// exportBatch is how many rows the export reads at a time
+const exportBatch = 100
+
+// UserExport is everything stored about a user, unlike the API lists nothing is capped
+type UserExport struct {
+	User                   User
+	Photos                 []Photo
+	Comments               []Comment
+	SocialMedias           []SocialMedia
+	Following              []User
+	Followers              []User
+	LikedPhotoIDs          []uint64
+	LikedCommentIDs        []uint64
+	NotificationPreference NotificationPreference
+}
+
+// ExportUser collects what the user has stored. Photos and comments are read in batches by id,
+// each batch loaded like the API loads a page.
+func (u *User) ExportUser(db *gorm.DB, uid uint32) (*UserExport, error) {
+	export := UserExport{}
+	if _, err := export.User.FindUserByID(db, uid); err != nil {
+		return &UserExport{}, err
+	}
+
+	for afterID := uint64(0); ; {
+		photos := []Photo{}
+		err := db.Debug().Model(&Photo{}).Where("user_id = ? AND id > ?", uid, afterID).Order("id").Limit(exportBatch).Find(&photos).Error
+		if err != nil {
+			return &UserExport{}, err
+		}
+		if err = loadPhotos(db, photos); err != nil {
+			return &UserExport{}, err
+		}
+		export.Photos = append(export.Photos, photos...)
+		if len(photos) < exportBatch {
+			break
+		}
+		afterID = photos[len(photos)-1].ID
+	}
+
+	for afterID := uint64(0); ; {
+		comments := []Comment{}
+		err := db.Debug().Model(&Comment{}).Where("user_id = ? AND id > ?", uid, afterID).Order("id").Limit(exportBatch).Find(&comments).Error
+		if err != nil {
+			return &UserExport{}, err
+		}
+		if err = loadComments(db, comments); err != nil {
+			return &UserExport{}, err
+		}
+		export.Comments = append(export.Comments, comments...)
+		if len(comments) < exportBatch {
+			break
+		}
+		afterID = comments[len(comments)-1].ID
+	}
+
+	err := db.Debug().Model(&SocialMedia{}).Where("user_id = ?", uid).Order("id").Find(&export.SocialMedias).Error
+	if err != nil {
+		return &UserExport{}, err
+	}
+	if err = loadSocialMedias(db, export.SocialMedias); err != nil {
+		return &UserExport{}, err
+	}
+
+	followees := db.Model(&Follow{}).Select("followee_id").Where("follower_id = ?", uid).QueryExpr()
+	if err = db.Debug().Model(&User{}).Where("id IN (?)", followees).Order("id").Find(&export.Following).Error; err != nil {
+		return &UserExport{}, err
+	}
+	followers := db.Model(&Follow{}).Select("follower_id").Where("followee_id = ?", uid).QueryExpr()
+	if err = db.Debug().Model(&User{}).Where("id IN (?)", followers).Order("id").Find(&export.Followers).Error; err != nil {
+		return &UserExport{}, err
+	}
+
+	if err = db.Debug().Model(&Like{}).Where("user_id = ?", uid).Order("photo_id").Pluck("photo_id", &export.LikedPhotoIDs).Error; err != nil {
+		return &UserExport{}, err
+	}
+	if err = db.Debug().Model(&CommentLike{}).Where("user_id = ?", uid).Order("comment_id").Pluck("comment_id", &export.LikedCommentIDs).Error; err != nil {
+		return &UserExport{}, err
+	}
+
+	preference, err := export.NotificationPreference.FindNotificationPreference(db, uid)
+	if err != nil {
+		return &UserExport{}, err
+	}
+	export.NotificationPreference = *preference
+	return &export, nil
+}
@@ -0,0 +1,134 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/utils/pagination"
+	"github.com/jinzhu/gorm"
+)
+
+// Follow is a user following another one, the unique index makes sure it happens only once
+type Follow struct {
+	ID         uint64    `gorm:"primary_key;auto_increment" json:"id"`
+	FollowerID uint32    `gorm:"not null;unique_index:idx_follows_follower_followee" json:"follower_id"`
+	FolloweeID uint32    `gorm:"not null;unique_index:idx_follows_follower_followee;index" json:"followee_id"`
+	Follower   User      `gorm:"-" json:"follower"`
+	Followee   User      `gorm:"-" json:"followee"`
+	CreatedAt  time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt  time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// FollowSortKeys are the orders the followers and following lists can be listed in, by when the follow happened
+var FollowSortKeys = []pagination.SortKey{
+	{Name: "created_at", Column: "created_at", Kind: pagination.Time},
+	{Name: "id", Column: "id", Kind: pagination.Number},
+}
+
+var (
+	// errSelfFollow and errDoubleFollow are turned into messages by formaterror
+	errSelfFollow   = errors.New("self follow")
+	errDoubleFollow = errors.New("double follow")
+)
+
+func (f *Follow) SaveFollow(db *gorm.DB) (*Follow, error) {
+	if f.FollowerID == f.FolloweeID {
+		return &Follow{}, errSelfFollow
+	}
+	err := db.Debug().Model(&Follow{}).Where("follower_id = ? AND followee_id = ?", f.FollowerID, f.FolloweeID).Take(&Follow{}).Error
+	if err == nil {
+		return &Follow{}, errDoubleFollow
+	}
+	if !gorm.IsRecordNotFoundError(err) {
+		return &Follow{}, err
+	}
+	err = db.Debug().Model(&Follow{}).Create(&f).Error
+	if err != nil {
+		// two requests raced past the check above, the unique index stopped the second one
+		if isUniqueViolation(err) {
+			return &Follow{}, errDoubleFollow
+		}
+		return &Follow{}, err
+	}
+	return f, nil
+}
+
+// DeleteFollow removes the follow, it returns a record not found error when there was none
+func (f *Follow) DeleteFollow(db *gorm.DB) (int64, error) {
+	db = db.Debug().Model(&Follow{}).Where("follower_id = ? AND followee_id = ?", f.FollowerID, f.FolloweeID).Take(&Follow{}).Delete(&Follow{})
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return db.RowsAffected, nil
+}
+
+// FindFollowers pages through the follows of the users following uid, with Follower loaded
+func (f *Follow) FindFollowers(db *gorm.DB, uid uint32, page pagination.Page) (*[]Follow, pagination.Links, error) {
+	return findFollows(db, "followee_id", uid, page, func(f *Follow) (uint32, *User) { return f.FollowerID, &f.Follower })
+}
+
+// FindFollowing pages through the follows of the users uid follows, with Followee loaded
+func (f *Follow) FindFollowing(db *gorm.DB, uid uint32, page pagination.Page) (*[]Follow, pagination.Links, error) {
+	return findFollows(db, "follower_id", uid, page, func(f *Follow) (uint32, *User) { return f.FolloweeID, &f.Followee })
+}
+
+func findFollows(db *gorm.DB, column string, uid uint32, page pagination.Page, other func(*Follow) (uint32, *User)) (*[]Follow, pagination.Links, error) {
+	follows := []Follow{}
+	err := page.Apply(db.Debug().Model(&Follow{}).Where("follows."+column+" = ?", uid), "follows").Find(&follows).Error
+	if err != nil {
+		return &[]Follow{}, pagination.Links{}, err
+	}
+	follows, links := pagination.Window(page, follows, func(row Follow) (interface{}, uint64) {
+		if page.Key.Name == "id" {
+			return row.ID, row.ID
+		}
+		return row.CreatedAt, row.ID
+	})
+	if err = loadUsers(db, follows, other); err != nil {
+		return &[]Follow{}, pagination.Links{}, err
+	}
+	return &follows, links, nil
+}
+
+// FillUserFollows sets FollowerCount, FollowingCount and FollowedByMe of the users, viewerID 0 is an anonymous viewer
+func (f *Follow) FillUserFollows(db *gorm.DB, users []User, viewerID uint32) error {
+	ids := make([]uint64, len(users))
+	for i := range users {
+		ids[i] = uint64(users[i].ID)
+	}
+	followers, err := countBy(db, "follows", "followee_id", ids)
+	if err != nil {
+		return err
+	}
+	following, err := countBy(db, "follows", "follower_id", ids)
+	if err != nil {
+		return err
+	}
+	followed := make(map[uint64]bool)
+	if viewerID != 0 && len(ids) > 0 {
+		var followedIDs []uint64
+		err = db.Debug().Model(&Follow{}).Where("follower_id = ? AND followee_id IN (?)", viewerID, ids).Pluck("followee_id", &followedIDs).Error
+		if err != nil {
+			return err
+		}
+		for _, id := range followedIDs {
+			followed[id] = true
+		}
+	}
+	for i := range users {
+		id := uint64(users[i].ID)
+		users[i].FollowerCount = followers[id]
+		users[i].FollowingCount = following[id]
+		users[i].FollowedByMe = followed[id]
+	}
+	return nil
+}
+
+// When a user is deleted, the follows in both directions go as well
+func (f *Follow) DeleteUserFollows(db *gorm.DB, uid uint32) (int64, error) {
+	db = db.Debug().Where("follower_id = ? OR followee_id = ?", uid, uid).Delete(&Follow{})
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return db.RowsAffected, nil
+}
@@ -0,0 +1,187 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Like is a user liking a photo, the unique index makes sure it happens only once
+type Like struct {
+	ID        uint64    `gorm:"primary_key;auto_increment" json:"id"`
+	UserID    uint32    `gorm:"not null;unique_index:idx_likes_user_photo" json:"user_id"`
+	PhotoID   uint64    `gorm:"not null;unique_index:idx_likes_user_photo;index" json:"photo_id"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// CommentLike is a user liking a comment
+type CommentLike struct {
+	ID        uint64    `gorm:"primary_key;auto_increment" json:"id"`
+	UserID    uint32    `gorm:"not null;unique_index:idx_comment_likes_user_comment" json:"user_id"`
+	CommentID uint64    `gorm:"not null;unique_index:idx_comment_likes_user_comment;index" json:"comment_id"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// errDoubleLike is turned into "You cannot like this post twice" by formaterror
+var errDoubleLike = errors.New("double like")
+
+func (l *Like) SaveLike(db *gorm.DB) (*Like, error) {
+	err := db.Debug().Model(&Like{}).Where("user_id = ? AND photo_id = ?", l.UserID, l.PhotoID).Take(&Like{}).Error
+	if err == nil {
+		return &Like{}, errDoubleLike
+	}
+	if !gorm.IsRecordNotFoundError(err) {
+		return &Like{}, err
+	}
+	err = db.Debug().Model(&Like{}).Create(&l).Error
+	if err != nil {
+		// two requests raced past the check above, the unique index stopped the second one
+		if isUniqueViolation(err) {
+			return &Like{}, errDoubleLike
+		}
+		return &Like{}, err
+	}
+	return l, nil
+}
+
+// DeleteLike removes the like of the user, it returns a record not found error when there was none
+func (l *Like) DeleteLike(db *gorm.DB) (int64, error) {
+	db = db.Debug().Model(&Like{}).Where("user_id = ? AND photo_id = ?", l.UserID, l.PhotoID).Take(&Like{}).Delete(&Like{})
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return db.RowsAffected, nil
+}
+
+// FillPhotoLikes sets LikeCount and LikedByMe of the photos with two queries, viewerID 0 is an anonymous viewer
+func (l *Like) FillPhotoLikes(db *gorm.DB, photos []Photo, viewerID uint32) error {
+	ids := make([]uint64, len(photos))
+	for i := range photos {
+		ids[i] = photos[i].ID
+	}
+	counts, liked, err := countLikes(db, "likes", "photo_id", ids, viewerID)
+	if err != nil {
+		return err
+	}
+	for i := range photos {
+		photos[i].LikeCount = counts[photos[i].ID]
+		photos[i].LikedByMe = liked[photos[i].ID]
+	}
+	return nil
+}
+
+func (l *Like) DeletePhotoLikes(db *gorm.DB, pid uint64) (int64, error) {
+	db = db.Debug().Where("photo_id = ?", pid).Delete(&Like{})
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return db.RowsAffected, nil
+}
+
+// When a user is deleted, their likes and the likes on their photos go as well
+func (l *Like) DeleteUserLikes(db *gorm.DB, uid uint32) (int64, error) {
+	db = db.Debug().Where("user_id = ? OR photo_id IN (?)", uid, db.Model(&Photo{}).Select("id").Where("user_id = ?", uid).QueryExpr()).Delete(&Like{})
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return db.RowsAffected, nil
+}
+
+func (l *CommentLike) SaveCommentLike(db *gorm.DB) (*CommentLike, error) {
+	err := db.Debug().Model(&CommentLike{}).Where("user_id = ? AND comment_id = ?", l.UserID, l.CommentID).Take(&CommentLike{}).Error
+	if err == nil {
+		return &CommentLike{}, errDoubleLike
+	}
+	if !gorm.IsRecordNotFoundError(err) {
+		return &CommentLike{}, err
+	}
+	err = db.Debug().Model(&CommentLike{}).Create(&l).Error
+	if err != nil {
+		if isUniqueViolation(err) {
+			return &CommentLike{}, errDoubleLike
+		}
+		return &CommentLike{}, err
+	}
+	return l, nil
+}
+
+func (l *CommentLike) DeleteCommentLike(db *gorm.DB) (int64, error) {
+	db = db.Debug().Model(&CommentLike{}).Where("user_id = ? AND comment_id = ?", l.UserID, l.CommentID).Take(&CommentLike{}).Delete(&CommentLike{})
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return db.RowsAffected, nil
+}
+
+// FillCommentLikes sets LikeCount and LikedByMe of the comments, viewerID 0 is an anonymous viewer
+func (l *CommentLike) FillCommentLikes(db *gorm.DB, comments []Comment, viewerID uint32) error {
+	ids := make([]uint64, len(comments))
+	for i := range comments {
+		ids[i] = comments[i].ID
+	}
+	counts, liked, err := countLikes(db, "comment_likes", "comment_id", ids, viewerID)
+	if err != nil {
+		return err
+	}
+	for i := range comments {
+		comments[i].LikeCount = counts[comments[i].ID]
+		comments[i].LikedByMe = liked[comments[i].ID]
+	}
+	return nil
+}
+
+func (l *CommentLike) DeleteCommentLikes(db *gorm.DB, cid uint64) (int64, error) {
+	db = db.Debug().Where("comment_id = ?", cid).Delete(&CommentLike{})
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return db.RowsAffected, nil
+}
+
+// When a user is deleted, their comment likes go, and so do the likes on comments that are deleted with them:
+// their own comments and every comment on their photos
+func (l *CommentLike) DeleteUserCommentLikes(db *gorm.DB, uid uint32) (int64, error) {
+	userPhotos := db.Model(&Photo{}).Select("id").Where("user_id = ?", uid).QueryExpr()
+	deletedComments := db.Model(&Comment{}).Select("id").Where("user_id = ? OR photo_id IN (?)", uid, userPhotos).QueryExpr()
+	db = db.Debug().Where("user_id = ? OR comment_id IN (?)", uid, deletedComments).Delete(&CommentLike{})
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return db.RowsAffected, nil
+}
+
+// countLikes counts the likes per id and reports which ids the viewer liked
+func countLikes(db *gorm.DB, table, column string, ids []uint64, viewerID uint32) (map[uint64]int64, map[uint64]bool, error) {
+	liked := make(map[uint64]bool)
+	if len(ids) == 0 {
+		return make(map[uint64]int64), liked, nil
+	}
+
+	counts, err := countBy(db, table, column, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if viewerID == 0 {
+		return counts, liked, nil
+	}
+	var likedIDs []uint64
+	err = db.Debug().Table(table).Where("user_id = ? AND "+column+" IN (?)", viewerID, ids).Pluck(column, &likedIDs).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, id := range likedIDs {
+		liked[id] = true
+	}
+	return counts, liked, nil
+}
+
+// isUniqueViolation recognises the duplicate key errors of postgres and mysql
+func isUniqueViolation(err error) bool {
+	message := strings.ToLower(err.Error())
+	return strings.Contains(message, "duplicate") || strings.Contains(message, "unique")
+}
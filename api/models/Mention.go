@@ -0,0 +1,180 @@
+package models
+
+import (
+	"time"
+
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/utils/mention"
+	"github.com/jinzhu/gorm"
+)
+
+// Mention is an @username of a caption or a comment that matched a user. Comment mentions carry
+// the photo of the comment as well, so everything said on a photo goes with it.
+// Start and End are offsets in characters into the text as it is stored and returned, see mention.Entity.
+type Mention struct {
+	ID        uint64    `gorm:"primary_key;auto_increment" json:"id"`
+	UserID    uint32    `gorm:"not null;index" json:"user_id"`
+	AuthorID  uint32    `gorm:"not null;index" json:"author_id"`
+	PhotoID   uint64    `gorm:"not null;index" json:"photo_id"`
+	CommentID *uint64   `gorm:"index" json:"comment_id"`
+	Start     int       `gorm:"column:start_offset;not null" json:"start"`
+	End       int       `gorm:"column:end_offset;not null" json:"end"`
+	User      User      `gorm:"-" json:"user"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+
+	// New is set by the sync on the first mention of a user who was not mentioned before the edit,
+	// those are the users to notify
+	New bool `gorm:"-" json:"-"`
+}
+
+// SyncPhotoMentions makes the mentions of the photo match its caption
+func (m *Mention) SyncPhotoMentions(db *gorm.DB, photo Photo) ([]Mention, error) {
+	scope := db.Where("photo_id = ? AND comment_id IS NULL", photo.ID)
+	return syncMentions(db, scope, Mention{AuthorID: photo.UserID, PhotoID: photo.ID}, photo.Caption)
+}
+
+// SyncCommentMentions makes the mentions of the comment match its message
+func (m *Mention) SyncCommentMentions(db *gorm.DB, comment Comment) ([]Mention, error) {
+	id := comment.ID
+	scope := db.Where("comment_id = ?", id)
+	return syncMentions(db, scope, Mention{AuthorID: comment.UserID, PhotoID: comment.PhotoID, CommentID: &id}, comment.Message)
+}
+
+// syncMentions replaces the mentions selected by scope with those of text, base holds the fields shared by all of them.
+// Usernames are matched exactly.
+func syncMentions(db *gorm.DB, scope *gorm.DB, base Mention, text string) ([]Mention, error) {
+	var before []uint32
+	if err := scope.Debug().Model(&Mention{}).Pluck("user_id", &before).Error; err != nil {
+		return nil, err
+	}
+	if err := scope.Debug().Delete(&Mention{}).Error; err != nil {
+		return nil, err
+	}
+
+	mentions := []Mention{}
+	entities := mention.Parse(text)
+	if len(entities) == 0 {
+		return mentions, nil
+	}
+	users := []User{}
+	if err := db.Debug().Model(&User{}).Where("username IN (?)", mention.Usernames(entities)).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	byName := make(map[string]User, len(users))
+	for _, user := range users {
+		byName[user.Username] = user
+	}
+	seen := make(map[uint32]bool)
+	for _, id := range before {
+		seen[id] = true
+	}
+	for _, entity := range entities {
+		user, ok := byName[entity.Username]
+		if !ok {
+			continue
+		}
+		m := base
+		m.ID = 0
+		m.UserID = user.ID
+		m.Start = entity.Start
+		m.End = entity.End
+		m.CreatedAt = time.Now()
+		if err := db.Debug().Create(&m).Error; err != nil {
+			return nil, err
+		}
+		m.User = user
+		m.New = !seen[user.ID]
+		seen[user.ID] = true
+		mentions = append(mentions, m)
+	}
+	return mentions, nil
+}
+
+// DeletePhotoMentions deletes the mentions of the caption of a photo and of its comments
+func (m *Mention) DeletePhotoMentions(db *gorm.DB, pid uint64) (int64, error) {
+	db = db.Debug().Where("photo_id = ?", pid).Delete(&Mention{})
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return db.RowsAffected, nil
+}
+
+func (m *Mention) DeleteCommentMentions(db *gorm.DB, ids []uint64) (int64, error) {
+	db = db.Debug().Where("comment_id IN (?)", ids).Delete(&Mention{})
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return db.RowsAffected, nil
+}
+
+// When a user is deleted, the mentions of them and those of their captions and comments go,
+// along with the mentions made on their photos
+func (m *Mention) DeleteUserMentions(db *gorm.DB, uid uint32) (int64, error) {
+	photos := db.Model(&Photo{}).Select("id").Where("user_id = ?", uid).QueryExpr()
+	db = db.Debug().Where("user_id = ? OR author_id = ? OR photo_id IN (?)", uid, uid, photos).Delete(&Mention{})
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return db.RowsAffected, nil
+}
+
+// loadPhotoMentions fills in the mentions of the captions of photos, with their users
+func loadPhotoMentions(db *gorm.DB, photos []Photo) error {
+	ids := make([]uint64, len(photos))
+	for i := range photos {
+		ids[i] = photos[i].ID
+	}
+	mentions, err := findMentions(db, db.Where("photo_id IN (?) AND comment_id IS NULL", ids), len(ids))
+	if err != nil {
+		return err
+	}
+	byPhoto := make(map[uint64][]Mention)
+	for _, m := range mentions {
+		byPhoto[m.PhotoID] = append(byPhoto[m.PhotoID], m)
+	}
+	for i := range photos {
+		photos[i].Mentions = byPhoto[photos[i].ID]
+		if photos[i].Mentions == nil {
+			photos[i].Mentions = []Mention{}
+		}
+	}
+	return nil
+}
+
+// loadCommentMentions fills in the mentions of comments, with their users
+func loadCommentMentions(db *gorm.DB, comments []Comment) error {
+	ids := make([]uint64, len(comments))
+	for i := range comments {
+		ids[i] = comments[i].ID
+	}
+	mentions, err := findMentions(db, db.Where("comment_id IN (?)", ids), len(ids))
+	if err != nil {
+		return err
+	}
+	byComment := make(map[uint64][]Mention)
+	for _, m := range mentions {
+		byComment[*m.CommentID] = append(byComment[*m.CommentID], m)
+	}
+	for i := range comments {
+		comments[i].Mentions = byComment[comments[i].ID]
+		if comments[i].Mentions == nil {
+			comments[i].Mentions = []Mention{}
+		}
+	}
+	return nil
+}
+
+// findMentions loads the mentions selected by scope in text order, n is the number of texts asked for
+func findMentions(db *gorm.DB, scope *gorm.DB, n int) ([]Mention, error) {
+	mentions := []Mention{}
+	if n == 0 {
+		return mentions, nil
+	}
+	if err := scope.Debug().Model(&Mention{}).Order("start_offset").Find(&mentions).Error; err != nil {
+		return nil, err
+	}
+	// a mentioned user is deleted along with their mentions, so none can be missing
+	if err := loadUsers(db, mentions, func(m *Mention) (uint32, *User) { return m.UserID, &m.User }); err != nil {
+		return nil, err
+	}
+	return mentions, nil
+}
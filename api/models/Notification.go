@@ -0,0 +1,166 @@
+package models
+
+import (
+	"time"
+
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/utils/pagination"
+	"github.com/jinzhu/gorm"
+)
+
+// The kinds of notification, each one can be turned off in the NotificationPreference of the user
+const (
+	// NotificationComment tells the owner of a photo about a new comment on it
+	NotificationComment = "comment"
+	// NotificationReply tells the author of a comment about a reply to it
+	NotificationReply = "reply"
+	// NotificationMention tells a user they were mentioned in a caption or a comment
+	NotificationMention = "mention"
+	// NotificationFollow tells a user about a new follower
+	NotificationFollow = "follow"
+	// NotificationLike tells the owner of a photo or the author of a comment about a like
+	NotificationLike = "like"
+)
+
+// NotificationKinds lists every kind of notification
+var NotificationKinds = []string{NotificationComment, NotificationReply, NotificationMention, NotificationFollow, NotificationLike}
+
+// Notification tells a user about something another user (the actor) did. PhotoID and CommentID
+// point at what it is about, when there is one.
+type Notification struct {
+	ID        uint64     `gorm:"primary_key;auto_increment" json:"id"`
+	UserID    uint32     `gorm:"not null;index" json:"user_id"`
+	ActorID   uint32     `gorm:"not null;index" json:"actor_id"`
+	Actor     User       `gorm:"-" json:"actor"`
+	Kind      string     `gorm:"size:20;not null" json:"kind"`
+	PhotoID   *uint64    `gorm:"index" json:"photo_id"`
+	CommentID *uint64    `gorm:"index" json:"comment_id"`
+	ReadAt    *time.Time `json:"read_at"`
+	CreatedAt time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// NotificationSortKeys are the orders GET /notifications can be listed in
+var NotificationSortKeys = []pagination.SortKey{
+	{Name: "created_at", Column: "created_at", Kind: pagination.Time},
+	{Name: "id", Column: "id", Kind: pagination.Number},
+}
+
+func (n *Notification) SaveNotification(db *gorm.DB) (*Notification, error) {
+	n.CreatedAt = time.Now()
+	err := db.Debug().Model(&Notification{}).Create(&n).Error
+	if err != nil {
+		return &Notification{}, err
+	}
+	return n, nil
+}
+
+// FindUnreadDuplicate reports whether the recipient has an unread notification just like n, so liking,
+// unliking and liking again does not notify twice
+func (n *Notification) FindUnreadDuplicate(db *gorm.DB) (bool, error) {
+	query := db.Debug().Model(&Notification{}).Where("user_id = ? AND actor_id = ? AND kind = ? AND read_at IS NULL", n.UserID, n.ActorID, n.Kind)
+	if n.PhotoID != nil {
+		query = query.Where("photo_id = ?", *n.PhotoID)
+	} else {
+		query = query.Where("photo_id IS NULL")
+	}
+	if n.CommentID != nil {
+		query = query.Where("comment_id = ?", *n.CommentID)
+	} else {
+		query = query.Where("comment_id IS NULL")
+	}
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// FindNotifications pages through the notifications of uid, with Actor loaded. unreadOnly leaves out those already read
+func (n *Notification) FindNotifications(db *gorm.DB, uid uint32, unreadOnly bool, page pagination.Page) (*[]Notification, pagination.Links, error) {
+	notifications := []Notification{}
+	query := db.Debug().Model(&Notification{}).Where("notifications.user_id = ?", uid)
+	if unreadOnly {
+		query = query.Where("notifications.read_at IS NULL")
+	}
+	err := page.Apply(query, "notifications").Find(&notifications).Error
+	if err != nil {
+		return &[]Notification{}, pagination.Links{}, err
+	}
+	notifications, links := pagination.Window(page, notifications, func(row Notification) (interface{}, uint64) {
+		if page.Key.Name == "id" {
+			return row.ID, row.ID
+		}
+		return row.CreatedAt, row.ID
+	})
+	if err = loadUsers(db, notifications, func(n *Notification) (uint32, *User) { return n.ActorID, &n.Actor }); err != nil {
+		return &[]Notification{}, pagination.Links{}, err
+	}
+	return &notifications, links, nil
+}
+
+// CountUnread counts the unread notifications of uid per kind
+func (n *Notification) CountUnread(db *gorm.DB, uid uint32) (map[string]int64, error) {
+	counts := make(map[string]int64)
+	rows, err := db.Debug().Model(&Notification{}).Select("kind, COUNT(*)").Where("user_id = ? AND read_at IS NULL", uid).Group("kind").Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var kind string
+		var count int64
+		if err = rows.Scan(&kind, &count); err != nil {
+			return nil, err
+		}
+		counts[kind] = count
+	}
+	return counts, rows.Err()
+}
+
+// MarkRead marks a notification of uid as read, it returns a record not found error when uid has no such notification
+func (n *Notification) MarkRead(db *gorm.DB, uid uint32, id uint64) (int64, error) {
+	err := db.Debug().Model(&Notification{}).Where("id = ? AND user_id = ?", id, uid).Take(&Notification{}).Error
+	if err != nil {
+		return 0, err
+	}
+	db = db.Debug().Model(&Notification{}).Where("id = ? AND read_at IS NULL", id).UpdateColumn("read_at", time.Now())
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return db.RowsAffected, nil
+}
+
+// MarkAllRead marks every notification of uid as read
+func (n *Notification) MarkAllRead(db *gorm.DB, uid uint32) (int64, error) {
+	db = db.Debug().Model(&Notification{}).Where("user_id = ? AND read_at IS NULL", uid).UpdateColumn("read_at", time.Now())
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return db.RowsAffected, nil
+}
+
+// DeletePhotoNotifications deletes the notifications about a photo and its comments
+func (n *Notification) DeletePhotoNotifications(db *gorm.DB, pid uint64) (int64, error) {
+	db = db.Debug().Where("photo_id = ?", pid).Delete(&Notification{})
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return db.RowsAffected, nil
+}
+
+func (n *Notification) DeleteCommentNotifications(db *gorm.DB, ids []uint64) (int64, error) {
+	db = db.Debug().Where("comment_id IN (?)", ids).Delete(&Notification{})
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return db.RowsAffected, nil
+}
+
+// When a user is deleted, the notifications they received or caused go, and those about their photos
+func (n *Notification) DeleteUserNotifications(db *gorm.DB, uid uint32) (int64, error) {
+	photos := db.Model(&Photo{}).Select("id").Where("user_id = ?", uid).QueryExpr()
+	db = db.Debug().Where("user_id = ? OR actor_id = ? OR photo_id IN (?)", uid, uid, photos).Delete(&Notification{})
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return db.RowsAffected, nil
+}
@@ -0,0 +1,125 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// NotificationPreference holds the kinds of notification a user wants. A user without a row gets every kind,
+// see DefaultNotificationPreference. The columns have no default so that false is written on insert,
+// and are prefixed as "like" is an SQL keyword.
+type NotificationPreference struct {
+	UserID    uint32    `gorm:"primary_key;auto_increment:false" json:"user_id"`
+	Comment   bool      `gorm:"column:on_comment;not null" json:"comment"`
+	Reply     bool      `gorm:"column:on_reply;not null" json:"reply"`
+	Mention   bool      `gorm:"column:on_mention;not null" json:"mention"`
+	Follow    bool      `gorm:"column:on_follow;not null" json:"follow"`
+	Like      bool      `gorm:"column:on_like;not null" json:"like"`
+	UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// UpdateNotificationPreference changes some of the preferences, the fields left out keep their value
+type UpdateNotificationPreference struct {
+	Comment *bool `json:"comment" example:"true"`
+	Reply   *bool `json:"reply" example:"true"`
+	Mention *bool `json:"mention" example:"true"`
+	Follow  *bool `json:"follow" example:"true"`
+	Like    *bool `json:"like" example:"false"`
+}
+
+func DefaultNotificationPreference(uid uint32) NotificationPreference {
+	return NotificationPreference{UserID: uid, Comment: true, Reply: true, Mention: true, Follow: true, Like: true}
+}
+
+// Allows reports whether the user wants notifications of the kind
+func (p *NotificationPreference) Allows(kind string) bool {
+	switch kind {
+	case NotificationComment:
+		return p.Comment
+	case NotificationReply:
+		return p.Reply
+	case NotificationMention:
+		return p.Mention
+	case NotificationFollow:
+		return p.Follow
+	case NotificationLike:
+		return p.Like
+	}
+	return true
+}
+
+// Apply sets the preferences given in u
+func (u *UpdateNotificationPreference) Apply(p *NotificationPreference) {
+	for _, field := range []struct {
+		value *bool
+		dst   *bool
+	}{{u.Comment, &p.Comment}, {u.Reply, &p.Reply}, {u.Mention, &p.Mention}, {u.Follow, &p.Follow}, {u.Like, &p.Like}} {
+		if field.value != nil {
+			*field.dst = *field.value
+		}
+	}
+}
+
+// FindNotificationPreference returns the preferences of uid, the defaults when they never changed them
+func (p *NotificationPreference) FindNotificationPreference(db *gorm.DB, uid uint32) (*NotificationPreference, error) {
+	prefs, err := p.FindNotificationPreferences(db, []uint32{uid})
+	if err != nil {
+		return &NotificationPreference{}, err
+	}
+	pref := prefs[uid]
+	return &pref, nil
+}
+
+// FindNotificationPreferences returns the preferences of the users with a single query
+func (p *NotificationPreference) FindNotificationPreferences(db *gorm.DB, uids []uint32) (map[uint32]NotificationPreference, error) {
+	prefs := make(map[uint32]NotificationPreference, len(uids))
+	for _, uid := range uids {
+		prefs[uid] = DefaultNotificationPreference(uid)
+	}
+	if len(uids) == 0 {
+		return prefs, nil
+	}
+	stored := []NotificationPreference{}
+	if err := db.Debug().Model(&NotificationPreference{}).Where("user_id IN (?)", uids).Find(&stored).Error; err != nil {
+		return nil, err
+	}
+	for _, pref := range stored {
+		prefs[pref.UserID] = pref
+	}
+	return prefs, nil
+}
+
+// SaveNotificationPreference stores the preferences, creating the row of the user the first time
+func (p *NotificationPreference) SaveNotificationPreference(db *gorm.DB) (*NotificationPreference, error) {
+	p.UpdatedAt = time.Now()
+	var count int64
+	err := db.Debug().Model(&NotificationPreference{}).Where("user_id = ?", p.UserID).Count(&count).Error
+	if err != nil {
+		return &NotificationPreference{}, err
+	}
+	if count == 0 {
+		err = db.Debug().Model(&NotificationPreference{}).Create(&p).Error
+		if err != nil && isUniqueViolation(err) {
+			// created by a request running alongside, update it instead
+			count = 1
+		} else if err != nil {
+			return &NotificationPreference{}, err
+		}
+	}
+	if count > 0 {
+		// a map, so the false values are written as well
+		err = db.Debug().Model(&NotificationPreference{}).Where("user_id = ?", p.UserID).UpdateColumns(map[string]interface{}{
+			"on_comment": p.Comment,
+			"on_reply":   p.Reply,
+			"on_mention": p.Mention,
+			"on_follow":  p.Follow,
+			"on_like":    p.Like,
+			"updated_at": p.UpdatedAt,
+		}).Error
+		if err != nil {
+			return &NotificationPreference{}, err
+		}
+	}
+	return p, nil
+}
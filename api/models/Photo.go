@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/utils/pagination"
 	"github.com/jinzhu/gorm"
 )
 
@@ -18,6 +19,35 @@ type Photo struct {
 	UserID    uint32    `gorm:"not null" json:"user_id"`
 	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
 	UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+
+	// set for photos uploaded to our storage, empty when the client sent a photo_url
+	StorageKey string `gorm:"size:255" json:"storage_key"`
+	MimeType   string `gorm:"size:50" json:"mime_type"`
+	Width      int    `json:"width"`
+	Height     int    `json:"height"`
+	ByteSize   int64  `json:"byte_size"`
+
+	// safe EXIF fields, GPS and serial numbers are stripped before the file is stored
+	TakenAt     *time.Time `json:"taken_at"`
+	CameraModel string     `gorm:"size:100" json:"camera_model"`
+	Orientation int        `json:"orientation"`
+
+	// resized copies, generated in the background after an upload
+	Variants []PhotoVariant `gorm:"-" json:"variants"`
+	// why the variants cannot be generated, set when the stored file does not decode
+	VariantsError string `gorm:"size:255;not null;default:''" json:"-"`
+
+	// filled in per viewer by Like.FillPhotoLikes
+	LikeCount int64 `gorm:"-" json:"like_count"`
+	LikedByMe bool  `gorm:"-" json:"liked_by_me"`
+
+	CommentCount int64 `gorm:"-" json:"comment_count"`
+	// the hashtags of the caption, kept in sync by SavePhoto and UpdateAPhoto
+	Tags []string `gorm:"-" json:"tags"`
+	// the users mentioned in the caption, kept in sync by SavePhoto and UpdateAPhoto
+	Mentions []Mention `gorm:"-" json:"mentions"`
+	// the first comments of the photo, only loaded when asked for with Comment.FindFirstComments
+	Comments []Comment `gorm:"-" json:"comments"`
 }
 
 type CreatePhoto struct {
@@ -32,6 +62,11 @@ type UpdatePhoto struct {
 	PhotoURL string `json:"photo_url"  binding:"required" example:"https://media.istockphoto.com/id/1322123064/photo/portrait-of-an-adorable-white-cat-in-sunglasses-and-an-shirt-lies-on-a-fabric-hammock.jpg?s=612x612&w=0&k=20&c=-G6l2c4jNI0y4cenh-t3qxvIQzVCOqOYZNvrRA7ZU5o="`
 }
 
+// IsUploaded reports whether the image is kept in our storage
+func (p *Photo) IsUploaded() bool {
+	return p.StorageKey != ""
+}
+
 func (p *Photo) Prepare() {
 	p.Title = html.EscapeString(strings.TrimSpace(p.Title))
 	p.Caption = html.EscapeString(strings.TrimSpace(p.Caption))
@@ -73,6 +108,14 @@ func (p *Photo) SavePhoto(db *gorm.DB) (*Photo, error) {
 	if err != nil {
 		return &Photo{}, err
 	}
+	tag := Tag{}
+	if err = tag.SyncPhotoTags(db, *p); err != nil {
+		return &Photo{}, err
+	}
+	mention := Mention{}
+	if p.Mentions, err = mention.SyncPhotoMentions(db, *p); err != nil {
+		return &Photo{}, err
+	}
 	if p.ID != 0 {
 		err = db.Debug().Model(&User{}).Where("id = ?", p.UserID).Take(&p.User).Error
 		if err != nil {
@@ -82,22 +125,44 @@ func (p *Photo) SavePhoto(db *gorm.DB) (*Photo, error) {
 	return p, nil
 }
 
-func (p *Photo) FindAllPhotos(db *gorm.DB) (*[]Photo, error) {
+// PhotoSortKeys are the orders GET /photos can be listed in
+var PhotoSortKeys = []pagination.SortKey{
+	{Name: "created_at", Column: "created_at", Kind: pagination.Time},
+	{Name: "id", Column: "id", Kind: pagination.Number},
+	{Name: "title", Column: "title", Kind: pagination.String},
+}
+
+func (p *Photo) FindAllPhotos(db *gorm.DB, page pagination.Page, filter pagination.Filter) (*[]Photo, pagination.Links, error) {
+	return findPhotos(db, db.Debug().Model(&Photo{}), page, filter)
+}
+
+// FindTagPhotos lists the photos carrying a tag, paged like FindAllPhotos
+func (p *Photo) FindTagPhotos(db *gorm.DB, name string, page pagination.Page, filter pagination.Filter) (*[]Photo, pagination.Links, error) {
+	tagged := db.Model(&PhotoTag{}).Select("photo_tags.photo_id").Joins("JOIN tags ON tags.id = photo_tags.tag_id").Where("tags.name = ?", name).QueryExpr()
+	return findPhotos(db, db.Debug().Model(&Photo{}).Where("photos.id IN (?)", tagged), page, filter)
+}
+
+// findPhotos pages through the photos selected by query, db loads their associations
+func findPhotos(db *gorm.DB, query *gorm.DB, page pagination.Page, filter pagination.Filter) (*[]Photo, pagination.Links, error) {
 	var err error
 	photos := []Photo{}
-	err = db.Debug().Model(&Photo{}).Limit(100).Order("created_at desc").Find(&photos).Error
+	err = page.Apply(filter.Apply(query, "photos"), "photos").Find(&photos).Error
 	if err != nil {
-		return &[]Photo{}, err
+		return &[]Photo{}, pagination.Links{}, err
 	}
-	if len(photos) > 0 {
-		for i, _ := range photos {
-			err := db.Debug().Model(&User{}).Where("id = ?", photos[i].UserID).Take(&photos[i].User).Error
-			if err != nil {
-				return &[]Photo{}, err
-			}
+	photos, links := pagination.Window(page, photos, func(row Photo) (interface{}, uint64) {
+		switch page.Key.Name {
+		case "title":
+			return row.Title, row.ID
+		case "id":
+			return row.ID, row.ID
 		}
+		return row.CreatedAt, row.ID
+	})
+	if err = loadPhotos(db, photos); err != nil {
+		return &[]Photo{}, pagination.Links{}, err
 	}
-	return &photos, nil
+	return &photos, links, nil
 }
 
 func (p *Photo) FindPhotoByID(db *gorm.DB, pid uint64) (*Photo, error) {
@@ -106,12 +171,11 @@ func (p *Photo) FindPhotoByID(db *gorm.DB, pid uint64) (*Photo, error) {
 	if err != nil {
 		return &Photo{}, err
 	}
-	if p.ID != 0 {
-		err = db.Debug().Model(&User{}).Where("id = ?", p.UserID).Take(&p.User).Error
-		if err != nil {
-			return &Photo{}, err
-		}
+	photos := []Photo{*p}
+	if err = loadPhotos(db, photos); err != nil {
+		return &Photo{}, err
 	}
+	*p = photos[0]
 	return p, nil
 }
 
@@ -123,6 +187,14 @@ func (p *Photo) UpdateAPhoto(db *gorm.DB) (*Photo, error) {
 	if err != nil {
 		return &Photo{}, err
 	}
+	tag := Tag{}
+	if err = tag.SyncPhotoTags(db, *p); err != nil {
+		return &Photo{}, err
+	}
+	mention := Mention{}
+	if p.Mentions, err = mention.SyncPhotoMentions(db, *p); err != nil {
+		return &Photo{}, err
+	}
 	if p.ID != 0 {
 		err = db.Debug().Model(&User{}).Where("id = ?", p.UserID).Take(&p.User).Error
 		if err != nil {
@@ -134,6 +206,30 @@ func (p *Photo) UpdateAPhoto(db *gorm.DB) (*Photo, error) {
 
 func (p *Photo) DeleteAPhoto(db *gorm.DB) (int64, error) {
 
+	variant := PhotoVariant{}
+	if _, err := variant.DeletePhotoVariants(db, p.ID); err != nil {
+		return 0, err
+	}
+	like := Like{}
+	if _, err := like.DeletePhotoLikes(db, p.ID); err != nil {
+		return 0, err
+	}
+	entry := TimelineEntry{}
+	if _, err := entry.DeletePhotoEntries(db, p.ID); err != nil {
+		return 0, err
+	}
+	tag := Tag{}
+	if _, err := tag.DeletePhotoTags(db, p.ID); err != nil {
+		return 0, err
+	}
+	mention := Mention{}
+	if _, err := mention.DeletePhotoMentions(db, p.ID); err != nil {
+		return 0, err
+	}
+	notification := Notification{}
+	if _, err := notification.DeletePhotoNotifications(db, p.ID); err != nil {
+		return 0, err
+	}
 	db = db.Debug().Model(&Photo{}).Where("id = ?", p.ID).Take(&Photo{}).Delete(&Photo{})
 	if db.Error != nil {
 		return 0, db.Error
@@ -149,19 +245,83 @@ func (p *Photo) FindUserPhotos(db *gorm.DB, uid uint32) (*[]Photo, error) {
 	if err != nil {
 		return &[]Photo{}, err
 	}
-	if len(photos) > 0 {
-		for i, _ := range photos {
-			err := db.Debug().Model(&User{}).Where("id = ?", photos[i].UserID).Take(&photos[i].User).Error
-			if err != nil {
-				return &[]Photo{}, err
-			}
-		}
+	if err = loadPhotos(db, photos); err != nil {
+		return &[]Photo{}, err
 	}
 	return &photos, nil
 }
 
+// FindUserStorageKeys lists the stored files of a user, so they can be removed along with the account
+func (p *Photo) FindUserStorageKeys(db *gorm.DB, uid uint32) ([]string, error) {
+	var keys, variantKeys []string
+	err := db.Debug().Model(&Photo{}).Where("user_id = ? AND storage_key <> ''", uid).Pluck("storage_key", &keys).Error
+	if err != nil {
+		return nil, err
+	}
+	err = db.Debug().Model(&PhotoVariant{}).Where("photo_id IN (?)", db.Model(&Photo{}).Select("id").Where("user_id = ?", uid).QueryExpr()).Pluck("storage_key", &variantKeys).Error
+	if err != nil {
+		return nil, err
+	}
+	return append(keys, variantKeys...), nil
+}
+
+// StorageKeys lists the stored files of the photo and its variants
+func (p *Photo) StorageKeys() []string {
+	var keys []string
+	if p.StorageKey != "" {
+		keys = append(keys, p.StorageKey)
+	}
+	for _, variant := range p.Variants {
+		keys = append(keys, variant.StorageKey)
+	}
+	return keys
+}
+
+// FindPhotosMissingVariants lists uploaded photos, ordered by id and starting after afterID,
+// that have fewer than the expected number of variants. Photos with a VariantsError are left out.
+func (p *Photo) FindPhotosMissingVariants(db *gorm.DB, afterID uint64, expected int, limit int) ([]Photo, error) {
+	photos := []Photo{}
+	err := db.Debug().Model(&Photo{}).
+		Where("id > ? AND storage_key <> '' AND variants_error = ''", afterID).
+		Where("(SELECT COUNT(*) FROM photo_variants WHERE photo_variants.photo_id = photos.id) < ?", expected).
+		Order("id").Limit(limit).Find(&photos).Error
+	if err != nil {
+		return nil, err
+	}
+	return photos, nil
+}
+
+// SetVariantsError records why the variants of the photo cannot be generated, an empty message clears it
+func (p *Photo) SetVariantsError(db *gorm.DB, message string) error {
+	if len(message) > 255 {
+		message = message[:255]
+	}
+	return db.Debug().Model(&Photo{}).Where("id = ?", p.ID).UpdateColumn("variants_error", message).Error
+}
+
+// loadPhotoVariants fills in the variants of the photos with a single query
+func loadPhotoVariants(db *gorm.DB, photos []Photo) error {
+	ids := make([]uint64, len(photos))
+	for i := range photos {
+		ids[i] = photos[i].ID
+	}
+	variant := PhotoVariant{}
+	byPhoto, err := variant.FindPhotoVariants(db, ids)
+	if err != nil {
+		return err
+	}
+	for i := range photos {
+		photos[i].Variants = byPhoto[photos[i].ID]
+	}
+	return nil
+}
+
 // When a user is deleted, we also delete the photo that the user had
 func (c *Photo) DeleteUserPhotos(db *gorm.DB, uid uint32) (int64, error) {
+	err := db.Debug().Where("photo_id IN (?)", db.Model(&Photo{}).Select("id").Where("user_id = ?", uid).QueryExpr()).Delete(&PhotoVariant{}).Error
+	if err != nil {
+		return 0, err
+	}
 	photos := []Photo{}
 	db = db.Debug().Model(&Photo{}).Where("user_id = ?", uid).Find(&photos).Delete(&photos)
 	if db.Error != nil {
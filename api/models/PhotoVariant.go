@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// PhotoVariant is a resized copy of an uploaded photo, e.g. its thumbnail
+type PhotoVariant struct {
+	ID         uint64    `gorm:"primary_key;auto_increment" json:"id"`
+	PhotoID    uint64    `gorm:"not null;unique_index:idx_photo_variants_photo_name" json:"photo_id"`
+	Name       string    `gorm:"size:20;not null;unique_index:idx_photo_variants_photo_name" json:"name"`
+	StorageKey string    `gorm:"size:255;not null" json:"storage_key"`
+	URL        string    `gorm:"size:255;not null" json:"url"`
+	MimeType   string    `gorm:"size:50" json:"mime_type"`
+	Width      int       `json:"width"`
+	Height     int       `json:"height"`
+	ByteSize   int64     `json:"byte_size"`
+	CreatedAt  time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// SavePhotoVariant stores the variant, replacing an earlier one with the same name
+func (v *PhotoVariant) SavePhotoVariant(db *gorm.DB) (*PhotoVariant, error) {
+	var err error
+	err = db.Debug().Where(PhotoVariant{PhotoID: v.PhotoID, Name: v.Name}).Assign(PhotoVariant{
+		StorageKey: v.StorageKey,
+		URL:        v.URL,
+		MimeType:   v.MimeType,
+		Width:      v.Width,
+		Height:     v.Height,
+		ByteSize:   v.ByteSize,
+	}).FirstOrCreate(&v).Error
+	if err != nil {
+		return &PhotoVariant{}, err
+	}
+	return v, nil
+}
+
+// FindPhotoVariants loads the variants of several photos in one query, keyed by photo id
+func (v *PhotoVariant) FindPhotoVariants(db *gorm.DB, photoIDs []uint64) (map[uint64][]PhotoVariant, error) {
+	variants := []PhotoVariant{}
+	byPhoto := make(map[uint64][]PhotoVariant)
+	if len(photoIDs) == 0 {
+		return byPhoto, nil
+	}
+	err := db.Debug().Model(&PhotoVariant{}).Where("photo_id IN (?)", photoIDs).Order("id").Find(&variants).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, variant := range variants {
+		byPhoto[variant.PhotoID] = append(byPhoto[variant.PhotoID], variant)
+	}
+	return byPhoto, nil
+}
+
+func (v *PhotoVariant) DeletePhotoVariants(db *gorm.DB, photoID uint64) (int64, error) {
+	db = db.Debug().Where("photo_id = ?", photoID).Delete(&PhotoVariant{})
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return db.RowsAffected, nil
+}
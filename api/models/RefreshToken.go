@@ -0,0 +1,95 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// RefreshToken is a single opaque refresh token. Tokens rotated from the same login share a FamilyID,
+// revoking the family logs that session out everywhere.
+type RefreshToken struct {
+	ID        uint64     `gorm:"primary_key;auto_increment" json:"id"`
+	TokenHash string     `gorm:"size:64;not null;unique" json:"-"`
+	FamilyID  string     `gorm:"size:64;not null;index" json:"family_id"`
+	UserID    uint32     `gorm:"not null;index" json:"user_id"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	CreatedAt time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+type TokenRefresh struct {
+	RefreshToken string `json:"refresh_token" binding:"required" example:"5f4dcc3b5aa765d61d8327deb882cf99a1b2c3d4-e5f6-4a7b-8c9d-0e1f2a3b4c5d"`
+}
+
+func (t *RefreshToken) SaveRefreshToken(db *gorm.DB) (*RefreshToken, error) {
+	var err error
+	err = db.Debug().Model(&RefreshToken{}).Create(&t).Error
+	if err != nil {
+		return &RefreshToken{}, err
+	}
+	return t, nil
+}
+
+func (t *RefreshToken) FindRefreshToken(db *gorm.DB, tokenHash string) (*RefreshToken, error) {
+	var err error
+	err = db.Debug().Model(&RefreshToken{}).Where("token_hash = ?", tokenHash).Take(&t).Error
+	if err != nil {
+		return &RefreshToken{}, err
+	}
+	return t, nil
+}
+
+// MarkUsed consumes the token. It reports false when another request already used it,
+// which means the token was replayed.
+func (t *RefreshToken) MarkUsed(db *gorm.DB) (bool, error) {
+	now := time.Now()
+	db = db.Debug().Model(&RefreshToken{}).Where("id = ? AND used_at IS NULL", t.ID).UpdateColumn("used_at", now)
+	if db.Error != nil {
+		return false, db.Error
+	}
+	t.UsedAt = &now
+	return db.RowsAffected == 1, nil
+}
+
+func (t *RefreshToken) IsExpired() bool {
+	return time.Now().After(t.ExpiresAt)
+}
+
+// RevokeFamily revokes every token issued for the same login
+func (t *RefreshToken) RevokeFamily(db *gorm.DB, familyID string) (int64, error) {
+	db = db.Debug().Model(&RefreshToken{}).Where("family_id = ? AND revoked_at IS NULL", familyID).UpdateColumn("revoked_at", time.Now())
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return db.RowsAffected, nil
+}
+
+// RevokeUserTokens logs the user out of every session
+func (t *RefreshToken) RevokeUserTokens(db *gorm.DB, uid uint32) (int64, error) {
+	db = db.Debug().Model(&RefreshToken{}).Where("user_id = ? AND revoked_at IS NULL", uid).UpdateColumn("revoked_at", time.Now())
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return db.RowsAffected, nil
+}
+
+// RevokeOtherUserTokens logs the user out of every session except the current one
+func (t *RefreshToken) RevokeOtherUserTokens(db *gorm.DB, uid uint32, keepFamilyID string) (int64, error) {
+	db = db.Debug().Model(&RefreshToken{}).Where("user_id = ? AND family_id <> ? AND revoked_at IS NULL", uid, keepFamilyID).UpdateColumn("revoked_at", time.Now())
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return db.RowsAffected, nil
+}
+
+// IsFamilyRevoked fails closed: if the lookup errors the family is treated as revoked
+func (t *RefreshToken) IsFamilyRevoked(db *gorm.DB, familyID string) bool {
+	var count int
+	err := db.Debug().Model(&RefreshToken{}).Where("family_id = ? AND revoked_at IS NOT NULL", familyID).Count(&count).Error
+	if err != nil {
+		return true
+	}
+	return count > 0
+}
@@ -0,0 +1,95 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ResetPassword is a single-use password reset token. Only the digest of the token is stored.
+type ResetPassword struct {
+	ID        uint64     `gorm:"primary_key;auto_increment" json:"id"`
+	UserID    uint32     `gorm:"not null;index" json:"user_id"`
+	Email     string     `gorm:"size:100;not null;" json:"email"`
+	TokenHash string     `gorm:"size:64;not null;unique" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+type ForgotPassword struct {
+	Email string `json:"email" binding:"required" example:"rizalaja@gmail.com"`
+}
+
+type ResetPasswordInput struct {
+	Token          string `json:"token" binding:"required" example:"5f4dcc3b5aa765d61d8327deb882cf99a1b2c3d4-e5f6-4a7b-8c9d-0e1f2a3b4c5d"`
+	NewPassword    string `json:"new_password" binding:"required" example:"newpassword"`
+	RetypePassword string `json:"retype_password" binding:"required" example:"newpassword"`
+}
+
+func (r *ResetPasswordInput) Validate() map[string]string {
+	var err error
+
+	var errorMessages = make(map[string]string)
+
+	if r.Token == "" {
+		err = errors.New("Required Token")
+		errorMessages["Required_token"] = err.Error()
+	}
+	if r.NewPassword == "" || r.RetypePassword == "" {
+		err = errors.New("Please ensure both field are entered")
+		errorMessages["Empty_passwords"] = err.Error()
+	}
+	if r.NewPassword != "" && len(r.NewPassword) < 6 {
+		err = errors.New("Password should be atleast 6 characters")
+		errorMessages["Invalid_password"] = err.Error()
+	}
+	if r.NewPassword != r.RetypePassword {
+		err = errors.New("Passwords provided do not match")
+		errorMessages["Password_unequal"] = err.Error()
+	}
+	return errorMessages
+}
+
+func (r *ResetPassword) SaveResetPassword(db *gorm.DB) (*ResetPassword, error) {
+	var err error
+	err = db.Debug().Model(&ResetPassword{}).Create(&r).Error
+	if err != nil {
+		return &ResetPassword{}, err
+	}
+	return r, nil
+}
+
+func (r *ResetPassword) FindResetPasswordByToken(db *gorm.DB, tokenHash string) (*ResetPassword, error) {
+	var err error
+	err = db.Debug().Model(&ResetPassword{}).Where("token_hash = ?", tokenHash).Take(&r).Error
+	if err != nil {
+		return &ResetPassword{}, err
+	}
+	return r, nil
+}
+
+func (r *ResetPassword) IsExpired() bool {
+	return time.Now().After(r.ExpiresAt)
+}
+
+// MarkUsed consumes the token, it reports false when the token was already used
+func (r *ResetPassword) MarkUsed(db *gorm.DB) (bool, error) {
+	now := time.Now()
+	db = db.Debug().Model(&ResetPassword{}).Where("id = ? AND used_at IS NULL", r.ID).UpdateColumn("used_at", now)
+	if db.Error != nil {
+		return false, db.Error
+	}
+	r.UsedAt = &now
+	return db.RowsAffected == 1, nil
+}
+
+// InvalidateUserResets burns the outstanding tokens of a user, so only the latest email works
+func (r *ResetPassword) InvalidateUserResets(db *gorm.DB, uid uint32) (int64, error) {
+	db = db.Debug().Model(&ResetPassword{}).Where("user_id = ? AND used_at IS NULL", uid).UpdateColumn("used_at", time.Now())
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return db.RowsAffected, nil
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/utils/pagination"
 	"github.com/jinzhu/gorm"
 )
 
@@ -74,22 +75,33 @@ func (p *SocialMedia) SaveSocialMedia(db *gorm.DB) (*SocialMedia, error) {
 	return p, nil
 }
 
-func (p *SocialMedia) FindAllSocialMedia(db *gorm.DB) (*[]SocialMedia, error) {
+// SocialMediaSortKeys are the orders GET /social-media-all can be listed in
+var SocialMediaSortKeys = []pagination.SortKey{
+	{Name: "created_at", Column: "created_at", Kind: pagination.Time},
+	{Name: "id", Column: "id", Kind: pagination.Number},
+	{Name: "name", Column: "name", Kind: pagination.String},
+}
+
+func (p *SocialMedia) FindAllSocialMedia(db *gorm.DB, page pagination.Page, filter pagination.Filter) (*[]SocialMedia, pagination.Links, error) {
 	var err error
 	socialMedias := []SocialMedia{}
-	err = db.Debug().Model(&SocialMedia{}).Limit(100).Order("created_at desc").Find(&socialMedias).Error
+	err = page.Apply(filter.Apply(db.Debug().Model(&SocialMedia{}), "social_media"), "social_media").Find(&socialMedias).Error
 	if err != nil {
-		return &[]SocialMedia{}, err
+		return &[]SocialMedia{}, pagination.Links{}, err
 	}
-	if len(socialMedias) > 0 {
-		for i, _ := range socialMedias {
-			err := db.Debug().Model(&User{}).Where("id = ?", socialMedias[i].UserID).Take(&socialMedias[i].User).Error
-			if err != nil {
-				return &[]SocialMedia{}, err
-			}
+	socialMedias, links := pagination.Window(page, socialMedias, func(row SocialMedia) (interface{}, uint64) {
+		switch page.Key.Name {
+		case "name":
+			return row.Name, row.ID
+		case "id":
+			return row.ID, row.ID
 		}
+		return row.CreatedAt, row.ID
+	})
+	if err = loadSocialMedias(db, socialMedias); err != nil {
+		return &[]SocialMedia{}, pagination.Links{}, err
 	}
-	return &socialMedias, nil
+	return &socialMedias, links, nil
 }
 
 func (p *SocialMedia) FindSocialMediaByID(db *gorm.DB, pid uint64) (*SocialMedia, error) {
@@ -141,13 +153,8 @@ func (p *SocialMedia) FindUserSocialMedias(db *gorm.DB, uid uint32) (*[]SocialMe
 	if err != nil {
 		return &[]SocialMedia{}, err
 	}
-	if len(socialMedias) > 0 {
-		for i, _ := range socialMedias {
-			err := db.Debug().Model(&User{}).Where("id = ?", socialMedias[i].UserID).Take(&socialMedias[i].User).Error
-			if err != nil {
-				return &[]SocialMedia{}, err
-			}
-		}
+	if err = loadSocialMedias(db, socialMedias); err != nil {
+		return &[]SocialMedia{}, err
 	}
 	return &socialMedias, nil
 }
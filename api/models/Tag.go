@@ -0,0 +1,202 @@
+package models
+
+import (
+	"html"
+	"strings"
+	"time"
+
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/utils/hashtag"
+	"github.com/jinzhu/gorm"
+)
+
+// Tag is a hashtag used in photo captions, stored lower cased and without the #
+type Tag struct {
+	ID   uint64 `gorm:"primary_key;auto_increment" json:"id"`
+	Name string `gorm:"size:50;not null;unique" json:"name"`
+	// PhotoCount is how many photos carry the tag, recounted whenever they change
+	PhotoCount int64     `gorm:"not null;default:0;index" json:"photo_count"`
+	CreatedAt  time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt  time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+
+	// only set by FindTrendingTags
+	RecentCount int64 `gorm:"-" json:"recent_count"`
+}
+
+// PhotoTag links a photo to a tag of its caption
+type PhotoTag struct {
+	ID        uint64    `gorm:"primary_key;auto_increment" json:"id"`
+	PhotoID   uint64    `gorm:"not null;unique_index:idx_photo_tags_photo_tag" json:"photo_id"`
+	TagID     uint64    `gorm:"not null;unique_index:idx_photo_tags_photo_tag;index" json:"tag_id"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP;index" json:"created_at"`
+}
+
+// SyncPhotoTags makes the tags of the photo match the hashtags of its caption
+func (t *Tag) SyncPhotoTags(db *gorm.DB, photo Photo) error {
+	// captions are stored escaped, "&#39;" must not look like a tag
+	names := hashtag.Parse(html.UnescapeString(photo.Caption))
+
+	wanted := make(map[uint64]bool, len(names))
+	for _, name := range names {
+		tag := Tag{}
+		err := db.Debug().Where(Tag{Name: name}).FirstOrCreate(&tag).Error
+		if err != nil && isUniqueViolation(err) {
+			// another caption created it in the meantime
+			err = db.Debug().Where("name = ?", name).Take(&tag).Error
+		}
+		if err != nil {
+			return err
+		}
+		wanted[tag.ID] = true
+	}
+
+	var current []uint64
+	if err := db.Debug().Model(&PhotoTag{}).Where("photo_id = ?", photo.ID).Pluck("tag_id", &current).Error; err != nil {
+		return err
+	}
+	changed := []uint64{}
+	removed := []uint64{}
+	for _, id := range current {
+		if wanted[id] {
+			delete(wanted, id)
+		} else {
+			removed = append(removed, id)
+		}
+	}
+	if len(removed) > 0 {
+		if err := db.Debug().Where("photo_id = ? AND tag_id IN (?)", photo.ID, removed).Delete(&PhotoTag{}).Error; err != nil {
+			return err
+		}
+		changed = append(changed, removed...)
+	}
+	for id := range wanted {
+		if err := db.Debug().Create(&PhotoTag{PhotoID: photo.ID, TagID: id, CreatedAt: time.Now()}).Error; err != nil {
+			return err
+		}
+		changed = append(changed, id)
+	}
+	return recountTags(db, changed)
+}
+
+// DeletePhotoTags unlinks the tags of a deleted photo
+func (t *Tag) DeletePhotoTags(db *gorm.DB, pid uint64) (int64, error) {
+	return deletePhotoTags(db, db.Model(&Photo{}).Select("id").Where("id = ?", pid).QueryExpr())
+}
+
+// When a user is deleted, the tags of their photos are unlinked
+func (t *Tag) DeleteUserPhotoTags(db *gorm.DB, uid uint32) (int64, error) {
+	return deletePhotoTags(db, db.Model(&Photo{}).Select("id").Where("user_id = ?", uid).QueryExpr())
+}
+
+// FindTagsByPrefix completes a partly typed tag, the most used tags come first
+func (t *Tag) FindTagsByPrefix(db *gorm.DB, prefix string, limit int) (*[]Tag, error) {
+	tags := []Tag{}
+	prefix = strings.NewReplacer("%", "", "_", `\_`).Replace(hashtag.Normalize(prefix))
+	err := db.Debug().Model(&Tag{}).Where("name LIKE ? AND photo_count > 0", prefix+"%").
+		Order("photo_count desc").Order("name").Limit(limit).Find(&tags).Error
+	if err != nil {
+		return &[]Tag{}, err
+	}
+	return &tags, nil
+}
+
+// FindTrendingTags lists the tags put on the most photos since the given time
+func (t *Tag) FindTrendingTags(db *gorm.DB, since time.Time, limit int) (*[]Tag, error) {
+	rows, err := db.Debug().Model(&PhotoTag{}).Select("tag_id, COUNT(*) AS recent").Where("created_at >= ?", since).
+		Group("tag_id").Order("recent desc").Order("tag_id").Limit(limit).Rows()
+	if err != nil {
+		return &[]Tag{}, err
+	}
+	defer rows.Close()
+	ids := []uint64{}
+	recent := make(map[uint64]int64)
+	for rows.Next() {
+		var id uint64
+		var count int64
+		if err = rows.Scan(&id, &count); err != nil {
+			return &[]Tag{}, err
+		}
+		ids = append(ids, id)
+		recent[id] = count
+	}
+	if err = rows.Err(); err != nil {
+		return &[]Tag{}, err
+	}
+
+	tags := []Tag{}
+	if len(ids) > 0 {
+		if err = db.Debug().Model(&Tag{}).Where("id IN (?)", ids).Find(&tags).Error; err != nil {
+			return &[]Tag{}, err
+		}
+	}
+	byID := make(map[uint64]Tag, len(tags))
+	for _, tag := range tags {
+		tag.RecentCount = recent[tag.ID]
+		byID[tag.ID] = tag
+	}
+	trending := make([]Tag, 0, len(ids))
+	for _, id := range ids {
+		if tag, ok := byID[id]; ok {
+			trending = append(trending, tag)
+		}
+	}
+	return &trending, nil
+}
+
+// loadPhotoTags fills in the tag names of the photos with a single query
+func loadPhotoTags(db *gorm.DB, photos []Photo) error {
+	ids := make([]uint64, len(photos))
+	for i := range photos {
+		ids[i] = photos[i].ID
+	}
+	byPhoto := make(map[uint64][]string)
+	if len(ids) > 0 {
+		rows, err := db.Debug().Table("photo_tags").Select("photo_tags.photo_id, tags.name").
+			Joins("JOIN tags ON tags.id = photo_tags.tag_id").Where("photo_tags.photo_id IN (?)", ids).
+			Order("photo_tags.id").Rows()
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var id uint64
+			var name string
+			if err = rows.Scan(&id, &name); err != nil {
+				return err
+			}
+			byPhoto[id] = append(byPhoto[id], name)
+		}
+		if err = rows.Err(); err != nil {
+			return err
+		}
+	}
+	for i := range photos {
+		photos[i].Tags = byPhoto[photos[i].ID]
+		if photos[i].Tags == nil {
+			photos[i].Tags = []string{}
+		}
+	}
+	return nil
+}
+
+// deletePhotoTags unlinks the tags of the photos selected by the photo id subquery and recounts them
+func deletePhotoTags(db *gorm.DB, photoIDs interface{}) (int64, error) {
+	var tagIDs []uint64
+	err := db.Debug().Model(&PhotoTag{}).Where("photo_id IN (?)", photoIDs).Pluck("DISTINCT tag_id", &tagIDs).Error
+	if err != nil {
+		return 0, err
+	}
+	result := db.Debug().Where("photo_id IN (?)", photoIDs).Delete(&PhotoTag{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, recountTags(db, tagIDs)
+}
+
+// recountTags sets PhotoCount of the tags from their links
+func recountTags(db *gorm.DB, ids []uint64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return db.Debug().Exec(`UPDATE tags SET photo_count = (SELECT COUNT(*) FROM photo_tags WHERE photo_tags.tag_id = tags.id),
+		updated_at = ? WHERE id IN (?)`, time.Now(), ids).Error
+}
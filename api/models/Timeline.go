@@ -0,0 +1,154 @@
+package models
+
+import (
+	"time"
+
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/utils/pagination"
+	"github.com/jinzhu/gorm"
+)
+
+// TimelineEntry puts a photo on the home feed of a user. Entries are only written when the feed is
+// precomputed (fan-out on write); CreatedAt is the time of the photo, so the feed reads in photo order.
+type TimelineEntry struct {
+	ID        uint64    `gorm:"primary_key;auto_increment" json:"id"`
+	UserID    uint32    `gorm:"not null;unique_index:idx_timeline_entries_user_photo;index:idx_timeline_entries_user_created" json:"user_id"`
+	PhotoID   uint64    `gorm:"not null;unique_index:idx_timeline_entries_user_photo;index" json:"photo_id"`
+	AuthorID  uint32    `gorm:"not null;index" json:"author_id"`
+	CreatedAt time.Time `gorm:"not null;index:idx_timeline_entries_user_created" json:"created_at"`
+}
+
+// FeedSortKeys are the orders a feed can be listed in, it is always by recency
+var FeedSortKeys = []pagination.SortKey{
+	{Name: "created_at", Column: "created_at", Kind: pagination.Time},
+}
+
+// FindFollowedPhotos pages through the photos of uid and of the accounts uid follows, looking them up at read time
+func (p *Photo) FindFollowedPhotos(db *gorm.DB, uid uint32, page pagination.Page) (*[]Photo, pagination.Links, error) {
+	followees := db.Model(&Follow{}).Select("followee_id").Where("follower_id = ?", uid).QueryExpr()
+	photos := []Photo{}
+	err := page.Apply(db.Debug().Model(&Photo{}).Where("photos.user_id = ? OR photos.user_id IN (?)", uid, followees), "photos").Find(&photos).Error
+	if err != nil {
+		return &[]Photo{}, pagination.Links{}, err
+	}
+	photos, links := pagination.Window(page, photos, func(row Photo) (interface{}, uint64) {
+		return row.CreatedAt, row.ID
+	})
+	if err = loadPhotos(db, photos); err != nil {
+		return &[]Photo{}, pagination.Links{}, err
+	}
+	return &photos, links, nil
+}
+
+// FindTimeline pages through the precomputed feed of uid
+func (t *TimelineEntry) FindTimeline(db *gorm.DB, uid uint32, page pagination.Page) (*[]Photo, pagination.Links, error) {
+	entries := []TimelineEntry{}
+	err := page.Apply(db.Debug().Model(&TimelineEntry{}).Where("timeline_entries.user_id = ?", uid), "timeline_entries").Find(&entries).Error
+	if err != nil {
+		return &[]Photo{}, pagination.Links{}, err
+	}
+	entries, links := pagination.Window(page, entries, func(row TimelineEntry) (interface{}, uint64) {
+		return row.CreatedAt, row.ID
+	})
+
+	ids := make([]uint64, len(entries))
+	for i := range entries {
+		ids[i] = entries[i].PhotoID
+	}
+	found := []Photo{}
+	if len(ids) > 0 {
+		if err = db.Debug().Model(&Photo{}).Where("id IN (?)", ids).Find(&found).Error; err != nil {
+			return &[]Photo{}, pagination.Links{}, err
+		}
+	}
+	byID := make(map[uint64]Photo, len(found))
+	for _, photo := range found {
+		byID[photo.ID] = photo
+	}
+	// keep the order of the timeline
+	photos := make([]Photo, 0, len(entries))
+	for _, entry := range entries {
+		if photo, ok := byID[entry.PhotoID]; ok {
+			photos = append(photos, photo)
+		}
+	}
+	if err = loadPhotos(db, photos); err != nil {
+		return &[]Photo{}, pagination.Links{}, err
+	}
+	return &photos, links, nil
+}
+
+// FanOutPhoto adds a new photo to the timelines of its author and of every follower, in a single statement
+func (t *TimelineEntry) FanOutPhoto(db *gorm.DB, photo Photo) error {
+	err := db.Debug().Create(&TimelineEntry{UserID: photo.UserID, PhotoID: photo.ID, AuthorID: photo.UserID, CreatedAt: photo.CreatedAt}).Error
+	if err != nil {
+		return err
+	}
+	return db.Debug().Exec(`INSERT INTO timeline_entries (user_id, photo_id, author_id, created_at)
+		SELECT follower_id, ?, ?, ? FROM follows WHERE followee_id = ?`,
+		photo.ID, photo.UserID, photo.CreatedAt, photo.UserID).Error
+}
+
+// FanInFollow copies the latest photos of followee into the timeline of follower, limit caps how many
+func (t *TimelineEntry) FanInFollow(db *gorm.DB, follower, followee uint32, limit int) error {
+	return db.Debug().Exec(`INSERT INTO timeline_entries (user_id, photo_id, author_id, created_at)
+		SELECT ?, id, user_id, created_at FROM photos
+		WHERE user_id = ? AND id NOT IN (SELECT photo_id FROM timeline_entries WHERE user_id = ?)
+		ORDER BY created_at DESC LIMIT ?`,
+		follower, followee, follower, limit).Error
+}
+
+// RemoveFollow takes the photos of followee off the timeline of follower
+func (t *TimelineEntry) RemoveFollow(db *gorm.DB, follower, followee uint32) (int64, error) {
+	db = db.Debug().Where("user_id = ? AND author_id = ?", follower, followee).Delete(&TimelineEntry{})
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return db.RowsAffected, nil
+}
+
+// RebuildTimelines recomputes every timeline from the photos and the follows, for switching to fan-out on write
+// on a database that has been reading at request time
+func (t *TimelineEntry) RebuildTimelines(db *gorm.DB) (int64, error) {
+	tx := db.Begin()
+	if tx.Error != nil {
+		return 0, tx.Error
+	}
+	if err := tx.Debug().Delete(&TimelineEntry{}).Error; err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	own := tx.Debug().Exec(`INSERT INTO timeline_entries (user_id, photo_id, author_id, created_at)
+		SELECT user_id, id, user_id, created_at FROM photos`)
+	if own.Error != nil {
+		tx.Rollback()
+		return 0, own.Error
+	}
+	followed := tx.Debug().Exec(`INSERT INTO timeline_entries (user_id, photo_id, author_id, created_at)
+		SELECT follows.follower_id, photos.id, photos.user_id, photos.created_at
+		FROM photos JOIN follows ON follows.followee_id = photos.user_id`)
+	if followed.Error != nil {
+		tx.Rollback()
+		return 0, followed.Error
+	}
+	if err := tx.Commit().Error; err != nil {
+		return 0, err
+	}
+	return own.RowsAffected + followed.RowsAffected, nil
+}
+
+func (t *TimelineEntry) DeletePhotoEntries(db *gorm.DB, pid uint64) (int64, error) {
+	db = db.Debug().Where("photo_id = ?", pid).Delete(&TimelineEntry{})
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return db.RowsAffected, nil
+}
+
+// When a user is deleted, their timeline goes and so do their photos on the timelines of others
+func (t *TimelineEntry) DeleteUserEntries(db *gorm.DB, uid uint32) (int64, error) {
+	db = db.Debug().Where("user_id = ? OR author_id = ?", uid, uid).Delete(&TimelineEntry{})
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return db.RowsAffected, nil
+}
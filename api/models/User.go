@@ -13,13 +13,20 @@ import (
 )
 
 type User struct {
-	ID        uint32    `gorm:"primary_key;auto_increment" json:"id"`
-	Username  string    `gorm:"size:255;not null;unique" json:"username"`
-	Email     string    `gorm:"size:100;not null;unique" json:"email"`
-	Password  string    `gorm:"size:100;not null;" json:"password"`
-	Age       uint32    `gorm:"not null;" json:"age"`
-	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
-	UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+	ID              uint32     `gorm:"primary_key;auto_increment" json:"id"`
+	Username        string     `gorm:"size:255;not null;unique" json:"username"`
+	Email           string     `gorm:"size:100;not null;unique" json:"email"`
+	Password        string     `gorm:"size:100;not null;" json:"password"`
+	Age             uint32     `gorm:"not null;" json:"age"`
+	Role            string     `gorm:"size:20;not null;default:'user'" json:"role"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	CreatedAt       time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt       time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+
+	// filled in per viewer by Follow.FillUserFollows
+	FollowerCount  int64 `gorm:"-" json:"follower_count"`
+	FollowingCount int64 `gorm:"-" json:"following_count"`
+	FollowedByMe   bool  `gorm:"-" json:"followed_by_me"`
 }
 
 type UserLogin struct {
@@ -27,6 +34,46 @@ type UserLogin struct {
 	Password string `json:"password" binding:"required" example:"password"`
 }
 
+type UpdateUser struct {
+	Username string `json:"username" binding:"required" example:"rizalaja updated"`
+	Email    string `json:"email" binding:"required" example:"rizalaja@gmail.com"`
+	Age      uint32 `json:"age" binding:"required" example:"24"`
+}
+
+type ChangePassword struct {
+	CurrentPassword string `json:"current_password" binding:"required" example:"password"`
+	NewPassword     string `json:"new_password" binding:"required" example:"newpassword"`
+	RetypePassword  string `json:"retype_password" binding:"required" example:"newpassword"`
+}
+
+func (p *ChangePassword) Validate() map[string]string {
+	var err error
+
+	var errorMessages = make(map[string]string)
+
+	if p.CurrentPassword == "" {
+		err = errors.New("Required Current Password")
+		errorMessages["Required_current_password"] = err.Error()
+	}
+	if p.NewPassword == "" || p.RetypePassword == "" {
+		err = errors.New("Please ensure both field are entered")
+		errorMessages["Empty_passwords"] = err.Error()
+	}
+	if p.NewPassword != "" && len(p.NewPassword) < 6 {
+		err = errors.New("Password should be atleast 6 characters")
+		errorMessages["Invalid_password"] = err.Error()
+	}
+	if p.NewPassword != p.RetypePassword {
+		err = errors.New("Passwords provided do not match")
+		errorMessages["Password_unequal"] = err.Error()
+	}
+	return errorMessages
+}
+
+type UpdateRole struct {
+	Role string `json:"role" binding:"required" example:"moderator"`
+}
+
 type UserRegister struct {
 	Username string `json:"username" binding:"required" example:"rizalaja"`
 	Email    string `json:"email" binding:"required" example:"rizalaja@gmail.com"`
@@ -54,8 +101,7 @@ func (u *User) AfterFind() (err error) {
 	if err != nil {
 		return err
 	}
-	//dont return the user password
-	// u.Password = ""
+	// the hash is kept for SignIn, controllers render users through responses.User which never includes it
 	return nil
 }
 
@@ -65,6 +111,10 @@ func (u *User) Validate(action string) map[string]string {
 
 	switch strings.ToLower(action) {
 	case "update":
+		if u.Username == "" {
+			err = errors.New("Required Username")
+			errorMessages["Required_username"] = err.Error()
+		}
 		if u.Email == "" {
 			err = errors.New("Required Email")
 			errorMessages["Required_email"] = err.Error()
@@ -75,6 +125,10 @@ func (u *User) Validate(action string) map[string]string {
 				errorMessages["Invalid_email"] = err.Error()
 			}
 		}
+		if u.Age <= 8 {
+			err = errors.New("Age should be atleast 8 years old")
+			errorMessages["Invalid_age"] = err.Error()
+		}
 
 	case "login":
 		if u.Password == "" {
@@ -155,3 +209,171 @@ func (u *User) FindUserByID(db *gorm.DB, uid uint32) (*User, error) {
 	}
 	return u, err
 }
+
+// FindUserByEmail looks a user up by the address they sign in with
+func (u *User) FindUserByEmail(db *gorm.DB, email string) (*User, error) {
+	err := db.Debug().Model(User{}).Where("email = ?", email).Take(&u).Error
+	if err != nil {
+		return &User{}, err
+	}
+	return u, nil
+}
+
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// MarkEmailVerified records that the user confirmed their address
+func (u *User) MarkEmailVerified(db *gorm.DB) error {
+	now := time.Now()
+	err := db.Debug().Model(&User{}).Where("id = ?", u.ID).UpdateColumns(map[string]interface{}{
+		"email_verified_at": now,
+		"updated_at":        now,
+	}).Error
+	if err != nil {
+		return err
+	}
+	u.EmailVerifiedAt = &now
+	return nil
+}
+
+// UpdateRole changes the role of the user, the new role is picked up by the next access token
+func (u *User) UpdateRole(db *gorm.DB, role string) error {
+	err := db.Debug().Model(&User{}).Where("id = ?", u.ID).UpdateColumns(map[string]interface{}{
+		"role":       role,
+		"updated_at": time.Now(),
+	}).Error
+	if err != nil {
+		return err
+	}
+	u.Role = role
+	return nil
+}
+
+// UpdatePassword hashes and stores a new password. UpdateColumns skips BeforeSave, so the hash is not hashed twice.
+func (u *User) UpdatePassword(db *gorm.DB, password string) error {
+	hashedPassword, err := security.Hash(password)
+	if err != nil {
+		return err
+	}
+	err = db.Debug().Model(&User{}).Where("id = ?", u.ID).UpdateColumns(map[string]interface{}{
+		"password":   string(hashedPassword),
+		"updated_at": time.Now(),
+	}).Error
+	if err != nil {
+		return err
+	}
+	u.Password = string(hashedPassword)
+	return nil
+}
+
+// UpdateAUser saves the profile fields. A new email address has to be verified again.
+func (u *User) UpdateAUser(db *gorm.DB, uid uint32) (*User, error) {
+
+	err := db.Debug().Model(&User{}).Where("id = ?", uid).UpdateColumns(map[string]interface{}{
+		"username":          u.Username,
+		"email":             u.Email,
+		"age":               u.Age,
+		"email_verified_at": u.EmailVerifiedAt,
+		"updated_at":        time.Now(),
+	}).Error
+	if err != nil {
+		return &User{}, err
+	}
+	// display the updated user
+	err = db.Debug().Model(&User{}).Where("id = ?", uid).Take(&u).Error
+	if err != nil {
+		return &User{}, err
+	}
+	return u, nil
+}
+
+// DeleteAUser removes the account together with everything it owns, in one transaction
+func (u *User) DeleteAUser(db *gorm.DB, uid uint32) (int64, error) {
+
+	tx := db.Begin()
+	if tx.Error != nil {
+		return 0, tx.Error
+	}
+
+	// likes first, they point at the comments and photos deleted below
+	commentLike := CommentLike{}
+	if _, err := commentLike.DeleteUserCommentLikes(tx, uid); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	like := Like{}
+	if _, err := like.DeleteUserLikes(tx, uid); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	follow := Follow{}
+	if _, err := follow.DeleteUserFollows(tx, uid); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	entry := TimelineEntry{}
+	if _, err := entry.DeleteUserEntries(tx, uid); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	tag := Tag{}
+	if _, err := tag.DeleteUserPhotoTags(tx, uid); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	mention := Mention{}
+	if _, err := mention.DeleteUserMentions(tx, uid); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	notification := Notification{}
+	if _, err := notification.DeleteUserNotifications(tx, uid); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	// comments left by others on the user's photos go with the photos
+	err := tx.Debug().Where("photo_id IN (?)", tx.Model(&Photo{}).Select("id").Where("user_id = ?", uid).QueryExpr()).Delete(&Comment{}).Error
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	comment := Comment{}
+	if _, err = comment.DeleteUserComments(tx, uid); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	photo := Photo{}
+	if _, err = photo.DeleteUserPhotos(tx, uid); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	socialMedia := SocialMedia{}
+	if _, err = socialMedia.DeleteUserSocialMedias(tx, uid); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	for _, model := range []interface{}{&ResetPassword{}, &EmailVerification{}, &NotificationPreference{}} {
+		if err = tx.Debug().Where("user_id = ?", uid).Delete(model).Error; err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+	// sessions are revoked rather than deleted, so outstanding access tokens stop working too
+	refreshToken := RefreshToken{}
+	if _, err = refreshToken.RevokeUserTokens(tx, uid); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	result := tx.Debug().Model(&User{}).Where("id = ?", uid).Take(&User{}).Delete(&User{})
+	if result.Error != nil {
+		tx.Rollback()
+		return 0, result.Error
+	}
+	if err = tx.Commit().Error; err != nil {
+		return 0, err
+	}
+	return result.RowsAffected, nil
+}
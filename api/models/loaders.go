@@ -0,0 +1,133 @@
+package models
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+// The loaders below fill the associations of a list of rows with one query per association,
+// whatever the length of the list. Finders returning several rows go through them instead of
+// looking up the associations row by row.
+
+// loadPhotos loads the owners, the variants, the tags, the mentions and the comment counts of photos
+func loadPhotos(db *gorm.DB, photos []Photo) error {
+	err := loadUsers(db, photos, func(p *Photo) (uint32, *User) { return p.UserID, &p.User })
+	if err != nil {
+		return err
+	}
+	if err = loadPhotoVariants(db, photos); err != nil {
+		return err
+	}
+	if err = loadPhotoTags(db, photos); err != nil {
+		return err
+	}
+	if err = loadPhotoMentions(db, photos); err != nil {
+		return err
+	}
+	return loadCommentCounts(db, photos)
+}
+
+// loadCommentCounts counts the comments of every photo with a single query
+func loadCommentCounts(db *gorm.DB, photos []Photo) error {
+	ids := make([]uint64, len(photos))
+	for i := range photos {
+		ids[i] = photos[i].ID
+	}
+	counts, err := countBy(db, "comments", "photo_id", ids)
+	if err != nil {
+		return err
+	}
+	for i := range photos {
+		photos[i].CommentCount = counts[photos[i].ID]
+	}
+	return nil
+}
+
+// loadComments loads the authors, the mentions and the reply counts of comments
+func loadComments(db *gorm.DB, comments []Comment) error {
+	err := loadUsers(db, comments, func(c *Comment) (uint32, *User) { return c.UserID, &c.User })
+	if err != nil {
+		return err
+	}
+	if err = loadCommentMentions(db, comments); err != nil {
+		return err
+	}
+	ids := make([]uint64, len(comments))
+	for i := range comments {
+		ids[i] = comments[i].ID
+	}
+	counts, err := countBy(db, "comments", "parent_id", ids)
+	if err != nil {
+		return err
+	}
+	for i := range comments {
+		comments[i].ReplyCount = counts[comments[i].ID]
+	}
+	return nil
+}
+
+// loadSocialMedias loads the owners of social media
+func loadSocialMedias(db *gorm.DB, socialMedias []SocialMedia) error {
+	return loadUsers(db, socialMedias, func(s *SocialMedia) (uint32, *User) { return s.UserID, &s.User })
+}
+
+// loadUsers sets the user of every row, owner returns the user id of a row and where its user goes.
+// Like looking them up one at a time, it fails with a record not found error when a user is missing.
+// Rows without a user id, the tombstones of deleted accounts, are left without a user.
+func loadUsers[T any](db *gorm.DB, rows []T, owner func(*T) (uint32, *User)) error {
+	ids := make([]uint32, 0, len(rows))
+	seen := map[uint32]bool{0: true}
+	for i := range rows {
+		id, _ := owner(&rows[i])
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	users := []User{}
+	err := db.Debug().Model(&User{}).Where("id IN (?)", ids).Find(&users).Error
+	if err != nil {
+		return err
+	}
+	byID := make(map[uint32]User, len(users))
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+	for i := range rows {
+		id, user := owner(&rows[i])
+		if id == 0 {
+			continue
+		}
+		found, ok := byID[id]
+		if !ok {
+			return gorm.ErrRecordNotFound
+		}
+		*user = found
+	}
+	return nil
+}
+
+// countBy counts the rows of table per value of column, for the given values
+func countBy(db *gorm.DB, table, column string, ids []uint64) (map[uint64]int64, error) {
+	counts := make(map[uint64]int64)
+	if len(ids) == 0 {
+		return counts, nil
+	}
+	rows, err := db.Debug().Table(table).Select(column+", COUNT(*)").Where(column+" IN (?)", ids).Group(column).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id uint64
+		var count int64
+		if err = rows.Scan(&id, &count); err != nil {
+			return nil, err
+		}
+		counts[id] = count
+	}
+	return counts, rows.Err()
+}
@@ -0,0 +1,138 @@
+package notify
+
+import (
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/models"
+	"github.com/jinzhu/gorm"
+)
+
+// Event is something a user (the actor) did that concerns another user (the recipient)
+type Event struct {
+	Kind      string
+	Actor     uint32
+	Recipient uint32
+	PhotoID   *uint64
+	CommentID *uint64
+}
+
+// Notifier turns the events emitted by the controllers into notifications. It drops the events of users acting
+// on their own content, those of a kind the recipient turned off, and those the recipient was already told about
+// and has not read yet.
+type Notifier struct {
+	db        *gorm.DB
+	listeners []func(models.Notification)
+}
+
+func NewNotifier(db *gorm.DB) *Notifier {
+	return &Notifier{db: db}
+}
+
+// Listen registers fn to be called with every notification Emit saves, once it is saved
+func (n *Notifier) Listen(fn func(models.Notification)) {
+	n.listeners = append(n.listeners, fn)
+}
+
+// Emit records the events, a recipient gets at most one notification per call: the first event concerning them wins
+func (n *Notifier) Emit(events ...Event) error {
+	kept := []Event{}
+	recipients := []uint32{}
+	seen := make(map[uint32]bool)
+	for _, event := range events {
+		if event.Recipient == 0 || event.Recipient == event.Actor || seen[event.Recipient] {
+			continue
+		}
+		seen[event.Recipient] = true
+		recipients = append(recipients, event.Recipient)
+		kept = append(kept, event)
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+
+	pref := models.NotificationPreference{}
+	prefs, err := pref.FindNotificationPreferences(n.db, recipients)
+	if err != nil {
+		return err
+	}
+	for _, event := range kept {
+		pref := prefs[event.Recipient]
+		if !pref.Allows(event.Kind) {
+			continue
+		}
+		notification := models.Notification{
+			UserID:    event.Recipient,
+			ActorID:   event.Actor,
+			Kind:      event.Kind,
+			PhotoID:   event.PhotoID,
+			CommentID: event.CommentID,
+		}
+		duplicate, err := notification.FindUnreadDuplicate(n.db)
+		if err != nil {
+			return err
+		}
+		if duplicate {
+			continue
+		}
+		if _, err = notification.SaveNotification(n.db); err != nil {
+			return err
+		}
+		for _, fn := range n.listeners {
+			fn(notification)
+		}
+	}
+	return nil
+}
+
+// CommentCreated tells the author of the comment replied to, the owner of the photo and the users mentioned
+func (n *Notifier) CommentCreated(comment models.Comment) error {
+	events := []Event{}
+	id, pid := comment.ID, comment.PhotoID
+	if comment.ParentID != nil {
+		parent := models.Comment{}
+		err := n.db.Debug().Model(&models.Comment{}).Where("id = ?", *comment.ParentID).Take(&parent).Error
+		if err != nil && !gorm.IsRecordNotFoundError(err) {
+			return err
+		}
+		events = append(events, Event{Kind: models.NotificationReply, Actor: comment.UserID, Recipient: parent.UserID, PhotoID: &pid, CommentID: &id})
+	}
+	photo := models.Photo{}
+	err := n.db.Debug().Model(&models.Photo{}).Where("id = ?", comment.PhotoID).Take(&photo).Error
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		return err
+	}
+	events = append(events, Event{Kind: models.NotificationComment, Actor: comment.UserID, Recipient: photo.UserID, PhotoID: &pid, CommentID: &id})
+	return n.Emit(append(events, mentionEvents(comment.Mentions)...)...)
+}
+
+// Mentioned tells the users newly mentioned in a caption or a comment
+func (n *Notifier) Mentioned(mentions []models.Mention) error {
+	return n.Emit(mentionEvents(mentions)...)
+}
+
+// Followed tells a user about their new follower
+func (n *Notifier) Followed(follow models.Follow) error {
+	return n.Emit(Event{Kind: models.NotificationFollow, Actor: follow.FollowerID, Recipient: follow.FolloweeID})
+}
+
+// PhotoLiked tells the owner of the photo
+func (n *Notifier) PhotoLiked(like models.Like, photo models.Photo) error {
+	pid := photo.ID
+	return n.Emit(Event{Kind: models.NotificationLike, Actor: like.UserID, Recipient: photo.UserID, PhotoID: &pid})
+}
+
+// CommentLiked tells the author of the comment
+func (n *Notifier) CommentLiked(like models.CommentLike, comment models.Comment) error {
+	id, pid := comment.ID, comment.PhotoID
+	return n.Emit(Event{Kind: models.NotificationLike, Actor: like.UserID, Recipient: comment.UserID, PhotoID: &pid, CommentID: &id})
+}
+
+func mentionEvents(mentions []models.Mention) []Event {
+	events := []Event{}
+	for _, m := range mentions {
+		if !m.New {
+			continue
+		}
+		pid := m.PhotoID
+		events = append(events, Event{Kind: models.NotificationMention, Actor: m.AuthorID, Recipient: m.UserID, PhotoID: &pid, CommentID: m.CommentID})
+	}
+	return events
+}
@@ -0,0 +1,78 @@
+package policy
+
+// Role is stored on models.User and embedded in the access token
+type Role = string
+
+const (
+	RoleUser      Role = "user"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+// Permission is an action that goes beyond changing your own content
+type Permission string
+
+const (
+	ModeratePhotos      Permission = "photos:moderate"
+	ModerateComments    Permission = "comments:moderate"
+	ModerateSocialMedia Permission = "social_media:moderate"
+	ManageUsers         Permission = "users:manage"
+	AssignRoles         Permission = "roles:assign"
+)
+
+var rolePermissions = map[Role][]Permission{
+	RoleUser: {},
+	RoleModerator: {
+		ModeratePhotos,
+		ModerateComments,
+		ModerateSocialMedia,
+	},
+	RoleAdmin: {
+		ModeratePhotos,
+		ModerateComments,
+		ModerateSocialMedia,
+		ManageUsers,
+		AssignRoles,
+	},
+}
+
+// Actor is the authenticated user a request is made on behalf of
+type Actor struct {
+	ID   uint32
+	Role Role
+}
+
+// ValidRole reports whether role is one of the known roles
+func ValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// Can reports whether the actor's role grants the permission
+func Can(actor Actor, permission Permission) bool {
+	for _, p := range rolePermissions[actor.Role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// CanModify is the ownership rule for every Update*/Delete* handler: owners can always change their
+// own resources, anyone else needs the moderation permission for that kind of resource.
+func CanModify(actor Actor, ownerID uint32, permission Permission) bool {
+	if actor.ID != 0 && actor.ID == ownerID {
+		return true
+	}
+	return Can(actor, permission)
+}
+
+// HasRole reports whether the actor has any of the given roles
+func HasRole(actor Actor, roles ...Role) bool {
+	for _, role := range roles {
+		if actor.Role == role {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+package realtime
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/config"
+	"github.com/jinzhu/gorm"
+	"github.com/lib/pq"
+)
+
+// Broker carries the messages between the instances of the API. Subscribe registers a handler that gets
+// every message published from now on by any instance, this one included.
+type Broker interface {
+	Publish(msg Message) error
+	Subscribe(handle func(Message)) error
+	Close() error
+}
+
+// NewBroker picks the configured Broker: memory (a single instance) or postgres
+// (LISTEN/NOTIFY on the database of the API, for several instances). dsn is the connection string of db.
+func NewBroker(db *gorm.DB, dsn string, cfg config.Realtime) (Broker, error) {
+	switch cfg.Broker {
+	case "memory":
+		return NewMemoryBroker(), nil
+	case "postgres":
+		if db.Dialect().GetName() != "postgres" {
+			return nil, fmt.Errorf("the postgres realtime broker needs a postgres database, not %s", db.Dialect().GetName())
+		}
+		return NewPostgresBroker(db.DB(), dsn)
+	default:
+		return nil, fmt.Errorf("unknown realtime broker %q", cfg.Broker)
+	}
+}
+
+// MemoryBroker delivers the messages within the process, it only reaches the clients of this instance
+type MemoryBroker struct {
+	mu       sync.RWMutex
+	handlers []func(Message)
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{}
+}
+
+func (b *MemoryBroker) Publish(msg Message) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, handle := range b.handlers {
+		handle(msg)
+	}
+	return nil
+}
+
+func (b *MemoryBroker) Subscribe(handle func(Message)) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handle)
+	return nil
+}
+
+func (b *MemoryBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = nil
+	return nil
+}
+
+// postgresChannel is the LISTEN/NOTIFY channel the instances share
+const postgresChannel = "realtime"
+
+// PostgresBroker sends the messages with NOTIFY and receives them with LISTEN on a connection of its own.
+// A payload is limited to 8000 bytes by postgres, and messages sent while the listening connection is
+// being re-established are lost: clients get fresh data when they reconnect anyway.
+type PostgresBroker struct {
+	db       *sql.DB
+	listener *pq.Listener
+}
+
+func NewPostgresBroker(db *sql.DB, dsn string) (*PostgresBroker, error) {
+	listener := pq.NewListener(dsn, 10*time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			fmt.Println("this is the error of the realtime listener: ", err)
+		}
+	})
+	if err := listener.Listen(postgresChannel); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return &PostgresBroker{db: db, listener: listener}, nil
+}
+
+func (b *PostgresBroker) Publish(msg Message) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = b.db.Exec("SELECT pg_notify($1, $2)", postgresChannel, string(payload))
+	return err
+}
+
+// Subscribe hands the notifications to handle from a goroutine, it takes a single handler
+func (b *PostgresBroker) Subscribe(handle func(Message)) error {
+	go func() {
+		for notification := range b.listener.Notify {
+			// nil tells the connection was re-established
+			if notification == nil {
+				continue
+			}
+			msg := Message{}
+			if err := json.Unmarshal([]byte(notification.Extra), &msg); err != nil {
+				fmt.Println("this is the error reading a realtime message: ", err)
+				continue
+			}
+			handle(msg)
+		}
+	}()
+	return nil
+}
+
+func (b *PostgresBroker) Close() error {
+	return b.listener.Close()
+}
@@ -0,0 +1,143 @@
+package realtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// subscriptionBuffer is how many messages a slow client can fall behind before it misses some
+const subscriptionBuffer = 64
+
+// Message is an event pushed to the clients subscribed to its topic, Data is the JSON payload
+type Message struct {
+	Topic string          `json:"topic"`
+	Event string          `json:"event"`
+	Data  json.RawMessage `json:"data" swaggertype:"object"`
+}
+
+// PhotoTopic carries the new comments and the like counts of a photo
+func PhotoTopic(pid uint64) string {
+	return fmt.Sprintf("photo:%d", pid)
+}
+
+// UserTopic carries the new notifications of a user
+func UserTopic(uid uint32) string {
+	return fmt.Sprintf("user:%d", uid)
+}
+
+// Hub fans the messages out to the clients connected to this instance. Messages always go through the broker,
+// even those published here, so every instance sharing the broker delivers them the same way.
+type Hub struct {
+	broker Broker
+
+	mu     sync.RWMutex
+	topics map[string]map[*Subscription]struct{}
+	closed bool
+}
+
+// Subscription receives the messages of its topics on C until it is closed
+type Subscription struct {
+	C <-chan Message
+
+	hub    *Hub
+	c      chan Message
+	topics []string
+	once   sync.Once
+}
+
+func NewHub(broker Broker) (*Hub, error) {
+	h := &Hub{broker: broker, topics: make(map[string]map[*Subscription]struct{})}
+	if err := broker.Subscribe(h.dispatch); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// Publish sends data, marshalled to JSON, to the subscribers of topic on every instance
+func (h *Hub) Publish(topic, event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return h.broker.Publish(Message{Topic: topic, Event: event, Data: payload})
+}
+
+// Subscribe starts receiving the messages of the topics, the subscription must be closed when the client goes.
+// The subscriptions of a closed hub are closed from the start.
+func (h *Hub) Subscribe(topics ...string) *Subscription {
+	c := make(chan Message, subscriptionBuffer)
+	s := &Subscription{C: c, hub: h, c: c, topics: topics}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		s.once.Do(func() { close(s.c) })
+		return s
+	}
+	for _, topic := range topics {
+		if h.topics[topic] == nil {
+			h.topics[topic] = make(map[*Subscription]struct{})
+		}
+		h.topics[topic][s] = struct{}{}
+	}
+	return s
+}
+
+// Subscribers is how many clients of this instance follow the topic
+func (h *Hub) Subscribers(topic string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.topics[topic])
+}
+
+// Close stops the subscription and closes C
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		h := s.hub
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		for _, topic := range s.topics {
+			delete(h.topics[topic], s)
+			if len(h.topics[topic]) == 0 {
+				delete(h.topics, topic)
+			}
+		}
+		close(s.c)
+	})
+}
+
+// Close disconnects the hub from the broker and closes every subscription, which ends the streams.
+// Closing it again does nothing.
+func (h *Hub) Close() error {
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return nil
+	}
+	h.closed = true
+	subscriptions := []*Subscription{}
+	for _, topic := range h.topics {
+		for s := range topic {
+			subscriptions = append(subscriptions, s)
+		}
+	}
+	h.mu.Unlock()
+	// a subscription of several topics is listed more than once, closing it again does nothing
+	for _, s := range subscriptions {
+		s.Close()
+	}
+	return h.broker.Close()
+}
+
+// dispatch hands a message from the broker to the local subscribers of its topic. It never blocks:
+// a client too slow to keep up misses the message rather than holding up everyone else.
+func (h *Hub) dispatch(msg Message) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for s := range h.topics[msg.Topic] {
+		select {
+		case s.c <- msg:
+		default:
+		}
+	}
+}
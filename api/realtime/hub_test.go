@@ -0,0 +1,112 @@
+package realtime
+
+import (
+	"testing"
+)
+
+func newTestHub(t *testing.T) *Hub {
+	t.Helper()
+	hub, err := NewHub(NewMemoryBroker())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { hub.Close() })
+	return hub
+}
+
+// receive returns the message waiting on the subscription, ok is false when there is none
+func receive(s *Subscription) (msg Message, ok bool) {
+	select {
+	case msg, ok = <-s.C:
+		return msg, ok
+	default:
+		return Message{}, false
+	}
+}
+
+func TestHubFanOut(t *testing.T) {
+	hub := newTestHub(t)
+	first := hub.Subscribe(PhotoTopic(1))
+	second := hub.Subscribe(PhotoTopic(1), UserTopic(7))
+	other := hub.Subscribe(PhotoTopic(2))
+
+	if err := hub.Publish(PhotoTopic(1), "likes", map[string]int{"like_count": 3}); err != nil {
+		t.Fatal(err)
+	}
+	for name, s := range map[string]*Subscription{"first": first, "second": second} {
+		msg, ok := receive(s)
+		if !ok || msg.Topic != "photo:1" || msg.Event != "likes" || string(msg.Data) != `{"like_count":3}` {
+			t.Errorf("%s subscriber of the photo: got %+v %v", name, msg, ok)
+		}
+	}
+	if msg, ok := receive(other); ok {
+		t.Errorf("subscriber of another photo got %+v", msg)
+	}
+
+	hub.Publish(UserTopic(7), "notification", "hello")
+	if msg, ok := receive(second); !ok || msg.Topic != "user:7" {
+		t.Errorf("subscriber of two topics: got %+v %v from the second one", msg, ok)
+	}
+	if msg, ok := receive(first); ok {
+		t.Errorf("subscriber of the photo only got %+v", msg)
+	}
+}
+
+func TestHubSlowSubscriber(t *testing.T) {
+	hub := newTestHub(t)
+	slow := hub.Subscribe(PhotoTopic(1))
+	for i := 0; i < subscriptionBuffer+10; i++ {
+		if err := hub.Publish(PhotoTopic(1), "likes", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := len(slow.C); got != subscriptionBuffer {
+		t.Errorf("%d messages are waiting, want the %d of the buffer and the others dropped", got, subscriptionBuffer)
+	}
+}
+
+func TestSubscriptionClose(t *testing.T) {
+	hub := newTestHub(t)
+	s := hub.Subscribe(PhotoTopic(1), UserTopic(7))
+	kept := hub.Subscribe(PhotoTopic(1))
+
+	s.Close()
+	s.Close()
+	if _, ok := <-s.C; ok {
+		t.Error("C of a closed subscription is still open")
+	}
+	if n := hub.Subscribers(PhotoTopic(1)); n != 1 {
+		t.Errorf("the photo has %d subscribers, want the one left", n)
+	}
+	if n := hub.Subscribers(UserTopic(7)); n != 0 {
+		t.Errorf("the user topic has %d subscribers, want none", n)
+	}
+	// publishing after a subscriber left must not send on its closed channel
+	hub.Publish(PhotoTopic(1), "likes", 1)
+	if _, ok := receive(kept); !ok {
+		t.Error("the subscriber left got nothing")
+	}
+}
+
+func TestHubClose(t *testing.T) {
+	hub := newTestHub(t)
+	s := hub.Subscribe(PhotoTopic(1), UserTopic(7))
+
+	if err := hub.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-s.C; ok {
+		t.Error("C of a subscription is still open after the hub closed")
+	}
+	if err := hub.Close(); err != nil {
+		t.Errorf("closing the hub again: %v", err)
+	}
+	// a stream that starts during the shutdown ends right away
+	if _, ok := <-hub.Subscribe(PhotoTopic(1)).C; ok {
+		t.Error("a subscription of a closed hub is open")
+	}
+	s.Close()
+	if err := hub.Publish(PhotoTopic(1), "likes", 1); err != nil {
+		t.Errorf("publishing to a closed hub: %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package responses
+
+import (
+	"time"
+
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/models"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/policy"
+)
+
+type Comment struct {
+	ID        uint64    `json:"id"`
+	Message   string    `json:"message"`
+	User      User      `json:"user"`
+	UserID    uint32    `json:"user_id"`
+	PhotoID   uint64    `json:"photo_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	LikeCount int64     `json:"like_count"`
+	LikedByMe bool      `json:"liked_by_me"`
+
+	ParentID   *uint64   `json:"parent_id"`
+	Depth      int       `json:"depth"`
+	ReplyCount int64     `json:"reply_count"`
+	Mentions   []Mention `json:"mentions"`
+	// a deleted comment with replies keeps its place in the thread, without message or author
+	Deleted bool `json:"deleted,omitempty"`
+	// only present when the thread is returned as a tree
+	Replies []Comment `json:"replies,omitempty"`
+	// cursor of GET /comments/{id}/thread, present when the thread leaves out replies under the comment
+	MoreReplies string `json:"more_replies,omitempty"`
+}
+
+func NewComment(c models.Comment, viewer policy.Actor) Comment {
+	var replies []Comment
+	if c.Replies != nil {
+		replies = NewComments(c.Replies, viewer)
+	}
+	if c.Deleted {
+		return Comment{
+			ID:         c.ID,
+			PhotoID:    c.PhotoID,
+			CreatedAt:  c.CreatedAt,
+			UpdatedAt:  c.UpdatedAt,
+			ParentID:   c.ParentID,
+			Depth:      c.Depth,
+			ReplyCount: c.ReplyCount,
+			Deleted:    true,
+			Replies:    replies,
+
+			MoreReplies: c.MoreReplies,
+		}
+	}
+	return Comment{
+		ID:        c.ID,
+		Message:   c.Message,
+		User:      NewUser(c.User, viewer),
+		UserID:    c.UserID,
+		PhotoID:   c.PhotoID,
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+		LikeCount: c.LikeCount,
+		LikedByMe: c.LikedByMe,
+
+		ParentID:   c.ParentID,
+		Depth:      c.Depth,
+		ReplyCount: c.ReplyCount,
+		Mentions:   NewMentions(c.Mentions, viewer),
+		Replies:    replies,
+
+		MoreReplies: c.MoreReplies,
+	}
+}
+
+func NewComments(comments []models.Comment, viewer policy.Actor) []Comment {
+	list := make([]Comment, len(comments))
+	for i := range comments {
+		list[i] = NewComment(comments[i], viewer)
+	}
+	return list
+}
@@ -0,0 +1,46 @@
+package responses
+
+import (
+	"time"
+
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/models"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/policy"
+)
+
+// Export is the wire format of models.UserExport, rendered as the user would see their own data. The viewer is
+// never given the role of the user, an admin exporting their data does not get the contact details of others.
+type Export struct {
+	ExportedAt             time.Time              `json:"exported_at"`
+	User                   User                   `json:"user"`
+	Photos                 []Photo                `json:"photos"`
+	Comments               []Comment              `json:"comments"`
+	SocialMedia            []SocialMedia          `json:"social_media"`
+	Following              []User                 `json:"following"`
+	Followers              []User                 `json:"followers"`
+	LikedPhotoIDs          []uint64               `json:"liked_photo_ids"`
+	LikedCommentIDs        []uint64               `json:"liked_comment_ids"`
+	NotificationPreference NotificationPreference `json:"notification_preferences"`
+}
+
+func NewExport(e models.UserExport) Export {
+	viewer := policy.Actor{ID: e.User.ID, Role: policy.RoleUser}
+	export := Export{
+		ExportedAt:             time.Now(),
+		User:                   NewUser(e.User, viewer),
+		Photos:                 NewPhotos(e.Photos, viewer),
+		Comments:               NewComments(e.Comments, viewer),
+		SocialMedia:            NewSocialMediaList(e.SocialMedias, viewer),
+		Following:              make([]User, len(e.Following)),
+		Followers:              make([]User, len(e.Followers)),
+		LikedPhotoIDs:          append([]uint64{}, e.LikedPhotoIDs...),
+		LikedCommentIDs:        append([]uint64{}, e.LikedCommentIDs...),
+		NotificationPreference: NewNotificationPreference(e.NotificationPreference),
+	}
+	for i := range e.Following {
+		export.Following[i] = NewUser(e.Following[i], viewer)
+	}
+	for i := range e.Followers {
+		export.Followers[i] = NewUser(e.Followers[i], viewer)
+	}
+	return export
+}
@@ -0,0 +1,47 @@
+package responses
+
+import (
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/models"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/policy"
+)
+
+// Profile is a user as shown on their own page, with the size of their follow graph
+type Profile struct {
+	User
+	FollowerCount  int64 `json:"follower_count"`
+	FollowingCount int64 `json:"following_count"`
+	FollowedByMe   bool  `json:"followed_by_me"`
+}
+
+// FollowStatus is returned after following or unfollowing a user
+type FollowStatus struct {
+	FollowerCount int64 `json:"follower_count"`
+	FollowedByMe  bool  `json:"followed_by_me"`
+}
+
+func NewProfile(u models.User, viewer policy.Actor) Profile {
+	return Profile{
+		User:           NewUser(u, viewer),
+		FollowerCount:  u.FollowerCount,
+		FollowingCount: u.FollowingCount,
+		FollowedByMe:   u.FollowedByMe,
+	}
+}
+
+// NewFollowers lists the users following someone
+func NewFollowers(follows []models.Follow, viewer policy.Actor) []User {
+	list := make([]User, len(follows))
+	for i := range follows {
+		list[i] = NewUser(follows[i].Follower, viewer)
+	}
+	return list
+}
+
+// NewFollowing lists the users someone follows
+func NewFollowing(follows []models.Follow, viewer policy.Actor) []User {
+	list := make([]User, len(follows))
+	for i := range follows {
+		list[i] = NewUser(follows[i].Followee, viewer)
+	}
+	return list
+}
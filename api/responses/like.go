@@ -0,0 +1,14 @@
+package responses
+
+// LikeStatus is returned after liking or unliking a photo or comment
+type LikeStatus struct {
+	LikeCount int64 `json:"like_count"`
+	LikedByMe bool  `json:"liked_by_me"`
+}
+
+// LikeCount is pushed to the streams of a photo when the likes of the photo or of one of its comments change
+type LikeCount struct {
+	PhotoID   uint64  `json:"photo_id"`
+	CommentID *uint64 `json:"comment_id,omitempty"`
+	LikeCount int64   `json:"like_count"`
+}
@@ -0,0 +1,23 @@
+package responses
+
+import (
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/models"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/policy"
+)
+
+// Mention is an @username of a caption or a comment. Start and End are offsets in characters
+// (unicode code points) into the text: Start is the offset of the @, End the offset just after the username.
+type Mention struct {
+	UserID uint32 `json:"user_id"`
+	User   User   `json:"user"`
+	Start  int    `json:"start"`
+	End    int    `json:"end"`
+}
+
+func NewMentions(mentions []models.Mention, viewer policy.Actor) []Mention {
+	list := make([]Mention, len(mentions))
+	for i, m := range mentions {
+		list[i] = Mention{UserID: m.UserID, User: NewUser(m.User, viewer), Start: m.Start, End: m.End}
+	}
+	return list
+}
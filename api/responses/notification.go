@@ -0,0 +1,64 @@
+package responses
+
+import (
+	"time"
+
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/models"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/policy"
+)
+
+type Notification struct {
+	ID        uint64    `json:"id"`
+	Kind      string    `json:"kind"`
+	Actor     User      `json:"actor"`
+	PhotoID   *uint64   `json:"photo_id"`
+	CommentID *uint64   `json:"comment_id"`
+	Read      bool      `json:"read"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func NewNotifications(notifications []models.Notification, viewer policy.Actor) []Notification {
+	list := make([]Notification, len(notifications))
+	for i, n := range notifications {
+		list[i] = Notification{
+			ID:        n.ID,
+			Kind:      n.Kind,
+			Actor:     NewUser(n.Actor, viewer),
+			PhotoID:   n.PhotoID,
+			CommentID: n.CommentID,
+			Read:      n.ReadAt != nil,
+			CreatedAt: n.CreatedAt,
+		}
+	}
+	return list
+}
+
+// UnreadCount is how many notifications a user has not read, in total and per kind
+type UnreadCount struct {
+	Total  int64            `json:"total"`
+	ByKind map[string]int64 `json:"by_kind"`
+}
+
+type NotificationPreference struct {
+	Comment bool `json:"comment"`
+	Reply   bool `json:"reply"`
+	Mention bool `json:"mention"`
+	Follow  bool `json:"follow"`
+	Like    bool `json:"like"`
+}
+
+func NewUnreadCount(counts map[string]int64) UnreadCount {
+	unread := UnreadCount{ByKind: make(map[string]int64, len(models.NotificationKinds))}
+	for _, kind := range models.NotificationKinds {
+		unread.ByKind[kind] = 0
+	}
+	for kind, count := range counts {
+		unread.ByKind[kind] = count
+		unread.Total += count
+	}
+	return unread
+}
+
+func NewNotificationPreference(p models.NotificationPreference) NotificationPreference {
+	return NotificationPreference{Comment: p.Comment, Reply: p.Reply, Mention: p.Mention, Follow: p.Follow, Like: p.Like}
+}
@@ -0,0 +1,94 @@
+package responses
+
+import (
+	"time"
+
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/models"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/policy"
+)
+
+type Photo struct {
+	ID        uint64    `json:"id"`
+	Title     string    `json:"title"`
+	Caption   string    `json:"caption"`
+	PhotoURL  string    `json:"photo_url"`
+	User      User      `json:"user"`
+	UserID    uint32    `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	MimeType string `json:"mime_type,omitempty"`
+	Width    int    `json:"width,omitempty"`
+	Height   int    `json:"height,omitempty"`
+	ByteSize int64  `json:"byte_size,omitempty"`
+
+	TakenAt     *time.Time `json:"taken_at,omitempty"`
+	CameraModel string     `json:"camera_model,omitempty"`
+	Orientation int        `json:"orientation,omitempty"`
+
+	LikeCount int64 `json:"like_count"`
+	LikedByMe bool  `json:"liked_by_me"`
+
+	CommentCount int64     `json:"comment_count"`
+	Tags         []string  `json:"tags"`
+	Mentions     []Mention `json:"mentions"`
+	// only present with ?include=comments
+	Comments []Comment `json:"comments,omitempty"`
+
+	// keyed by variant name, e.g. "thumbnail"; absent until the variants are generated
+	Variants map[string]PhotoVariant `json:"variants,omitempty"`
+}
+
+type PhotoVariant struct {
+	URL      string `json:"url"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+	MimeType string `json:"mime_type"`
+}
+
+func NewPhoto(p models.Photo, viewer policy.Actor) Photo {
+	var variants map[string]PhotoVariant
+	if len(p.Variants) > 0 {
+		variants = make(map[string]PhotoVariant, len(p.Variants))
+		for _, v := range p.Variants {
+			variants[v.Name] = PhotoVariant{URL: v.URL, Width: v.Width, Height: v.Height, MimeType: v.MimeType}
+		}
+	}
+	var comments []Comment
+	if p.Comments != nil {
+		comments = NewComments(p.Comments, viewer)
+	}
+	return Photo{
+		ID:          p.ID,
+		Title:       p.Title,
+		Caption:     p.Caption,
+		PhotoURL:    p.PhotoURL,
+		User:        NewUser(p.User, viewer),
+		UserID:      p.UserID,
+		CreatedAt:   p.CreatedAt,
+		UpdatedAt:   p.UpdatedAt,
+		MimeType:    p.MimeType,
+		Width:       p.Width,
+		Height:      p.Height,
+		ByteSize:    p.ByteSize,
+		TakenAt:     p.TakenAt,
+		CameraModel: p.CameraModel,
+		Orientation: p.Orientation,
+		LikeCount:   p.LikeCount,
+		LikedByMe:   p.LikedByMe,
+		Variants:    variants,
+
+		CommentCount: p.CommentCount,
+		Tags:         p.Tags,
+		Mentions:     NewMentions(p.Mentions, viewer),
+		Comments:     comments,
+	}
+}
+
+func NewPhotos(photos []models.Photo, viewer policy.Actor) []Photo {
+	list := make([]Photo, len(photos))
+	for i := range photos {
+		list[i] = NewPhoto(photos[i], viewer)
+	}
+	return list
+}
@@ -0,0 +1,38 @@
+package responses
+
+import (
+	"time"
+
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/models"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/policy"
+)
+
+type SocialMedia struct {
+	ID             uint64    `json:"id"`
+	Name           string    `json:"name"`
+	SocialMediaURL string    `json:"socialMediaURL"`
+	User           User      `json:"user"`
+	UserID         uint32    `json:"user_id"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+func NewSocialMedia(s models.SocialMedia, viewer policy.Actor) SocialMedia {
+	return SocialMedia{
+		ID:             s.ID,
+		Name:           s.Name,
+		SocialMediaURL: s.SocialMediaURL,
+		User:           NewUser(s.User, viewer),
+		UserID:         s.UserID,
+		CreatedAt:      s.CreatedAt,
+		UpdatedAt:      s.UpdatedAt,
+	}
+}
+
+func NewSocialMediaList(socialMedias []models.SocialMedia, viewer policy.Actor) []SocialMedia {
+	list := make([]SocialMedia, len(socialMedias))
+	for i := range socialMedias {
+		list[i] = NewSocialMedia(socialMedias[i], viewer)
+	}
+	return list
+}
@@ -0,0 +1,33 @@
+package responses
+
+import (
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/models"
+)
+
+type Tag struct {
+	Name       string `json:"name"`
+	PhotoCount int64  `json:"photo_count"`
+}
+
+// TrendingTag is a tag with how many photos got it within the trending window
+type TrendingTag struct {
+	Name        string `json:"name"`
+	PhotoCount  int64  `json:"photo_count"`
+	RecentCount int64  `json:"recent_count"`
+}
+
+func NewTags(tags []models.Tag) []Tag {
+	list := make([]Tag, len(tags))
+	for i, tag := range tags {
+		list[i] = Tag{Name: tag.Name, PhotoCount: tag.PhotoCount}
+	}
+	return list
+}
+
+func NewTrendingTags(tags []models.Tag) []TrendingTag {
+	list := make([]TrendingTag, len(tags))
+	for i, tag := range tags {
+		list[i] = TrendingTag{Name: tag.Name, PhotoCount: tag.PhotoCount, RecentCount: tag.RecentCount}
+	}
+	return list
+}
@@ -0,0 +1,42 @@
+package responses
+
+import (
+	"time"
+
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/models"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/policy"
+)
+
+// User is the wire format of models.User. The password hash is never sent, contact details
+// (email, age, verification state, role) only to the user themselves and to admins.
+type User struct {
+	ID              uint32     `json:"id"`
+	Username        string     `json:"username"`
+	Email           string     `json:"email,omitempty"`
+	Age             uint32     `json:"age,omitempty"`
+	Role            string     `json:"role,omitempty"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// canSeePrivate reports whether the viewer may see the contact details of the user
+func canSeePrivate(u models.User, viewer policy.Actor) bool {
+	return policy.CanModify(viewer, u.ID, policy.ManageUsers)
+}
+
+func NewUser(u models.User, viewer policy.Actor) User {
+	user := User{
+		ID:        u.ID,
+		Username:  u.Username,
+		CreatedAt: u.CreatedAt,
+		UpdatedAt: u.UpdatedAt,
+	}
+	if canSeePrivate(u, viewer) {
+		user.Email = u.Email
+		user.Age = u.Age
+		user.Role = u.Role
+		user.EmailVerifiedAt = u.EmailVerifiedAt
+	}
+	return user
+}
@@ -2,6 +2,7 @@ package security
 
 import (
 	"crypto/md5"
+	"crypto/sha256"
 	"encoding/hex"
 	"github.com/twinj/uuid"
 
@@ -18,4 +19,11 @@ func TokenHash(text string) string {
 	theToken := theHash + u.String()
 
 	return theToken
-}
\ No newline at end of file
+}
+
+// Digest hashes an opaque token for storage, so a leaked table cannot be replayed
+func Digest(token string) string {
+
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,64 @@
+package seed
+
+import (
+	"time"
+
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/models"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/policy"
+)
+
+// seeded accounts are already verified so they can post right away
+var seededAt = time.Now()
+
+// demoSet fills a local or demo server with a few accounts talking to each other
+var demoSet = Set{
+	Users: []models.User{
+		{
+			Username:        "admin",
+			Email:           "admin@gmail.com",
+			Password:        "password",
+			Age:             17,
+			Role:            policy.RoleAdmin,
+			EmailVerifiedAt: &seededAt,
+		},
+		{
+			Username:        "udin",
+			Email:           "udin@gmail.com",
+			Password:        "password",
+			Age:             19,
+			EmailVerifiedAt: &seededAt,
+		},
+		{
+			Username:        "rizal",
+			Email:           "rizal@gmail.com",
+			Password:        "password",
+			Age:             13,
+			EmailVerifiedAt: &seededAt,
+		},
+	},
+	Photos: []Photo{
+		{
+			Owner:    "udin",
+			Title:    "Morning at the beach",
+			Caption:  "First light over the water #sunrise #beach with @rizal",
+			PhotoURL: "https://images.unsplash.com/photo-1507525428034-b723cf961d3e",
+		},
+		{
+			Owner:    "rizal",
+			Title:    "Cat in sunglasses",
+			Caption:  "Too cool for the hammock #cat",
+			PhotoURL: "https://media.istockphoto.com/id/1322123064/photo/portrait-of-an-adorable-white-cat-in-sunglasses-and-an-shirt-lies-on-a-fabric-hammock.jpg",
+		},
+	},
+	Comments: []Comment{
+		{Author: "rizal", Photo: "Morning at the beach", Message: "Worth getting up that early!"},
+		{Author: "udin", Photo: "Cat in sunglasses", Message: "@rizal is this your cat? #cat"},
+	},
+	Follows: []Follow{
+		{Follower: "udin", Followee: "rizal"},
+		{Follower: "rizal", Followee: "udin"},
+	},
+	SocialMedias: []SocialMedia{
+		{Owner: "rizal", Name: "rizal on instagram", URL: "https://www.instagram.com/ahmadnurrizal/"},
+	},
+}
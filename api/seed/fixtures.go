@@ -0,0 +1,55 @@
+package seed
+
+import (
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/models"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/policy"
+)
+
+// testSet holds the fixtures of the integration tests: an admin, two verified users
+// and one who never verified their email, every password being "password"
+var testSet = Set{
+	Users: []models.User{
+		{
+			Username:        "test_admin",
+			Email:           "test_admin@example.com",
+			Password:        "password",
+			Age:             30,
+			Role:            policy.RoleAdmin,
+			EmailVerifiedAt: &seededAt,
+		},
+		{
+			Username:        "test_alice",
+			Email:           "test_alice@example.com",
+			Password:        "password",
+			Age:             25,
+			EmailVerifiedAt: &seededAt,
+		},
+		{
+			Username:        "test_bob",
+			Email:           "test_bob@example.com",
+			Password:        "password",
+			Age:             25,
+			EmailVerifiedAt: &seededAt,
+		},
+		{
+			Username: "test_unverified",
+			Email:    "test_unverified@example.com",
+			Password: "password",
+			Age:      25,
+		},
+	},
+	Photos: []Photo{
+		{
+			Owner:    "test_alice",
+			Title:    "test photo of alice",
+			Caption:  "fixture #test for @test_bob",
+			PhotoURL: "https://example.com/test/alice.jpg",
+		},
+	},
+	Comments: []Comment{
+		{Author: "test_bob", Photo: "test photo of alice", Message: "fixture comment of bob"},
+	},
+	Follows: []Follow{
+		{Follower: "test_bob", Followee: "test_alice"},
+	},
+}
@@ -0,0 +1,20 @@
+package seed
+
+import (
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/migrations"
+	"github.com/jinzhu/gorm"
+)
+
+// Reset rolls every migration back and applies them again: ALL THE DATA IS DELETED.
+// It is only run when asked for explicitly, to start a test or demo database from scratch.
+func Reset(db *gorm.DB) error {
+	// a database from before the migrations is brought under them first, so going down drops its tables too
+	if _, err := migrations.Up(db, 0); err != nil {
+		return err
+	}
+	if _, err := migrations.Down(db, 0); err != nil {
+		return err
+	}
+	_, err := migrations.Up(db, 0)
+	return err
+}
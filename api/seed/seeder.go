@@ -1,77 +1,231 @@
 package seed
 
 import (
-	"log"
+	"fmt"
+	"sort"
 
 	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/models"
 	"github.com/jinzhu/gorm"
 )
 
-var users = []models.User{
-	models.User{
-		Username: "admin",
-		Email:    "admin@gmail.com",
-		Password: "password",
-		Age:      17,
-	},
-	models.User{
-		Username: "udin",
-		Email:    "udin@gmail.com",
-		Password: "password",
-		Age:      19,
-	},
-	models.User{
-		Username: "rizal",
-		Email:    "rizal@gmail.com",
-		Password: "password",
-		Age:      13,
-	},
+// Set is a named batch of seed data. Rows point at each other by username and photo title,
+// which are unique, so a set reads the same whatever ids the database hands out.
+type Set struct {
+	Users        []models.User
+	Photos       []Photo
+	Comments     []Comment
+	Follows      []Follow
+	SocialMedias []SocialMedia
 }
 
-func Load(db *gorm.DB) {
+type Photo struct {
+	Owner    string
+	Title    string
+	Caption  string
+	PhotoURL string
+}
 
-	// make sure drop and migrate table in correct order
-	// or can avoid error by remove foreign key constraint first
-	// db.Model(&models.Comment{}).RemoveForeignKey("user_id", "users(id)")
-	// db.Model(&models.Comment{}).RemoveForeignKey("photo_id", "photos(id)")
-	err := db.Debug().DropTableIfExists(&models.SocialMedia{}, &models.Comment{}, &models.Photo{}, &models.User{}).Error
-	if err != nil {
-		log.Fatalf("cannot drop table: %v", err)
+// Comment is a top level comment of Author on the photo titled Photo
+type Comment struct {
+	Author  string
+	Photo   string
+	Message string
+}
+
+type Follow struct {
+	Follower string
+	Followee string
+}
+
+type SocialMedia struct {
+	Owner string
+	Name  string
+	URL   string
+}
+
+// sets are the seed sets by name, see demo.go and fixtures.go
+var sets = map[string]Set{
+	"demo": demoSet,
+	"test": testSet,
+}
+
+// Names lists the seed sets Load knows
+func Names() []string {
+	names := make([]string, 0, len(sets))
+	for name := range sets {
+		names = append(names, name)
 	}
-	err = db.Debug().AutoMigrate(&models.User{}, &models.Photo{}, &models.SocialMedia{}, &models.Comment{}).Error
-	if err != nil {
-		log.Fatalf("cannot migrate table: %v", err)
+	sort.Strings(names)
+	return names
+}
+
+// Exists reports whether there is a seed set called name
+func Exists(name string) bool {
+	_, ok := sets[name]
+	return ok
+}
+
+// Load upserts the seed set called name. Rows are matched on their natural keys (email, photo title,
+// social media name, the text of a comment) and only created when missing, so loading a set twice
+// leaves the database as loading it once, and nothing that is not part of the set is touched.
+// Existing users keep their password.
+func Load(db *gorm.DB, name string) error {
+	set, ok := sets[name]
+	if !ok {
+		return fmt.Errorf("unknown seed set %q, expected one of %v", name, Names())
+	}
+	userIDs := make(map[string]uint32, len(set.Users))
+	for _, user := range set.Users {
+		id, err := upsertUser(db, user)
+		if err != nil {
+			return fmt.Errorf("cannot seed user %s: %v", user.Username, err)
+		}
+		userIDs[user.Username] = id
+	}
+	owner := func(username string) (uint32, error) {
+		id, ok := userIDs[username]
+		if !ok {
+			return 0, fmt.Errorf("user %s is not part of the set", username)
+		}
+		return id, nil
 	}
 
-	err = db.Debug().Model(&models.Photo{}).AddForeignKey("user_id", "users(id)", "cascade", "cascade").Error
-	if err != nil {
-		log.Fatalf("attaching foreign key error: %v", err)
+	photoIDs := make(map[string]uint64, len(set.Photos))
+	for _, photo := range set.Photos {
+		uid, err := owner(photo.Owner)
+		if err == nil {
+			photoIDs[photo.Title], err = upsertPhoto(db, uid, photo)
+		}
+		if err != nil {
+			return fmt.Errorf("cannot seed photo %s: %v", photo.Title, err)
+		}
+	}
+	for _, comment := range set.Comments {
+		uid, err := owner(comment.Author)
+		if err == nil {
+			pid, ok := photoIDs[comment.Photo]
+			if !ok {
+				err = fmt.Errorf("photo %s is not part of the set", comment.Photo)
+			} else {
+				err = upsertComment(db, uid, pid, comment.Message)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("cannot seed comment %q: %v", comment.Message, err)
+		}
 	}
+	for _, follow := range set.Follows {
+		follower, err := owner(follow.Follower)
+		if err != nil {
+			return fmt.Errorf("cannot seed follow: %v", err)
+		}
+		followee, err := owner(follow.Followee)
+		if err != nil {
+			return fmt.Errorf("cannot seed follow: %v", err)
+		}
+		if err = upsertFollow(db, follower, followee); err != nil {
+			return fmt.Errorf("cannot seed follow of %s by %s: %v", follow.Followee, follow.Follower, err)
+		}
+	}
+	for _, socialMedia := range set.SocialMedias {
+		uid, err := owner(socialMedia.Owner)
+		if err == nil {
+			err = upsertSocialMedia(db, uid, socialMedia)
+		}
+		if err != nil {
+			return fmt.Errorf("cannot seed social media %s: %v", socialMedia.Name, err)
+		}
+	}
+	return nil
+}
 
-	err = db.Debug().Model(&models.Comment{}).AddForeignKey("user_id", "users(id)", "cascade", "cascade").Error
+// upsertUser creates the user, or brings the profile of the user with the same email in line with the set.
+// The role of an existing account is left alone, seeding never promotes or demotes anyone.
+func upsertUser(db *gorm.DB, user models.User) (uint32, error) {
+	found := models.User{}
+	err := db.Debug().Model(&models.User{}).Where("email = ?", user.Email).Take(&found).Error
+	if gorm.IsRecordNotFoundError(err) {
+		// Create goes through BeforeSave, which hashes the password
+		err = db.Debug().Model(&models.User{}).Create(&user).Error
+		return user.ID, err
+	}
 	if err != nil {
-		log.Fatalf("attaching foreign key error: %v", err)
+		return 0, err
+	}
+	updates := map[string]interface{}{"username": user.Username, "age": user.Age}
+	if found.EmailVerifiedAt == nil && user.EmailVerifiedAt != nil {
+		updates["email_verified_at"] = user.EmailVerifiedAt
 	}
+	err = db.Debug().Model(&models.User{}).Where("id = ?", found.ID).UpdateColumns(updates).Error
+	return found.ID, err
+}
 
-	err = db.Debug().Model(&models.SocialMedia{}).AddForeignKey("user_id", "users(id)", "cascade", "cascade").Error
+// upsertPhoto creates the photo, or updates the caption of the photo with the same title. A photo of the same
+// title posted by someone else is left alone and reported, titles are unique.
+func upsertPhoto(db *gorm.DB, uid uint32, seed Photo) (uint64, error) {
+	photo := models.Photo{Title: seed.Title, Caption: seed.Caption, PhotoURL: seed.PhotoURL, UserID: uid}
+	photo.Prepare()
+	found := models.Photo{}
+	err := db.Debug().Model(&models.Photo{}).Where("title = ?", photo.Title).Take(&found).Error
+	if gorm.IsRecordNotFoundError(err) {
+		// SavePhoto keeps the tags and the mentions of the caption
+		_, err = photo.SavePhoto(db)
+		return photo.ID, err
+	}
 	if err != nil {
-		log.Fatalf("attaching foreign key error: %v", err)
+		return 0, err
+	}
+	if found.UserID != uid {
+		return 0, fmt.Errorf("the title is taken by user %d", found.UserID)
+	}
+	if found.Caption != photo.Caption {
+		photo.ID = found.ID
+		if _, err = photo.UpdateAPhoto(db); err != nil {
+			return 0, err
+		}
 	}
+	return found.ID, nil
+}
 
-	err = db.Debug().Model(&models.Photo{}).AddForeignKey("user_id", "users(id)", "cascade", "cascade").Error
-	if err != nil {
-		log.Fatalf("attaching foreign key error: %v", err)
+func upsertComment(db *gorm.DB, uid uint32, pid uint64, message string) error {
+	comment := models.Comment{Message: message, UserID: uid, PhotoID: pid}
+	comment.Prepare()
+	var count int64
+	err := db.Debug().Model(&models.Comment{}).
+		Where("user_id = ? AND photo_id = ? AND message = ? AND parent_id IS NULL", uid, pid, comment.Message).Count(&count).Error
+	if err != nil || count > 0 {
+		return err
+	}
+	_, err = comment.SaveComment(db)
+	return err
+}
+
+func upsertFollow(db *gorm.DB, follower, followee uint32) error {
+	var count int64
+	err := db.Debug().Model(&models.Follow{}).Where("follower_id = ? AND followee_id = ?", follower, followee).Count(&count).Error
+	if err != nil || count > 0 {
+		return err
 	}
+	follow := models.Follow{FollowerID: follower, FolloweeID: followee}
+	_, err = follow.SaveFollow(db)
+	return err
+}
 
-	err = db.Debug().Model(&models.Comment{}).AddForeignKey("photo_id", "photos(id)", "cascade", "cascade").Error
+// upsertSocialMedia creates the social media, or points the one of the same name at the URL of the set
+func upsertSocialMedia(db *gorm.DB, uid uint32, seed SocialMedia) error {
+	socialMedia := models.SocialMedia{Name: seed.Name, SocialMediaURL: seed.URL, UserID: uid}
+	socialMedia.Prepare()
+	found := models.SocialMedia{}
+	err := db.Debug().Model(&models.SocialMedia{}).Where("name = ?", socialMedia.Name).Take(&found).Error
+	if gorm.IsRecordNotFoundError(err) {
+		_, err = socialMedia.SaveSocialMedia(db)
+		return err
+	}
 	if err != nil {
-		log.Fatalf("attaching foreign key error: %v", err)
+		return err
 	}
-
-	for i, _ := range users {
-		err = db.Debug().Model(&models.User{}).Create(&users[i]).Error
-		if err != nil {
-			log.Fatalf("cannot seed users table: %v", err)
-		}
+	if found.UserID != uid {
+		return fmt.Errorf("the name is taken by user %d", found.UserID)
 	}
+	return db.Debug().Model(&models.SocialMedia{}).Where("id = ?", found.ID).UpdateColumn("social_media_url", socialMedia.SocialMediaURL).Error
 }
@@ -1,42 +1,177 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"log"
-	"os"
+	"time"
 
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/config"
 	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/controllers"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/feed"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/migrations"
 	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/seed"
-	"github.com/joho/godotenv"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/storage"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/variants"
 )
 
 var server = controllers.Server{}
 
-func init() {
-	// loads values from .env into the system
-	if err := godotenv.Load(); err != nil {
-		log.Print("sad .env file found")
+// configFile is the optional config file named by the -config flag or CONFIG_FILE, see config.Load
+var configFile string
+
+// loadConfig reads the configuration every command starts from, a missing or invalid setting stops the command
+func loadConfig() *config.Config {
+	cfg, err := config.Load(configFile)
+	if err != nil {
+		log.Fatal(err)
 	}
+	return cfg
 }
 
 func Run() {
 
-	var err error
-	err = godotenv.Load()
+	cfg := loadConfig()
+	// the secrets are redacted
+	fmt.Printf("We are getting values\n%s", cfg)
+
+	server.Initialize(cfg)
+
+	fmt.Printf("Listening to port %s", cfg.App.Addr())
+
+	if err := server.Run(); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("Stopped")
+
+}
+
+// BackfillVariants generates the variants of photos uploaded before they existed, or whose generation failed
+func BackfillVariants() {
+
+	cfg := loadConfig()
+	server.Open(cfg)
+	defer server.DB.Close()
+	// the backfill generates one photo at a time itself, the queue and its worker stay idle
+	store, err := storage.New(cfg.Storage)
 	if err != nil {
-		log.Fatalf("Error getting env, %v", err)
-	} else {
-		fmt.Println("We are getting values")
+		log.Fatalf("Cannot set up the photo storage: %v", err)
 	}
+	generator := variants.NewGenerator(server.DB, store, 1, cfg.Uploads.MaxPixels)
 
-	server.Initialize(os.Getenv("DB_DRIVER"), os.Getenv("PGUSER"), os.Getenv("PGPASSWORD"), os.Getenv("PGPORT"), os.Getenv("PGHOST"), os.Getenv("PGDATABASE"))
+	processed, err := generator.Backfill(context.Background())
+	generator.Close()
+	if err != nil {
+		log.Fatalf("Cannot backfill photo variants: %v", err)
+	}
+	fmt.Printf("Generated variants for %d photos\n", processed)
+}
 
-	// This is for testing, when done, do well to comment
-	seed.Load(server.DB)
+// Seed upserts the seed set called name, see seed.Load. With reset every migration is rolled back and applied
+// again first, which deletes all the data. Every set holds an admin whose password is "password", so seeding
+// is refused when APP_ENV is production: create-admin makes the admins there.
+func Seed(name string, reset bool) {
 
-	apiPort := fmt.Sprintf("%s:%s", os.Getenv("APP_HOST"), os.Getenv("PORT"))
-	fmt.Printf("Listening to port %s", apiPort)
+	// checked before anything is reset
+	if !seed.Exists(name) {
+		log.Fatalf("Unknown seed set %q, expected one of %v", name, seed.Names())
+	}
+	cfg := loadConfig()
+	if cfg.App.IsProduction() {
+		log.Fatal("Refusing to seed the database with APP_ENV=production, the seed sets hold known admin passwords")
+	}
+	if reset {
+		// before Open, which refuses a database with pending migrations
+		server.Connect(cfg.Database)
+		if err := seed.Reset(server.DB); err != nil {
+			log.Fatalf("Cannot reset the database: %v", err)
+		}
+		server.DB.Close()
+	}
+	server.Open(cfg)
+	defer server.DB.Close()
 
-	server.Run(apiPort)
+	if err := seed.Load(server.DB, name); err != nil {
+		log.Fatalf("Cannot seed the database: %v", err)
+	}
+	fmt.Printf("Seeded the %s set\n", name)
+	if cfg.Feed.Strategy == "write" {
+		fmt.Println("The feeds are precomputed, run the rebuild-feed command to add the seeded photos to them")
+	}
+}
+
+// Migrate runs the migrate command: up applies the pending migrations, down rolls back the latest ones and
+// status lists them all. steps caps how many are applied or rolled back, 0 applies them all but rolls back one.
+// Rolling back drops tables, so down is refused when APP_ENV is production.
+func Migrate(command string, steps int) {
 
+	cfg := loadConfig()
+	if command == "down" && cfg.App.IsProduction() {
+		log.Fatal("Refusing to roll back migrations with APP_ENV=production")
+	}
+	if command != "up" && command != "down" && command != "status" {
+		log.Fatalf("Unknown migrate command %q, expected up, down or status", command)
+	}
+	server.Connect(cfg.Database)
+	fmt.Println()
+
+	switch command {
+	case "up":
+		done, err := migrations.Up(server.DB, steps)
+		for _, migration := range done {
+			fmt.Printf("Applied %d %s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatalf("Cannot apply the migrations: %v", err)
+		}
+		if len(done) == 0 {
+			fmt.Println("The database is up to date")
+		}
+	case "down":
+		if steps == 0 {
+			steps = 1
+		}
+		done, err := migrations.Down(server.DB, steps)
+		for _, migration := range done {
+			fmt.Printf("Rolled back %d %s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatalf("Cannot roll back the migrations: %v", err)
+		}
+		if len(done) == 0 {
+			fmt.Println("No migration is applied")
+		}
+	case "status":
+		states, err := migrations.Status(server.DB)
+		if err != nil {
+			log.Fatalf("Cannot read the migrations: %v", err)
+		}
+		for _, state := range states {
+			applied := "pending"
+			if state.AppliedAt != nil {
+				applied = "applied " + state.AppliedAt.Format(time.RFC3339)
+			}
+			if state.Unknown {
+				applied += ", unknown to this build"
+			}
+			fmt.Printf("%4d  %-30s %s\n", state.Version, state.Name, applied)
+		}
+	}
+}
+
+// RebuildFeed recomputes the precomputed feeds, needed once when FEED_STRATEGY switches to write
+func RebuildFeed() {
+
+	cfg := loadConfig()
+	if cfg.Feed.Strategy != "write" {
+		log.Fatal("The feed is built at read time, set FEED_STRATEGY=write to precompute it")
+	}
+	server.Open(cfg)
+	defer server.DB.Close()
+
+	entries, err := feed.NewWriteFeed(server.DB, cfg.Feed.BackfillLimit).Rebuild()
+	if err != nil {
+		log.Fatalf("Cannot rebuild the feeds: %v", err)
+	}
+	fmt.Printf("Wrote %d feed entries\n", entries)
 }
@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+)
+
+// MemoryStorage keeps objects in a map. It is meant for tests and local development.
+type MemoryStorage struct {
+	mu        sync.RWMutex
+	objects   map[string][]byte
+	types     map[string]string
+	publicURL string
+}
+
+func NewMemoryStorage(publicURL string) *MemoryStorage {
+	return &MemoryStorage{
+		objects:   map[string][]byte{},
+		types:     map[string]string{},
+		publicURL: strings.TrimRight(publicURL, "/"),
+	}
+}
+
+func (s *MemoryStorage) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	key, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[key] = data
+	s.types[key] = contentType
+	return nil
+}
+
+func (s *MemoryStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	data, ok := s.objects[strings.TrimLeft(key, "/")]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s *MemoryStorage) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.objects, strings.TrimLeft(key, "/"))
+	delete(s.types, strings.TrimLeft(key, "/"))
+	return nil
+}
+
+func (s *MemoryStorage) URL(key string) string {
+	return s.publicURL + "/" + strings.TrimLeft(key, "/")
+}
+
+// Keys lists the stored keys, handy for asserting what an upload produced
+func (s *MemoryStorage) Keys() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := make([]string, 0, len(s.objects))
+	for key := range s.objects {
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -0,0 +1,232 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Config describes an S3 compatible bucket (AWS S3, MinIO, Ceph, R2, ...)
+type S3Config struct {
+	// Endpoint is the API base URL, e.g. https://s3.eu-west-1.amazonaws.com or http://localhost:9000
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	// PublicURL overrides the download address, e.g. a CDN in front of the bucket
+	PublicURL string
+	// ForcePathStyle addresses objects as endpoint/bucket/key instead of bucket.endpoint/key, MinIO needs this
+	ForcePathStyle bool
+}
+
+// S3Storage talks to the S3 REST API directly, requests are signed with AWS Signature Version 4
+type S3Storage struct {
+	config   S3Config
+	endpoint *url.URL
+	client   *http.Client
+}
+
+func NewS3Storage(config S3Config) (*S3Storage, error) {
+	if config.Endpoint == "" || config.Bucket == "" {
+		return nil, errors.New("s3 storage needs an endpoint and a bucket")
+	}
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+	endpoint, err := url.Parse(strings.TrimRight(config.Endpoint, "/"))
+	if err != nil {
+		return nil, err
+	}
+	return &S3Storage{
+		config:   config,
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: time.Minute},
+	}, nil
+}
+
+func (s *S3Storage) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	response, err := s.do(ctx, http.MethodPut, key, data, contentType)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return s3Error(response)
+	}
+	return nil
+}
+
+func (s *S3Storage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	response, err := s.do(ctx, http.MethodGet, key, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode == http.StatusNotFound {
+		response.Body.Close()
+		return nil, ErrNotFound
+	}
+	if response.StatusCode != http.StatusOK {
+		defer response.Body.Close()
+		return nil, s3Error(response)
+	}
+	return response.Body, nil
+}
+
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	response, err := s.do(ctx, http.MethodDelete, key, nil, "")
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusNoContent && response.StatusCode != http.StatusOK && response.StatusCode != http.StatusNotFound {
+		return s3Error(response)
+	}
+	return nil
+}
+
+func (s *S3Storage) URL(key string) string {
+	if s.config.PublicURL != "" {
+		return strings.TrimRight(s.config.PublicURL, "/") + "/" + uriEncode(strings.TrimLeft(key, "/"), false)
+	}
+	return s.objectURL(key).String()
+}
+
+func (s *S3Storage) objectURL(key string) *url.URL {
+	key = strings.TrimLeft(key, "/")
+	u := *s.endpoint
+	path := "/" + key
+	if s.config.ForcePathStyle {
+		path = "/" + s.config.Bucket + path
+	} else {
+		u.Host = s.config.Bucket + "." + u.Host
+	}
+	u.Path = strings.TrimRight(s.endpoint.Path, "/") + path
+	u.RawPath = strings.TrimRight(s.endpoint.EscapedPath(), "/") + uriEncode(path, false)
+	return &u
+}
+
+func (s *S3Storage) do(ctx context.Context, method, key string, body []byte, contentType string) (*http.Response, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+	request, err := http.NewRequest(method, s.objectURL(key).String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	request = request.WithContext(ctx)
+	if contentType != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+	s.sign(request, body, time.Now().UTC())
+	return s.client.Do(request)
+}
+
+// sign adds the AWS Signature Version 4 headers,
+// see https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html
+func (s *S3Storage) sign(request *http.Request, body []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	request.Header.Set("X-Amz-Date", amzDate)
+	request.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{
+		"host":                 request.URL.Host,
+		"x-amz-date":           amzDate,
+		"x-amz-content-sha256": payloadHash,
+	}
+	if contentType := request.Header.Get("Content-Type"); contentType != "" {
+		headers["content-type"] = contentType
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		request.URL.EscapedPath(),
+		"",
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := strings.Join([]string{date, s.config.Region, "s3", "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	signature := hex.EncodeToString(hmacSHA256(signingKey(s.config.SecretKey, date, s.config.Region, "s3"), stringToSign))
+
+	request.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.config.AccessKey, scope, signedHeaders, signature))
+}
+
+// signingKey derives the key of a day, region and service from the secret key
+func signingKey(secretKey, date, region, service string) []byte {
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	return hmacSHA256(key, "aws4_request")
+}
+
+func s3Error(response *http.Response) error {
+	message, _ := ioutil.ReadAll(io.LimitReader(response.Body, 1024))
+	return fmt.Errorf("s3 %s %s: %s: %s", response.Request.Method, response.Request.URL.Path, response.Status, bytes.TrimSpace(message))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// uriEncode is the URI encoding SigV4 expects: everything but unreserved characters is percent encoded
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,194 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const (
+	testAccessKey = "minioadmin"
+	testSecretKey = "minio-secret-key"
+)
+
+var authorization = regexp.MustCompile(`^AWS4-HMAC-SHA256 Credential=([^/]+)/(\d{8})/([^/]+)/s3/aws4_request, SignedHeaders=([a-z0-9;-]+), Signature=([0-9a-f]{64})$`)
+
+// fakeS3 stands in for MinIO: it keeps objects of one bucket in memory and rejects requests whose
+// signature does not check out, verified the way the server side does it rather than with S3Storage.sign
+type fakeS3 struct {
+	t      *testing.T
+	bucket string
+
+	mu        sync.Mutex
+	objects   map[string][]byte
+	canonical []string
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	canonical, ok := f.verify(r, body)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.canonical = append(f.canonical, canonical)
+	if !ok {
+		http.Error(w, "<Error><Code>SignatureDoesNotMatch</Code></Error>", http.StatusForbidden)
+		return
+	}
+
+	path := strings.SplitN(r.RequestURI, "?", 2)[0]
+	prefix := "/" + f.bucket + "/"
+	if !strings.HasPrefix(path, prefix) {
+		http.Error(w, "<Error><Code>NoSuchBucket</Code></Error>", http.StatusNotFound)
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, prefix)
+	switch r.Method {
+	case http.MethodPut:
+		f.objects[key] = body
+	case http.MethodGet:
+		object, ok := f.objects[key]
+		if !ok {
+			http.Error(w, "<Error><Code>NoSuchKey</Code></Error>", http.StatusNotFound)
+			return
+		}
+		w.Write(object)
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// verify rebuilds the canonical request from what was received and checks the signature against it
+func (f *fakeS3) verify(r *http.Request, body []byte) (string, bool) {
+	match := authorization.FindStringSubmatch(r.Header.Get("Authorization"))
+	if match == nil {
+		f.t.Errorf("%s %s: malformed Authorization %q", r.Method, r.URL, r.Header.Get("Authorization"))
+		return "", false
+	}
+	accessKey, date, region, signedHeaders, signature := match[1], match[2], match[3], match[4], match[5]
+
+	payloadHash := sha256.Sum256(body)
+	if r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(payloadHash[:]) {
+		f.t.Errorf("%s %s: x-amz-content-sha256 is not the hash of the body", r.Method, r.URL)
+		return "", false
+	}
+	names := strings.Split(signedHeaders, ";")
+	if !sort.StringsAreSorted(names) {
+		f.t.Errorf("%s %s: signed headers %q are not sorted", r.Method, r.URL, signedHeaders)
+	}
+	var headers strings.Builder
+	for _, name := range names {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		headers.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+	canonical := strings.Join([]string{
+		r.Method,
+		strings.SplitN(r.RequestURI, "?", 2)[0],
+		r.URL.RawQuery,
+		headers.String(),
+		signedHeaders,
+		r.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+
+	canonicalHash := sha256.Sum256([]byte(canonical))
+	stringToSign := "AWS4-HMAC-SHA256\n" + r.Header.Get("X-Amz-Date") + "\n" + date + "/" + region + "/s3/aws4_request\n" + hex.EncodeToString(canonicalHash[:])
+	key := []byte("AWS4" + testSecretKey)
+	for _, part := range []string{date, region, "s3", "aws4_request"} {
+		key = sign(key, part)
+	}
+	want := hex.EncodeToString(sign(key, stringToSign))
+	return canonical, accessKey == testAccessKey && region == "eu-central-1" && hmac.Equal([]byte(signature), []byte(want))
+}
+
+func sign(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func newFakeS3(t *testing.T, secretKey string) (*S3Storage, *fakeS3) {
+	t.Helper()
+	fake := &fakeS3{t: t, bucket: "photos", objects: map[string][]byte{}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	s3, err := NewS3Storage(S3Config{
+		Endpoint:       server.URL,
+		Region:         "eu-central-1",
+		Bucket:         "photos",
+		AccessKey:      testAccessKey,
+		SecretKey:      secretKey,
+		ForcePathStyle: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s3, fake
+}
+
+func TestS3PutGetDelete(t *testing.T) {
+	s3, fake := newFakeS3(t, testSecretKey)
+	ctx := context.Background()
+	key := "photos/1/a b+c.jpg"
+
+	if err := s3.Put(ctx, key, strings.NewReader("jpeg bytes"), 10, "image/jpeg"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	put := fake.canonical[0]
+	wantPrefix := "PUT\n/photos/photos/1/a%20b%2Bc.jpg\n\ncontent-type:image/jpeg\nhost:" + s3.endpoint.Host + "\n"
+	if !strings.HasPrefix(put, wantPrefix) || !strings.Contains(put, "\ncontent-type;host;x-amz-content-sha256;x-amz-date\n") {
+		t.Errorf("canonical request of Put:\n%s\nwant it to start with\n%s", put, wantPrefix)
+	}
+
+	object, err := s3.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	data, _ := ioutil.ReadAll(object)
+	object.Close()
+	if string(data) != "jpeg bytes" {
+		t.Errorf("Get: got %q, want the bytes that were put", data)
+	}
+
+	if err := s3.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := s3.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete: got %v, want ErrNotFound", err)
+	}
+	for i, method := range []string{"PUT", "GET", "DELETE", "GET"} {
+		if !strings.HasPrefix(fake.canonical[i], method+"\n/photos/photos/1/a%20b%2Bc.jpg\n") {
+			t.Errorf("canonical request %d:\n%s\nwant a %s of the escaped key", i, fake.canonical[i], method)
+		}
+	}
+}
+
+func TestS3WrongSecret(t *testing.T) {
+	s3, _ := newFakeS3(t, "not-the-secret")
+	if err := s3.Put(context.Background(), "photos/1/a.jpg", strings.NewReader("x"), 1, "image/jpeg"); err == nil {
+		t.Fatal("Put signed with the wrong secret: got no error")
+	}
+}
+
+// TestSigningKey is the key derivation example of the AWS documentation,
+// https://docs.aws.amazon.com/general/latest/gr/signature-v4-examples.html
+func TestSigningKey(t *testing.T) {
+	got := hex.EncodeToString(signingKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20120215", "us-east-1", "iam"))
+	want := "f4780e2d9f65fa895f9c67b32ce1baf0b0d8a43505a000a1a9e090d414db404d"
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
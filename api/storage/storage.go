@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/config"
+)
+
+// ErrNotFound is returned by Get when the key does not exist
+var ErrNotFound = errors.New("object not found")
+
+// Storage keeps uploaded files. Keys are slash separated paths such as "photos/1/abc.jpg".
+type Storage interface {
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	// URL is the public address clients download the object from
+	URL(key string) string
+}
+
+// New picks the Storage named by the storage driver (local, s3 or memory)
+func New(cfg config.Storage) (Storage, error) {
+	switch cfg.Driver {
+	case "local":
+		return NewLocalStorage(cfg.LocalDir, cfg.PublicURL)
+	case "s3":
+		return NewS3Storage(S3Config{
+			Endpoint:       cfg.S3Endpoint,
+			Region:         cfg.S3Region,
+			Bucket:         cfg.S3Bucket,
+			AccessKey:      cfg.S3AccessKey,
+			SecretKey:      cfg.S3SecretKey,
+			PublicURL:      cfg.PublicURL,
+			ForcePathStyle: cfg.S3ForcePathStyle,
+		})
+	case "memory":
+		return NewMemoryStorage(cfg.PublicURL), nil
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", cfg.Driver)
+	}
+}
+
+// cleanKey rejects keys that could escape the storage root
+func cleanKey(key string) (string, error) {
+	key = strings.TrimLeft(key, "/")
+	if key == "" {
+		return "", errors.New("empty storage key")
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return "", fmt.Errorf("invalid storage key %q", key)
+		}
+	}
+	return key, nil
+}
@@ -4,12 +4,13 @@ import (
 	"strings"
 )
 
-var errorMessages = make(map[string]string)
-
 var err error
 
 func FormatError(errString string) map[string]string {
 
+	// a fresh map per call, a shared one kept the messages of earlier errors
+	errorMessages := make(map[string]string)
+
 	if strings.Contains(errString, "username") {
 		errorMessages["Taken_username"] = "Username Already Taken"
 	}
@@ -33,6 +34,22 @@ func FormatError(errString string) map[string]string {
 		errorMessages["Double_like"] = "You cannot like this post twice"
 	}
 
+	if strings.Contains(errString, "self follow") {
+		errorMessages["Self_follow"] = "You cannot follow yourself"
+	}
+
+	if strings.Contains(errString, "double follow") {
+		errorMessages["Double_follow"] = "You already follow this user"
+	}
+
+	if strings.Contains(errString, "max depth") {
+		errorMessages["Max_depth"] = "Replies cannot be nested any deeper"
+	}
+
+	if strings.Contains(errString, "deleted comment") {
+		errorMessages["Deleted_comment"] = "You cannot reply to a deleted comment"
+	}
+
 	if strings.Contains(errString, "name") {
 		errorMessages["Taken_name"] = "Name Already Taken"
 	}
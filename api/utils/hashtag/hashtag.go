@@ -0,0 +1,50 @@
+package hashtag
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	// MaxLength is the longest tag kept, longer ones are dropped rather than cut
+	MaxLength = 50
+	// MaxPerText caps how many tags one caption can carry
+	MaxPerText = 30
+)
+
+// Parse returns the hashtags of text, lower cased and without the #, in the order they first appear.
+// A tag is a # followed by letters, digits and underscores, not glued to a word before it ("a#b" is no tag),
+// and it needs at least one letter so "#1" or an HTML entity like "&#39;" is not a tag.
+func Parse(text string) []string {
+	tags := []string{}
+	seen := make(map[string]bool)
+	runes := []rune(text)
+	for i := 0; i < len(runes) && len(tags) < MaxPerText; i++ {
+		if runes[i] != '#' || (i > 0 && isTagRune(runes[i-1])) {
+			continue
+		}
+		end := i + 1
+		letter := false
+		for end < len(runes) && isTagRune(runes[end]) {
+			letter = letter || unicode.IsLetter(runes[end])
+			end++
+		}
+		tag := Normalize(string(runes[i+1 : end]))
+		i = end - 1
+		if !letter || tag == "" || len([]rune(tag)) > MaxLength || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// Normalize turns a tag, with or without its #, into the form it is stored in
+func Normalize(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+}
+
+func isTagRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+}
@@ -0,0 +1,65 @@
+package mention
+
+import (
+	"unicode"
+)
+
+// MaxPerText caps how many users one caption or comment can mention
+const MaxPerText = 20
+
+// Entity is an @username found in a text. Start and End are offsets in characters (unicode code points)
+// into the text: Start is the offset of the @ and End the offset just after the username.
+type Entity struct {
+	Username string
+	Start    int
+	End      int
+}
+
+// Parse returns the mentions of text in the order they appear, a user mentioned twice gives two entities.
+// A username is made of letters, digits, underscores and dots; it must not be glued to a word before it,
+// so the @ of an email address is no mention, and a dot ending a sentence is not part of it.
+func Parse(text string) []Entity {
+	entities := []Entity{}
+	users := make(map[string]bool)
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '@' || (i > 0 && isUsernameRune(runes[i-1])) {
+			continue
+		}
+		end := i + 1
+		for end < len(runes) && isUsernameRune(runes[end]) {
+			end++
+		}
+		next := end
+		for end > i+1 && runes[end-1] == '.' {
+			end--
+		}
+		if end > i+1 {
+			username := string(runes[i+1 : end])
+			if !users[username] && len(users) == MaxPerText {
+				break
+			}
+			users[username] = true
+			entities = append(entities, Entity{Username: username, Start: i, End: end})
+		}
+		i = next - 1
+	}
+	return entities
+}
+
+// Usernames returns the distinct usernames of the entities
+func Usernames(entities []Entity) []string {
+	usernames := []string{}
+	seen := make(map[string]bool)
+	for _, entity := range entities {
+		if !seen[entity.Username] {
+			seen[entity.Username] = true
+			usernames = append(usernames, entity.Username)
+		}
+	}
+	return usernames
+}
+
+func isUsernameRune(r rune) bool {
+	return r == '_' || r == '.' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,65 @@
+package pagination
+
+import (
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Filter narrows a list down, the zero value lets every row through
+type Filter struct {
+	UserID        uint32
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+}
+
+// FilterFromQuery reads user_id, created_after and created_before.
+// Dates are RFC3339 timestamps or plain YYYY-MM-DD days.
+func FilterFromQuery(query url.Values) (Filter, error) {
+	filter := Filter{}
+	if uid := query.Get("user_id"); uid != "" {
+		id, err := strconv.ParseUint(uid, 10, 32)
+		if err != nil {
+			return Filter{}, ErrInvalidFilter
+		}
+		filter.UserID = uint32(id)
+	}
+	var err error
+	if filter.CreatedAfter, err = parseDate(query.Get("created_after")); err != nil {
+		return Filter{}, ErrInvalidFilter
+	}
+	if filter.CreatedBefore, err = parseDate(query.Get("created_before")); err != nil {
+		return Filter{}, ErrInvalidFilter
+	}
+	return filter, nil
+}
+
+// Apply adds the filter conditions to a query on table
+func (f Filter) Apply(db *gorm.DB, table string) *gorm.DB {
+	if f.UserID != 0 {
+		db = db.Where(table+".user_id = ?", f.UserID)
+	}
+	if f.CreatedAfter != nil {
+		db = db.Where(table+".created_at > ?", *f.CreatedAfter)
+	}
+	if f.CreatedBefore != nil {
+		db = db.Where(table+".created_at < ?", *f.CreatedBefore)
+	}
+	return db
+}
+
+func parseDate(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t, err = time.Parse("2006-01-02", value)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
@@ -0,0 +1,226 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	DefaultLimit = 20
+	// MaxLimit caps the page size whatever the client asks for
+	MaxLimit = 100
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidSort   = errors.New("invalid sort")
+	ErrInvalidLimit  = errors.New("invalid limit")
+	ErrInvalidFilter = errors.New("invalid filter")
+)
+
+// Kind is the type of a sort column, it tells how cursor values are read back
+type Kind int
+
+const (
+	Time Kind = iota
+	String
+	Number
+)
+
+// SortKey is a column a list can be sorted by
+type SortKey struct {
+	Name   string
+	Column string
+	Kind   Kind
+}
+
+// Page describes the slice of a list a request asks for. Rows are ordered by the sort key
+// and then by id, so rows with the same value still have a stable order.
+type Page struct {
+	Limit  int
+	Key    SortKey
+	Desc   bool
+	Cursor *Cursor
+}
+
+// Cursor points just past a row. It is handed to clients as an opaque string.
+type Cursor struct {
+	Sort     string `json:"s"`
+	Value    string `json:"v"`
+	ID       uint64 `json:"i"`
+	Backward bool   `json:"b,omitempty"`
+}
+
+// Links goes into the response envelope next to the rows
+type Links struct {
+	Limit      int    `json:"limit"`
+	Sort       string `json:"sort"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+// FromQuery reads limit, sort and cursor. sort is a key name, with a leading "-" for descending order.
+func FromQuery(query url.Values, keys []SortKey, defaultSort string) (Page, error) {
+	page := Page{Limit: DefaultLimit}
+
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			return Page{}, ErrInvalidLimit
+		}
+		if n > MaxLimit {
+			n = MaxLimit
+		}
+		page.Limit = n
+	}
+
+	sort := query.Get("sort")
+	if sort == "" {
+		sort = defaultSort
+	}
+	page.Desc = strings.HasPrefix(sort, "-")
+	name := strings.TrimPrefix(sort, "-")
+	found := false
+	for _, key := range keys {
+		if key.Name == name {
+			page.Key, found = key, true
+			break
+		}
+	}
+	if !found {
+		return Page{}, ErrInvalidSort
+	}
+
+	if raw := query.Get("cursor"); raw != "" {
+		cursor, err := decodeCursor(raw)
+		// a cursor only makes sense with the sort it was made for
+		if err != nil || cursor.Sort != page.sortName() {
+			return Page{}, ErrInvalidCursor
+		}
+		if _, err = page.parseValue(cursor.Value); err != nil {
+			return Page{}, ErrInvalidCursor
+		}
+		page.Cursor = &cursor
+	}
+	return page, nil
+}
+
+// Apply adds the cursor condition, the order and the limit to a query on table.
+// One row more than the limit is fetched to find out whether there is a next page.
+func (p Page) Apply(db *gorm.DB, table string) *gorm.DB {
+	column := table + "." + p.Key.Column
+	id := table + ".id"
+
+	// walking backwards reads the rows in reverse order, Window puts them back
+	desc := p.Desc
+	if p.Cursor != nil && p.Cursor.Backward {
+		desc = !desc
+	}
+	direction, comparison := "ASC", ">"
+	if desc {
+		direction, comparison = "DESC", "<"
+	}
+
+	if p.Cursor != nil {
+		value, _ := p.parseValue(p.Cursor.Value)
+		db = db.Where(fmt.Sprintf("(%s %s ?) OR (%s = ? AND %s %s ?)", column, comparison, column, id, comparison),
+			value, value, p.Cursor.ID)
+	}
+	return db.Order(column + " " + direction).Order(id + " " + direction).Limit(p.Limit + 1)
+}
+
+// Window trims rows fetched by Apply to the page and builds the cursors around it.
+// key returns the sort value and the id of a row.
+func Window[T any](p Page, rows []T, key func(T) (interface{}, uint64)) ([]T, Links) {
+	links := Links{Limit: p.Limit, Sort: p.sortName()}
+	more := len(rows) > p.Limit
+	if more {
+		rows = rows[:p.Limit]
+	}
+	backward := p.Cursor != nil && p.Cursor.Backward
+	if backward {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+	if len(rows) == 0 {
+		// past either end, the way back is the cursor the client came with
+		if p.Cursor != nil {
+			flipped := *p.Cursor
+			flipped.Backward = !backward
+			if backward {
+				links.NextCursor = encodeCursor(flipped)
+			} else {
+				links.PrevCursor = encodeCursor(flipped)
+			}
+		}
+		return rows, links
+	}
+
+	// there is a next page when we saw an extra row going forward, or when we came back from it
+	if more || backward {
+		value, id := key(rows[len(rows)-1])
+		links.NextCursor = p.cursorFor(value, id, false)
+	}
+	// and a previous one when we came from it, or saw an extra row going backward
+	if (backward && more) || (!backward && p.Cursor != nil) {
+		value, id := key(rows[0])
+		links.PrevCursor = p.cursorFor(value, id, true)
+	}
+	return rows, links
+}
+
+func (p Page) cursorFor(value interface{}, id uint64, backward bool) string {
+	return encodeCursor(Cursor{Sort: p.sortName(), Value: p.formatValue(value), ID: id, Backward: backward})
+}
+
+func (p Page) sortName() string {
+	if p.Desc {
+		return "-" + p.Key.Name
+	}
+	return p.Key.Name
+}
+
+func (p Page) formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func (p Page) parseValue(value string) (interface{}, error) {
+	switch p.Key.Kind {
+	case Time:
+		return time.Parse(time.RFC3339Nano, value)
+	case Number:
+		return strconv.ParseInt(value, 10, 64)
+	}
+	return value, nil
+}
+
+func encodeCursor(cursor Cursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(raw string) (Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return Cursor{}, err
+	}
+	cursor := Cursor{}
+	err = json.Unmarshal(data, &cursor)
+	return cursor, err
+}
@@ -0,0 +1,204 @@
+package variants
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/imaging"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/models"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/storage"
+	"github.com/jinzhu/gorm"
+)
+
+// queueSize is how many photos can wait for their variants, when the queue is full
+// new photos are skipped and picked up by the next backfill
+const queueSize = 256
+
+// Generator creates the resized variants of uploaded photos on background workers,
+// so uploads return as soon as the original is stored.
+type Generator struct {
+	db      *gorm.DB
+	storage storage.Storage
+	// maxPixels is the largest photo decoded, see imaging.Inspect
+	maxPixels int64
+	jobs      chan uint64
+	wg        sync.WaitGroup
+
+	// mu keeps Enqueue from sending on jobs once Close closed it
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewGenerator starts the given number of workers. Photos over maxPixels get no variants, the photos stored
+// before the limit are not trusted to be under it.
+func NewGenerator(db *gorm.DB, store storage.Storage, workers int, maxPixels int64) *Generator {
+	if workers < 1 {
+		workers = 1
+	}
+	g := &Generator{
+		db:        db,
+		storage:   store,
+		maxPixels: maxPixels,
+		jobs:      make(chan uint64, queueSize),
+	}
+	for i := 0; i < workers; i++ {
+		g.wg.Add(1)
+		go g.work()
+	}
+	return g
+}
+
+// Enqueue schedules the variants of a photo, it never blocks the request. Once the generator is closed
+// photos are skipped, the next backfill picks them up.
+func (g *Generator) Enqueue(photoID uint64) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if g.closed {
+		fmt.Println("variant queue is closed, skipping photo ", photoID)
+		return false
+	}
+	select {
+	case g.jobs <- photoID:
+		return true
+	default:
+		fmt.Println("variant queue is full, skipping photo ", photoID)
+		return false
+	}
+}
+
+// Close stops accepting photos and waits for the queued ones to finish
+func (g *Generator) Close() {
+	g.mu.Lock()
+	if !g.closed {
+		g.closed = true
+		close(g.jobs)
+	}
+	g.mu.Unlock()
+	g.wg.Wait()
+}
+
+func (g *Generator) work() {
+	defer g.wg.Done()
+	for photoID := range g.jobs {
+		if err := g.Generate(context.Background(), photoID); err != nil {
+			fmt.Printf("Cannot generate variants of photo %d: %v\n", photoID, err)
+		}
+	}
+}
+
+// Generate creates every variant of the photo, replacing existing ones. When the stored file does not decode
+// the error is recorded on the photo as well as returned.
+func (g *Generator) Generate(ctx context.Context, photoID uint64) error {
+	photo := models.Photo{}
+	_, err := photo.FindPhotoByID(g.db, photoID)
+	if err != nil {
+		return err
+	}
+	if !photo.IsUploaded() {
+		return nil
+	}
+
+	original, err := g.storage.Get(ctx, photo.StorageKey)
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadAll(original)
+	original.Close()
+	if err != nil {
+		return err
+	}
+	img, mimeType, err := imaging.Decode(data, g.maxPixels)
+	if err != nil {
+		// the file will not decode any better next time, the backfill skips the photo from now on
+		if recordErr := photo.SetVariantsError(g.db, err.Error()); recordErr != nil {
+			return recordErr
+		}
+		return err
+	}
+
+	var keys []string
+	for _, spec := range imaging.Variants {
+		encoded, info, err := imaging.Render(img, mimeType, spec)
+		if err != nil {
+			return err
+		}
+		key := variantKey(photo.StorageKey, spec.Name, info.MimeType)
+		err = g.storage.Put(ctx, key, bytes.NewReader(encoded), int64(len(encoded)), info.MimeType)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, key)
+		variant := models.PhotoVariant{
+			PhotoID:    photo.ID,
+			Name:       spec.Name,
+			StorageKey: key,
+			URL:        g.storage.URL(key),
+			MimeType:   info.MimeType,
+			Width:      info.Width,
+			Height:     info.Height,
+			ByteSize:   int64(len(encoded)),
+		}
+		_, err = variant.SavePhotoVariant(g.db)
+		if err != nil {
+			g.storage.Delete(ctx, key)
+			return err
+		}
+	}
+
+	// the photo may have been deleted while we were busy, its files must not be left behind
+	check := models.Photo{}
+	if _, err = check.FindPhotoByID(g.db, photo.ID); gorm.IsRecordNotFoundError(err) {
+		for _, key := range append(keys, photo.StorageKey) {
+			g.storage.Delete(ctx, key)
+		}
+		variant := models.PhotoVariant{}
+		if _, err = variant.DeletePhotoVariants(g.db, photo.ID); err != nil {
+			return err
+		}
+		return gorm.ErrRecordNotFound
+	}
+	if photo.VariantsError != "" {
+		return photo.SetVariantsError(g.db, "")
+	}
+	return nil
+}
+
+// Backfill generates the missing variants of every uploaded photo and reports how many photos got them.
+// A photo that fails is logged and skipped, one whose file does not decode is not tried again.
+func (g *Generator) Backfill(ctx context.Context) (int, error) {
+	const batchSize = 100
+	processed := 0
+	var afterID uint64
+	for {
+		photo := models.Photo{}
+		photos, err := photo.FindPhotosMissingVariants(g.db, afterID, len(imaging.Variants), batchSize)
+		if err != nil {
+			return processed, err
+		}
+		if len(photos) == 0 {
+			return processed, nil
+		}
+		for _, p := range photos {
+			if err = ctx.Err(); err != nil {
+				return processed, err
+			}
+			afterID = p.ID
+			if err = g.Generate(ctx, p.ID); err != nil {
+				fmt.Printf("Cannot generate variants of photo %d: %v\n", p.ID, err)
+				continue
+			}
+			processed++
+		}
+	}
+}
+
+// variantKey stores a variant next to its original: photos/1/abc.jpg becomes photos/1/abc_thumbnail.jpg
+func variantKey(originalKey, name, mimeType string) string {
+	base := strings.TrimSuffix(originalKey, path.Ext(originalKey))
+	return base + "_" + name + imaging.Extensions[mimeType]
+}
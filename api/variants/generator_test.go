@@ -0,0 +1,114 @@
+package variants
+
+import (
+	"bytes"
+	"context"
+	"image"
+	imagepng "image/png"
+	"sync"
+	"testing"
+
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/imaging"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/models"
+	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api/storage"
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite" //sqlite database driver of the tests
+)
+
+func newTestGenerator(t *testing.T) *Generator {
+	t.Helper()
+	db, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every connection to :memory: opens a database of its own
+	db.DB().SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	// Generate loads the photo with everything a response shows
+	err = db.AutoMigrate(&models.User{}, &models.Photo{}, &models.PhotoVariant{}, &models.Comment{}, &models.Like{},
+		&models.Tag{}, &models.PhotoTag{}, &models.Mention{}).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewGenerator(db, storage.NewMemoryStorage("http://localhost:8080/files"), 2, 1000)
+}
+
+func TestEnqueueAfterClose(t *testing.T) {
+	g := newTestGenerator(t)
+	g.Close()
+	if g.Enqueue(1) {
+		t.Error("a closed generator accepted a photo")
+	}
+	// closing again does nothing
+	g.Close()
+}
+
+// TestEnqueueDuringClose has requests still uploading while the server shuts down, they must not panic
+func TestEnqueueDuringClose(t *testing.T) {
+	g := newTestGenerator(t)
+	var uploads sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		uploads.Add(1)
+		go func(i int) {
+			defer uploads.Done()
+			for j := 0; j < 50; j++ {
+				g.Enqueue(uint64(i*50 + j))
+			}
+		}(i)
+	}
+	g.Close()
+	uploads.Wait()
+}
+
+// TestBackfillSkipsUndecodable counts only the photos that got their variants and records the ones
+// whose file does not decode, so the next backfill does not try them again
+func TestBackfillSkipsUndecodable(t *testing.T) {
+	g := newTestGenerator(t)
+	defer g.Close()
+	ctx := context.Background()
+
+	var png bytes.Buffer
+	if err := imagepng.Encode(&png, image.NewGray(image.Rect(0, 0, 20, 20))); err != nil {
+		t.Fatal(err)
+	}
+	// a PNG signature followed by garbage passes the type sniffing but does not decode
+	broken := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{7}, 64)...)
+	files := map[string][]byte{"photos/1/good.png": png.Bytes(), "photos/1/broken.png": broken}
+	user := models.User{Username: "alice", Email: "alice@example.com", Password: "password", Age: 20}
+	if err := g.db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	var good, bad models.Photo
+	for key, data := range files {
+		if err := g.storage.Put(ctx, key, bytes.NewReader(data), int64(len(data)), "image/png"); err != nil {
+			t.Fatal(err)
+		}
+		photo := models.Photo{Title: key, Caption: key, PhotoURL: key, UserID: user.ID, StorageKey: key, MimeType: "image/png"}
+		if err := g.db.Create(&photo).Error; err != nil {
+			t.Fatal(err)
+		}
+		if key == "photos/1/good.png" {
+			good = photo
+		} else {
+			bad = photo
+		}
+	}
+
+	processed, err := g.Backfill(ctx)
+	if err != nil || processed != 1 {
+		t.Fatalf("first backfill: got %d, %v, want 1 photo", processed, err)
+	}
+	var count int
+	g.db.Model(&models.PhotoVariant{}).Where("photo_id = ?", good.ID).Count(&count)
+	if count != len(imaging.Variants) {
+		t.Errorf("the good photo has %d variants, want %d", count, len(imaging.Variants))
+	}
+	if _, err = bad.FindPhotoByID(g.db, bad.ID); err != nil || bad.VariantsError == "" {
+		t.Errorf("the broken photo has no variants error: %q, %v", bad.VariantsError, err)
+	}
+
+	missing, err := bad.FindPhotosMissingVariants(g.db, 0, len(imaging.Variants), 10)
+	if err != nil || len(missing) != 0 {
+		t.Errorf("second backfill: got %d photos to do, %v, want none", len(missing), err)
+	}
+}
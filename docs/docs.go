@@ -20,6 +20,52 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/admin/users/{id}/role": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Change the role (user, moderator, admin) of a user. Admin only.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Update User Role",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Role",
+                        "name": "UpdateRole",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdateRole"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/responses.User"
+                        }
+                    }
+                }
+            }
+        },
         "/comments": {
             "get": {
                 "description": "Retrieve all comment",
@@ -33,13 +79,53 @@ const docTemplate = `{
                     "Comment"
                 ],
                 "summary": "Get All Comment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Cursor from the pagination of a previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Page size, at most 100",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "-created_at",
+                        "description": "created_at or id, prefixed with - for descending order",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Only items of this user",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only items created after this time (RFC3339 or YYYY-MM-DD)",
+                        "name": "created_after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only items created before this time (RFC3339 or YYYY-MM-DD)",
+                        "name": "created_before",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/models.Comment"
+                                "$ref": "#/definitions/responses.Comment"
                             }
                         }
                     }
@@ -72,7 +158,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Comment"
+                            "$ref": "#/definitions/responses.Comment"
                         }
                     }
                 }
@@ -116,7 +202,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Comment"
+                            "$ref": "#/definitions/responses.Comment"
                         }
                     }
                 }
@@ -160,7 +246,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Comment"
+                            "$ref": "#/definitions/responses.Comment"
                         }
                     }
                 }
@@ -201,9 +287,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/login": {
+        "/comments/{id}/like": {
             "post": {
-                "description": "Login for User",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Like a comment, a comment can only be liked once",
                 "consumes": [
                     "application/json"
                 ],
@@ -211,62 +302,34 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "User"
+                    "Like"
                 ],
-                "summary": "Login",
+                "summary": "Like Comment",
                 "parameters": [
                     {
-                        "description": "User Data",
-                        "name": "user",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.UserLogin"
-                        }
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/models.UserLogin"
-                        }
+                        "type": "integer",
+                        "description": "Comment ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
-                }
-            }
-        },
-        "/photos": {
-            "get": {
-                "description": "Retrieve all photos",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "Photo"
                 ],
-                "summary": "Get All Photos",
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.Photo"
-                            }
+                            "$ref": "#/definitions/responses.LikeStatus"
                         }
                     }
                 }
             },
-            "post": {
+            "delete": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Add a new Photo",
+                "description": "Remove the like of the current user from a comment",
                 "consumes": [
                     "application/json"
                 ],
@@ -274,33 +337,31 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Photo"
+                    "Like"
                 ],
-                "summary": "Create Photo",
+                "summary": "Unlike Comment",
                 "parameters": [
                     {
-                        "description": "Photo Data",
-                        "name": "CreatePhoto",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.CreatePhoto"
-                        }
+                        "type": "integer",
+                        "description": "Comment ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Photo"
+                            "$ref": "#/definitions/responses.LikeStatus"
                         }
                     }
                 }
             }
         },
-        "/photos/{id}": {
+        "/comments/{id}/replies": {
             "get": {
-                "description": "Retrieve a photo by ID",
+                "description": "Retrieve the replies to a comment. The direct replies are paged and come with the first replies under them",
                 "consumes": [
                     "application/json"
                 ],
@@ -308,34 +369,86 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Photo"
+                    "Comment"
                 ],
-                "summary": "Get Photo by ID",
+                "summary": "Get Comment Replies",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Photo ID",
+                        "description": "Comment ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "enum": [
+                            "flat",
+                            "tree"
+                        ],
+                        "type": "string",
+                        "default": "flat",
+                        "description": "flat for the thread depth first, tree for the replies nested in their comment",
+                        "name": "view",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor from the pagination of a previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Page size, at most 100",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "-created_at",
+                        "description": "created_at or id, prefixed with - for descending order",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Only items of this user",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only items created after this time (RFC3339 or YYYY-MM-DD)",
+                        "name": "created_after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only items created before this time (RFC3339 or YYYY-MM-DD)",
+                        "name": "created_before",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Photo"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/responses.Comment"
+                            }
                         }
                     }
                 }
             },
-            "put": {
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Update a photo by ID",
+                "description": "Reply to a comment, replies can be nested up to a maximum depth",
                 "consumes": [
                     "application/json"
                 ],
@@ -343,43 +456,40 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Photo"
+                    "Comment"
                 ],
-                "summary": "Update Photo by ID",
+                "summary": "Reply to Comment",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Photo ID",
+                        "description": "Comment ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Photo Data",
-                        "name": "UpdatePhoto",
+                        "description": "Comment Data",
+                        "name": "CreateComment",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.UpdatePhoto"
+                            "$ref": "#/definitions/models.CreateComment"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/models.Photo"
+                            "$ref": "#/definitions/responses.Comment"
                         }
                     }
                 }
-            },
-            "delete": {
-                "security": [
-                    {
-                        "ApiKeyAuth": []
-                    }
-                ],
-                "description": "Delete a photo by ID",
+            }
+        },
+        "/comments/{id}/thread": {
+            "get": {
+                "description": "Retrieve the replies under a comment that a thread left out, depth first, from the more_replies cursor of the comment",
                 "consumes": [
                     "application/json"
                 ],
@@ -387,36 +497,57 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Photo"
+                    "Comment"
                 ],
-                "summary": "Delete Photo by ID",
+                "summary": "Get More Replies",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Photo ID",
+                        "description": "Comment ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "more_replies of the comment, or of the previous response",
+                        "name": "cursor",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "flat",
+                            "tree"
+                        ],
+                        "type": "string",
+                        "default": "flat",
+                        "description": "flat for the thread depth first, tree for the replies nested in their comment",
+                        "name": "view",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Photo deleted",
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/responses.Comment"
+                            }
                         }
                     }
                 }
             }
         },
-        "/social-media": {
-            "post": {
+        "/feed": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Add a new Social Media",
+                "description": "Retrieve the home feed: the photos of the authenticated user and of the accounts they follow, newest first",
                 "consumes": [
                     "application/json"
                 ],
@@ -424,59 +555,46 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Social Media"
+                    "Feed"
                 ],
-                "summary": "Create Social Media",
+                "summary": "Get Feed",
                 "parameters": [
                     {
-                        "description": "SocialMedia Data",
-                        "name": "CreateSocialMedia",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.CreateSocialMedia"
-                        }
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/models.SocialMedia"
-                        }
+                        "type": "string",
+                        "description": "Cursor from the pagination of a previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Page size, at most 100",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Set to comments to embed the first comments of every photo",
+                        "name": "include",
+                        "in": "query"
                     }
-                }
-            }
-        },
-        "/social-media-all": {
-            "get": {
-                "description": "Retrieve all social media",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "Social Media"
                 ],
-                "summary": "Get All Social Media",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/models.SocialMedia"
+                                "$ref": "#/definitions/responses.Photo"
                             }
                         }
                     }
                 }
             }
         },
-        "/social-media/{id}": {
-            "get": {
-                "description": "Retrieve a social media by ID",
+        "/login": {
+            "post": {
+                "description": "Login for User",
                 "consumes": [
                     "application/json"
                 ],
@@ -484,34 +602,38 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Social Media"
+                    "User"
                 ],
-                "summary": "Get Social Media by ID",
+                "summary": "Login",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "SocialMedia ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "User Data",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UserLogin"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.SocialMedia"
+                            "$ref": "#/definitions/models.UserLogin"
                         }
                     }
                 }
-            },
-            "put": {
+            }
+        },
+        "/logout": {
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Update a social Media by ID",
+                "description": "Revoke the refresh token family of the current session",
                 "consumes": [
                     "application/json"
                 ],
@@ -519,24 +641,130 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Social Media"
+                    "User"
                 ],
-                "summary": "Update Social Media by ID",
+                "summary": "Logout",
+                "responses": {
+                    "200": {
+                        "description": "Logged out",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/notifications": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Retrieve the notifications of the authenticated user, newest first, with how many are unread",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Notification"
+                ],
+                "summary": "Get Notifications",
                 "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Only the unread notifications",
+                        "name": "unread",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor from the pagination of a previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
                     {
                         "type": "integer",
-                        "description": "SocialMedia ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "default": 20,
+                        "description": "Page size, at most 100",
+                        "name": "limit",
+                        "in": "query"
                     },
                     {
-                        "description": "SocialMedia Data",
-                        "name": "UpdateSocialMedia",
+                        "type": "string",
+                        "default": "-created_at",
+                        "description": "created_at or id, prefixed with - for descending order",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/responses.Notification"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/notifications/preferences": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Retrieve the kinds of notification the authenticated user gets",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Notification"
+                ],
+                "summary": "Get Notification Preferences",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/responses.NotificationPreference"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Turn kinds of notification on or off for the authenticated user, the kinds left out keep their setting",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Notification"
+                ],
+                "summary": "Update Notification Preferences",
+                "parameters": [
+                    {
+                        "description": "Update Notification Preferences",
+                        "name": "preferences",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.UpdateSocialMedia"
+                            "$ref": "#/definitions/models.UpdateNotificationPreference"
                         }
                     }
                 ],
@@ -544,18 +772,20 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.SocialMedia"
+                            "$ref": "#/definitions/responses.NotificationPreference"
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/notifications/read": {
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Delete a social media by ID",
+                "description": "Mark every notification of the authenticated user as read",
                 "consumes": [
                     "application/json"
                 ],
@@ -563,13 +793,41 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Social Media"
+                    "Notification"
                 ],
-                "summary": "Delete social media by ID",
+                "summary": "Mark All Notifications Read",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UnreadCount"
+                        }
+                    }
+                }
+            }
+        },
+        "/notifications/{id}/read": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Mark a notification of the authenticated user as read",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Notification"
+                ],
+                "summary": "Mark Notification Read",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "SocialMedia ID",
+                        "description": "Notification ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -577,7 +835,41 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "Social Media deleted",
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UnreadCount"
+                        }
+                    }
+                }
+            }
+        },
+        "/password/forgot": {
+            "post": {
+                "description": "Send a password reset link to the given email. The response is the same whether or not the email is registered.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Forgot Password",
+                "parameters": [
+                    {
+                        "description": "User Email",
+                        "name": "ForgotPassword",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ForgotPassword"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "If the email is registered, a reset link has been sent",
                         "schema": {
                             "type": "string"
                         }
@@ -585,9 +877,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/users": {
+        "/password/reset": {
             "post": {
-                "description": "Add a new User",
+                "description": "Set a new password using the token from the reset email",
                 "consumes": [
                     "application/json"
                 ],
@@ -597,15 +889,124 @@ const docTemplate = `{
                 "tags": [
                     "User"
                 ],
-                "summary": "Register User",
+                "summary": "Reset Password",
                 "parameters": [
                     {
-                        "description": "User Data",
-                        "name": "UserRegister",
+                        "description": "Reset Data",
+                        "name": "ResetPasswordInput",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.UserRegister"
+                            "$ref": "#/definitions/models.ResetPasswordInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Password reset",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/photos": {
+            "get": {
+                "description": "Retrieve all photos",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Photo"
+                ],
+                "summary": "Get All Photos",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Cursor from the pagination of a previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Page size, at most 100",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "-created_at",
+                        "description": "created_at, id or title, prefixed with - for descending order",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Only items of this user",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only items created after this time (RFC3339 or YYYY-MM-DD)",
+                        "name": "created_after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only items created before this time (RFC3339 or YYYY-MM-DD)",
+                        "name": "created_before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Set to comments to embed the first comments of every photo",
+                        "name": "include",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/responses.Photo"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Add a new Photo. Send JSON with a photo_url, or multipart/form-data with title, caption and a \"photo\" file (JPEG, PNG, GIF or WebP) to upload the image.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Photo"
+                ],
+                "summary": "Create Photo",
+                "parameters": [
+                    {
+                        "description": "Photo Data",
+                        "name": "CreatePhoto",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CreatePhoto"
                         }
                     }
                 ],
@@ -613,106 +1014,1675 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.User"
+                            "$ref": "#/definitions/responses.Photo"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "models.Comment": {
+        },
+        "/photos/{id}": {
+            "get": {
+                "description": "Retrieve a photo by ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Photo"
+                ],
+                "summary": "Get Photo by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Photo ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Set to comments to embed the first comments of every photo",
+                        "name": "include",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/responses.Photo"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Update a photo by ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Photo"
+                ],
+                "summary": "Update Photo by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Photo ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Photo Data",
+                        "name": "UpdatePhoto",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdatePhoto"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/responses.Photo"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Delete a photo by ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Photo"
+                ],
+                "summary": "Delete Photo by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Photo ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Photo deleted",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/photos/{id}/comments": {
+            "get": {
+                "description": "Retrieve the comments of a photo. With a view, the top level comments are paged and come with their first replies",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Comment"
+                ],
+                "summary": "Get Photo Comments",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Photo ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "flat",
+                            "tree"
+                        ],
+                        "type": "string",
+                        "description": "flat for the threads depth first, tree for the replies nested in their comment; by default every comment is listed on its own",
+                        "name": "view",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor from the pagination of a previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Page size, at most 100",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "-created_at",
+                        "description": "created_at or id, prefixed with - for descending order",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Only items of this user",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only items created after this time (RFC3339 or YYYY-MM-DD)",
+                        "name": "created_after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only items created before this time (RFC3339 or YYYY-MM-DD)",
+                        "name": "created_before",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/responses.Comment"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/photos/{id}/like": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Like a photo, a photo can only be liked once",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Like"
+                ],
+                "summary": "Like Photo",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Photo ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/responses.LikeStatus"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Remove the like of the current user from a photo",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Like"
+                ],
+                "summary": "Unlike Photo",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Photo ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/responses.LikeStatus"
+                        }
+                    }
+                }
+            }
+        },
+        "/social-media": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Add a new Social Media",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Social Media"
+                ],
+                "summary": "Create Social Media",
+                "parameters": [
+                    {
+                        "description": "SocialMedia Data",
+                        "name": "CreateSocialMedia",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateSocialMedia"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/responses.SocialMedia"
+                        }
+                    }
+                }
+            }
+        },
+        "/social-media-all": {
+            "get": {
+                "description": "Retrieve all social media",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Social Media"
+                ],
+                "summary": "Get All Social Media",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Cursor from the pagination of a previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Page size, at most 100",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "-created_at",
+                        "description": "created_at, id or name, prefixed with - for descending order",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Only items of this user",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only items created after this time (RFC3339 or YYYY-MM-DD)",
+                        "name": "created_after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only items created before this time (RFC3339 or YYYY-MM-DD)",
+                        "name": "created_before",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/responses.SocialMedia"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/social-media/{id}": {
+            "get": {
+                "description": "Retrieve a social media by ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Social Media"
+                ],
+                "summary": "Get Social Media by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "SocialMedia ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/responses.SocialMedia"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Update a social Media by ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Social Media"
+                ],
+                "summary": "Update Social Media by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "SocialMedia ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "SocialMedia Data",
+                        "name": "UpdateSocialMedia",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdateSocialMedia"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/responses.SocialMedia"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Delete a social media by ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Social Media"
+                ],
+                "summary": "Delete social media by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "SocialMedia ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Social Media deleted",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/stream": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Server-Sent Events stream of the authenticated user: their new notifications (\"notification\" events),\nand the new comments (\"comment\") and like counts (\"likes\") of the photos given in photo_id.\nBrowsers can pass the access token as the token query parameter, EventSource cannot set headers.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "Stream"
+                ],
+                "summary": "Stream Updates",
+                "parameters": [
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "integer"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Photos to follow, at most 50",
+                        "name": "photo_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/realtime.Message"
+                        }
+                    }
+                }
+            }
+        },
+        "/tags": {
+            "get": {
+                "description": "Retrieve the tags starting with a prefix, the most used first",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tag"
+                ],
+                "summary": "Complete Tag",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Start of the tag, with or without the #",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "How many tags, at most 50",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/responses.Tag"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/tags/trending": {
+            "get": {
+                "description": "Retrieve the tags put on the most photos lately",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tag"
+                ],
+                "summary": "Get Trending Tags",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "24h",
+                        "description": "How far back to look, as a duration such as 24h",
+                        "name": "window",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "How many tags, at most 50",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/responses.TrendingTag"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/tags/{name}/photos": {
+            "get": {
+                "description": "Retrieve the photos whose caption carries a hashtag",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tag"
+                ],
+                "summary": "Get Tag Photos",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tag, with or without the #",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor from the pagination of a previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Page size, at most 100",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "-created_at",
+                        "description": "created_at, id or title, prefixed with - for descending order",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Only items of this user",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only items created after this time (RFC3339 or YYYY-MM-DD)",
+                        "name": "created_after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only items created before this time (RFC3339 or YYYY-MM-DD)",
+                        "name": "created_before",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/responses.Photo"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/token/refresh": {
+            "post": {
+                "description": "Exchange a refresh token for a new access token and refresh token",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Refresh Token",
+                "parameters": [
+                    {
+                        "description": "Refresh Token",
+                        "name": "TokenRefresh",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.TokenRefresh"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.TokenRefresh"
+                        }
+                    }
+                }
+            }
+        },
+        "/users": {
+            "post": {
+                "description": "Add a new User",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Register User",
+                "parameters": [
+                    {
+                        "description": "User Data",
+                        "name": "UserRegister",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UserRegister"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/responses.User"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Retrieve the authenticated user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Get Current User",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/responses.Profile"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/password": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Change the password of the authenticated user. Other sessions are logged out.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Change Password",
+                "parameters": [
+                    {
+                        "description": "Passwords",
+                        "name": "ChangePassword",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ChangePassword"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Password changed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/verify": {
+            "get": {
+                "description": "Confirm the email address of an account with the token from the verification email",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Verify Email",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Verification Token",
+                        "name": "token",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Email verified",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/verify/resend": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Send a new verification email to the authenticated user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Resend Verification Email",
+                "responses": {
+                    "200": {
+                        "description": "Verification email sent",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{id}": {
+            "get": {
+                "description": "Retrieve a user by ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Get User by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/responses.Profile"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Update the profile of a user. Use /users/me for the authenticated user.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Update User",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "User Data",
+                        "name": "UpdateUser",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdateUser"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/responses.User"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Delete a user together with their photos, comments and social media. Use /users/me for the authenticated user.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Delete User",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "User deleted",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{id}/follow": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Follow a user, a user can only be followed once and cannot follow themselves",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Follow"
+                ],
+                "summary": "Follow User",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/responses.FollowStatus"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Stop following a user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Follow"
+                ],
+                "summary": "Unfollow User",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/responses.FollowStatus"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{id}/followers": {
+            "get": {
+                "description": "Retrieve the users following a user, most recent follow first",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Follow"
+                ],
+                "summary": "Get Followers",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor from the pagination of a previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Page size, at most 100",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "-created_at",
+                        "description": "created_at or id, prefixed with - for descending order",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/responses.User"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{id}/following": {
+            "get": {
+                "description": "Retrieve the users a user follows, most recent follow first",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Follow"
+                ],
+                "summary": "Get Following",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor from the pagination of a previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Page size, at most 100",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "-created_at",
+                        "description": "created_at or id, prefixed with - for descending order",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/responses.User"
+                            }
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "models.ChangePassword": {
+            "type": "object",
+            "required": [
+                "current_password",
+                "new_password",
+                "retype_password"
+            ],
+            "properties": {
+                "current_password": {
+                    "type": "string",
+                    "example": "password"
+                },
+                "new_password": {
+                    "type": "string",
+                    "example": "newpassword"
+                },
+                "retype_password": {
+                    "type": "string",
+                    "example": "newpassword"
+                }
+            }
+        },
+        "models.CreateComment": {
+            "type": "object",
+            "required": [
+                "message"
+            ],
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "example": "hi there!!"
+                }
+            }
+        },
+        "models.CreatePhoto": {
+            "type": "object",
+            "required": [
+                "caption",
+                "photo_url",
+                "title"
+            ],
+            "properties": {
+                "caption": {
+                    "type": "string",
+                    "example": "It Ends with Us is a romance novel by Colleen Hoover"
+                },
+                "photo_url": {
+                    "type": "string",
+                    "example": "https://media.istockphoto.com/id/1322123064/photo/portrait-of-an-adorable-white-cat-in-sunglasses-and-an-shirt-lies-on-a-fabric-hammock.jpg?s=612x612\u0026w=0\u0026k=20\u0026c=-G6l2c4jNI0y4cenh-t3qxvIQzVCOqOYZNvrRA7ZU5o="
+                },
+                "title": {
+                    "type": "string",
+                    "example": "It Ends with Us Part 2"
+                }
+            }
+        },
+        "models.CreateSocialMedia": {
+            "type": "object",
+            "required": [
+                "name",
+                "socialMediaURL"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "example": "mahmuddin"
+                },
+                "socialMediaURL": {
+                    "type": "string",
+                    "example": "https://www.instagram.com/mhmudnn/"
+                }
+            }
+        },
+        "models.ForgotPassword": {
+            "type": "object",
+            "required": [
+                "email"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "rizalaja@gmail.com"
+                }
+            }
+        },
+        "models.ResetPasswordInput": {
+            "type": "object",
+            "required": [
+                "new_password",
+                "retype_password",
+                "token"
+            ],
+            "properties": {
+                "new_password": {
+                    "type": "string",
+                    "example": "newpassword"
+                },
+                "retype_password": {
+                    "type": "string",
+                    "example": "newpassword"
+                },
+                "token": {
+                    "type": "string",
+                    "example": "5f4dcc3b5aa765d61d8327deb882cf99a1b2c3d4-e5f6-4a7b-8c9d-0e1f2a3b4c5d"
+                }
+            }
+        },
+        "models.TokenRefresh": {
+            "type": "object",
+            "required": [
+                "refresh_token"
+            ],
+            "properties": {
+                "refresh_token": {
+                    "type": "string",
+                    "example": "5f4dcc3b5aa765d61d8327deb882cf99a1b2c3d4-e5f6-4a7b-8c9d-0e1f2a3b4c5d"
+                }
+            }
+        },
+        "models.UpdateComment": {
+            "type": "object",
+            "required": [
+                "message"
+            ],
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "example": "hi there!! updated"
+                }
+            }
+        },
+        "models.UpdateNotificationPreference": {
+            "type": "object",
+            "properties": {
+                "comment": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "follow": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "like": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "mention": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "reply": {
+                    "type": "boolean",
+                    "example": true
+                }
+            }
+        },
+        "models.UpdatePhoto": {
+            "type": "object",
+            "required": [
+                "caption",
+                "photo_url",
+                "title"
+            ],
+            "properties": {
+                "caption": {
+                    "type": "string",
+                    "example": "It Ends with Us is a romance novel by Colleen Hoover updated"
+                },
+                "photo_url": {
+                    "type": "string",
+                    "example": "https://media.istockphoto.com/id/1322123064/photo/portrait-of-an-adorable-white-cat-in-sunglasses-and-an-shirt-lies-on-a-fabric-hammock.jpg?s=612x612\u0026w=0\u0026k=20\u0026c=-G6l2c4jNI0y4cenh-t3qxvIQzVCOqOYZNvrRA7ZU5o="
+                },
+                "title": {
+                    "type": "string",
+                    "example": "It Ends with Us Part 2 updated"
+                }
+            }
+        },
+        "models.UpdateRole": {
+            "type": "object",
+            "required": [
+                "role"
+            ],
+            "properties": {
+                "role": {
+                    "type": "string",
+                    "example": "moderator"
+                }
+            }
+        },
+        "models.UpdateSocialMedia": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "example": "mahmuddin updated"
+                },
+                "socialMediaURL": {
+                    "type": "string",
+                    "example": "https://www.instagram.com/mhmudnn/"
+                }
+            }
+        },
+        "models.UpdateUser": {
+            "type": "object",
+            "required": [
+                "age",
+                "email",
+                "username"
+            ],
+            "properties": {
+                "age": {
+                    "type": "integer",
+                    "example": 24
+                },
+                "email": {
+                    "type": "string",
+                    "example": "rizalaja@gmail.com"
+                },
+                "username": {
+                    "type": "string",
+                    "example": "rizalaja updated"
+                }
+            }
+        },
+        "models.UserLogin": {
+            "type": "object",
+            "required": [
+                "email",
+                "password"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "rizalaja@gmail.com"
+                },
+                "password": {
+                    "type": "string",
+                    "example": "password"
+                }
+            }
+        },
+        "models.UserRegister": {
+            "type": "object",
+            "required": [
+                "age",
+                "email",
+                "password",
+                "username"
+            ],
+            "properties": {
+                "age": {
+                    "type": "integer",
+                    "example": 23
+                },
+                "email": {
+                    "type": "string",
+                    "example": "rizalaja@gmail.com"
+                },
+                "password": {
+                    "type": "string",
+                    "example": "password"
+                },
+                "username": {
+                    "type": "string",
+                    "example": "rizalaja"
+                }
+            }
+        },
+        "realtime.Message": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "object"
+                },
+                "event": {
+                    "type": "string"
+                },
+                "topic": {
+                    "type": "string"
+                }
+            }
+        },
+        "responses.Comment": {
             "type": "object",
             "properties": {
                 "created_at": {
                     "type": "string"
                 },
+                "deleted": {
+                    "description": "a deleted comment with replies keeps its place in the thread, without message or author",
+                    "type": "boolean"
+                },
+                "depth": {
+                    "type": "integer"
+                },
                 "id": {
                     "type": "integer"
                 },
+                "like_count": {
+                    "type": "integer"
+                },
+                "liked_by_me": {
+                    "type": "boolean"
+                },
+                "mentions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/responses.Mention"
+                    }
+                },
                 "message": {
                     "type": "string"
                 },
+                "more_replies": {
+                    "description": "cursor of GET /comments/{id}/thread, present when the thread leaves out replies under the comment",
+                    "type": "string"
+                },
+                "parent_id": {
+                    "type": "integer"
+                },
                 "photo_id": {
                     "type": "integer"
                 },
+                "replies": {
+                    "description": "only present when the thread is returned as a tree",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/responses.Comment"
+                    }
+                },
+                "reply_count": {
+                    "type": "integer"
+                },
                 "updated_at": {
                     "type": "string"
                 },
                 "user": {
-                    "$ref": "#/definitions/models.User"
+                    "$ref": "#/definitions/responses.User"
                 },
                 "user_id": {
                     "type": "integer"
                 }
             }
         },
-        "models.CreateComment": {
+        "responses.FollowStatus": {
             "type": "object",
-            "required": [
-                "message"
-            ],
             "properties": {
-                "message": {
-                    "type": "string",
-                    "example": "hi there!!"
+                "followed_by_me": {
+                    "type": "boolean"
+                },
+                "follower_count": {
+                    "type": "integer"
                 }
             }
         },
-        "models.CreatePhoto": {
+        "responses.LikeStatus": {
             "type": "object",
-            "required": [
-                "caption",
-                "photo_url",
-                "title"
-            ],
             "properties": {
-                "caption": {
-                    "type": "string",
-                    "example": "It Ends with Us is a romance novel by Colleen Hoover"
+                "like_count": {
+                    "type": "integer"
                 },
-                "photo_url": {
-                    "type": "string",
-                    "example": "https://media.istockphoto.com/id/1322123064/photo/portrait-of-an-adorable-white-cat-in-sunglasses-and-an-shirt-lies-on-a-fabric-hammock.jpg?s=612x612\u0026w=0\u0026k=20\u0026c=-G6l2c4jNI0y4cenh-t3qxvIQzVCOqOYZNvrRA7ZU5o="
+                "liked_by_me": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "responses.Mention": {
+            "type": "object",
+            "properties": {
+                "end": {
+                    "type": "integer"
                 },
-                "title": {
-                    "type": "string",
-                    "example": "It Ends with Us Part 2"
+                "start": {
+                    "type": "integer"
+                },
+                "user": {
+                    "$ref": "#/definitions/responses.User"
+                },
+                "user_id": {
+                    "type": "integer"
                 }
             }
         },
-        "models.CreateSocialMedia": {
+        "responses.Notification": {
             "type": "object",
-            "required": [
-                "name",
-                "socialMediaURL"
-            ],
             "properties": {
-                "name": {
-                    "type": "string",
-                    "example": "mahmuddin"
+                "actor": {
+                    "$ref": "#/definitions/responses.User"
                 },
-                "socialMediaURL": {
-                    "type": "string",
-                    "example": "https://www.instagram.com/mhmudnn/"
+                "comment_id": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "kind": {
+                    "type": "string"
+                },
+                "photo_id": {
+                    "type": "integer"
+                },
+                "read": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "responses.NotificationPreference": {
+            "type": "object",
+            "properties": {
+                "comment": {
+                    "type": "boolean"
+                },
+                "follow": {
+                    "type": "boolean"
+                },
+                "like": {
+                    "type": "boolean"
+                },
+                "mention": {
+                    "type": "boolean"
+                },
+                "reply": {
+                    "type": "boolean"
                 }
             }
         },
-        "models.Photo": {
+        "responses.Photo": {
             "type": "object",
             "properties": {
+                "byte_size": {
+                    "type": "integer"
+                },
+                "camera_model": {
+                    "type": "string"
+                },
                 "caption": {
                     "type": "string"
                 },
+                "comment_count": {
+                    "type": "integer"
+                },
+                "comments": {
+                    "description": "only present with ?include=comments",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/responses.Comment"
+                    }
+                },
                 "created_at": {
                     "type": "string"
                 },
+                "height": {
+                    "type": "integer"
+                },
                 "id": {
                     "type": "integer"
                 },
+                "like_count": {
+                    "type": "integer"
+                },
+                "liked_by_me": {
+                    "type": "boolean"
+                },
+                "mentions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/responses.Mention"
+                    }
+                },
+                "mime_type": {
+                    "type": "string"
+                },
+                "orientation": {
+                    "type": "integer"
+                },
                 "photo_url": {
                     "type": "string"
                 },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "taken_at": {
+                    "type": "string"
+                },
                 "title": {
                     "type": "string"
                 },
@@ -720,14 +2690,79 @@ const docTemplate = `{
                     "type": "string"
                 },
                 "user": {
-                    "$ref": "#/definitions/models.User"
+                    "$ref": "#/definitions/responses.User"
                 },
                 "user_id": {
                     "type": "integer"
+                },
+                "variants": {
+                    "description": "keyed by variant name, e.g. \"thumbnail\"; absent until the variants are generated",
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/responses.PhotoVariant"
+                    }
+                },
+                "width": {
+                    "type": "integer"
+                }
+            }
+        },
+        "responses.PhotoVariant": {
+            "type": "object",
+            "properties": {
+                "height": {
+                    "type": "integer"
+                },
+                "mime_type": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                },
+                "width": {
+                    "type": "integer"
+                }
+            }
+        },
+        "responses.Profile": {
+            "type": "object",
+            "properties": {
+                "age": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "email_verified_at": {
+                    "type": "string"
+                },
+                "followed_by_me": {
+                    "type": "boolean"
+                },
+                "follower_count": {
+                    "type": "integer"
+                },
+                "following_count": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
                 }
             }
         },
-        "models.SocialMedia": {
+        "responses.SocialMedia": {
             "type": "object",
             "properties": {
                 "created_at": {
@@ -746,61 +2781,53 @@ const docTemplate = `{
                     "type": "string"
                 },
                 "user": {
-                    "$ref": "#/definitions/models.User"
+                    "$ref": "#/definitions/responses.User"
                 },
                 "user_id": {
                     "type": "integer"
                 }
             }
         },
-        "models.UpdateComment": {
+        "responses.Tag": {
             "type": "object",
-            "required": [
-                "message"
-            ],
             "properties": {
-                "message": {
-                    "type": "string",
-                    "example": "hi there!! updated"
+                "name": {
+                    "type": "string"
+                },
+                "photo_count": {
+                    "type": "integer"
                 }
             }
         },
-        "models.UpdatePhoto": {
+        "responses.TrendingTag": {
             "type": "object",
-            "required": [
-                "caption",
-                "photo_url",
-                "title"
-            ],
             "properties": {
-                "caption": {
-                    "type": "string",
-                    "example": "It Ends with Us is a romance novel by Colleen Hoover updated"
+                "name": {
+                    "type": "string"
                 },
-                "photo_url": {
-                    "type": "string",
-                    "example": "https://media.istockphoto.com/id/1322123064/photo/portrait-of-an-adorable-white-cat-in-sunglasses-and-an-shirt-lies-on-a-fabric-hammock.jpg?s=612x612\u0026w=0\u0026k=20\u0026c=-G6l2c4jNI0y4cenh-t3qxvIQzVCOqOYZNvrRA7ZU5o="
+                "photo_count": {
+                    "type": "integer"
                 },
-                "title": {
-                    "type": "string",
-                    "example": "It Ends with Us Part 2 updated"
+                "recent_count": {
+                    "type": "integer"
                 }
             }
         },
-        "models.UpdateSocialMedia": {
+        "responses.UnreadCount": {
             "type": "object",
             "properties": {
-                "name": {
-                    "type": "string",
-                    "example": "mahmuddin updated"
+                "by_kind": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
                 },
-                "socialMediaURL": {
-                    "type": "string",
-                    "example": "https://www.instagram.com/mhmudnn/"
+                "total": {
+                    "type": "integer"
                 }
             }
         },
-        "models.User": {
+        "responses.User": {
             "type": "object",
             "properties": {
                 "age": {
@@ -812,10 +2839,13 @@ const docTemplate = `{
                 "email": {
                     "type": "string"
                 },
+                "email_verified_at": {
+                    "type": "string"
+                },
                 "id": {
                     "type": "integer"
                 },
-                "password": {
+                "role": {
                     "type": "string"
                 },
                 "updated_at": {
@@ -825,50 +2855,6 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
-        },
-        "models.UserLogin": {
-            "type": "object",
-            "required": [
-                "email",
-                "password"
-            ],
-            "properties": {
-                "email": {
-                    "type": "string",
-                    "example": "rizalaja@gmail.com"
-                },
-                "password": {
-                    "type": "string",
-                    "example": "password"
-                }
-            }
-        },
-        "models.UserRegister": {
-            "type": "object",
-            "required": [
-                "age",
-                "email",
-                "password",
-                "username"
-            ],
-            "properties": {
-                "age": {
-                    "type": "integer",
-                    "example": 23
-                },
-                "email": {
-                    "type": "string",
-                    "example": "rizalaja@gmail.com"
-                },
-                "password": {
-                    "type": "string",
-                    "example": "password"
-                },
-                "username": {
-                    "type": "string",
-                    "example": "rizalaja"
-                }
-            }
         }
     },
     "securityDefinitions": {
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"os"
+
 	"github.com/ahmadnurrizal/final-project-scalable-web-services-with-golang/api"
 )
 
@@ -21,5 +23,6 @@ import (
 
 // @schemes http
 func main() {
-	api.Run()
+	// the first argument names the command, see api.Execute; without one the API is served
+	api.Execute(os.Args[1:])
 }